@@ -1,57 +1,109 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"carsapi/internal/api"
 	"carsapi/internal/repository"
 	"carsapi/internal/service"
-	_ "modernc.org/sqlite"
 )
 
 func main() {
 	addr := flag.String("addr", ":8080", "HTTP server address")
 	dbPath := flag.String("db-path", "cars.db", "SQLite database path")
 	schemaPath := flag.String("schema-path", "db/schema.sql", "Path to SQL schema file")
+	vinCheck := flag.String("vin-check", "off", "Cross-check submitted year and make against the decoded VIN on create: off, warn or reject")
+	idempotencyTTL := flag.Duration("idempotency-ttl", 24*time.Hour, "How long Idempotency-Key responses are kept for replay")
+	attachmentsDir := flag.String("attachments-dir", "attachments", "Directory car attachments are stored in")
+	maxAttachmentSize := flag.Int64("max-attachment-size", service.DefaultMaxAttachmentSize, "Largest accepted attachment upload, in bytes")
+	strictCatalog := flag.Bool("strict-catalog", false, "Require car makes, models and trims to be in the catalog, rewriting them to its spelling")
+	reservationSweep := flag.Duration("reservation-sweep-interval", time.Minute, "How often expired car reservations are released")
 	flag.Parse()
 
-	db, err := sql.Open("sqlite", *dbPath)
+	vinCheckMode, err := service.ParseVINCheckMode(*vinCheck)
 	if err != nil {
-		log.Fatalf("open database: %v", err)
+		log.Fatalf("parse -vin-check: %v", err)
 	}
-	defer db.Close()
 
-	if _, err := db.Exec("PRAGMA foreign_keys = ON;"); err != nil {
-		log.Fatalf("enable foreign keys: %v", err)
+	db, err := repository.OpenSQLite(*dbPath)
+	if err != nil {
+		log.Fatalf("open database: %v", err)
 	}
+	defer db.Close()
 
 	if err := applySchema(db, *schemaPath); err != nil {
 		log.Fatalf("apply schema: %v", err)
 	}
 
-	repo := repository.NewSQLiteCarRepository(repository.NewSQLDBAdapter(db))
-	svc := service.NewCarService(repo)
-	h := api.NewCarHandler(svc)
+	attachmentFiles, err := repository.NewLocalFileStore(*attachmentsDir)
+	if err != nil {
+		log.Fatalf("open attachments dir: %v", err)
+	}
+
+	adapter := repository.NewSQLDBAdapter(db)
+	carRepo := repository.NewSQLiteCarRepository(adapter)
+	inventoryRepo := repository.NewSQLiteInventoryRepository(adapter)
+	catalogRepo := repository.NewSQLiteCatalogRepository(adapter)
+	catalogService := service.NewCatalogService(catalogRepo)
+	if err := catalogService.Seed(context.Background()); err != nil {
+		log.Fatalf("seed catalog: %v", err)
+	}
+
+	carOptions := []service.CarServiceOption{service.WithVINCheck(vinCheckMode), service.WithAttachmentFiles(attachmentFiles)}
+	if *strictCatalog {
+		carOptions = append(carOptions, service.WithStrictCatalog(catalogRepo))
+	}
+	carService := service.NewCarService(carRepo, inventoryRepo, carOptions...)
+	carHandler := api.NewCarHandler(carService)
+	attachmentHandler := api.NewAttachmentHandler(service.NewAttachmentService(repository.NewSQLiteAttachmentRepository(adapter), carRepo, attachmentFiles, *maxAttachmentSize))
+	serviceRecordHandler := api.NewServiceRecordHandler(service.NewServiceRecordService(repository.NewSQLiteServiceRecordRepository(adapter), carRepo))
+	inventoryHandler := api.NewInventoryHandler(service.NewInventoryService(inventoryRepo, carRepo))
+	saleHandler := api.NewSaleHandler(service.NewSaleService(repository.NewSQLiteSaleRepository(adapter)))
+	vinHandler := api.NewVINHandler(service.NewVINService())
+	catalogHandler := api.NewCatalogHandler(catalogService)
+	idempotency := service.NewIdempotencyService(repository.NewSQLiteIdempotencyRepository(adapter), *idempotencyTTL)
 
 	mux := http.NewServeMux()
-	api.RegisterRoutes(mux, h)
+	api.RegisterRoutes(mux, carHandler, attachmentHandler, serviceRecordHandler, inventoryHandler, saleHandler, vinHandler, catalogHandler, idempotency)
+
+	go sweepReservations(carService, *reservationSweep)
 
 	log.Printf("server listening on %s", *addr)
-	if err := http.ListenAndServe(*addr, mux); err != nil {
+	if err := http.ListenAndServe(*addr, api.WithActor(mux)); err != nil {
 		log.Fatal(err)
 	}
 }
 
+// sweepReservations releases expired car reservations every interval until
+// the process exits.
+func sweepReservations(cars service.CarService, interval time.Duration) {
+	ctx := service.ContextWithActor(context.Background(), "reservation-sweeper")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		released, err := cars.ExpireReservations(ctx, time.Now())
+		if err != nil {
+			log.Printf("expire reservations: %v", err)
+			continue
+		}
+		if released > 0 {
+			log.Printf("released %d expired reservations", released)
+		}
+	}
+}
+
 func applySchema(db *sql.DB, schemaPath string) error {
 	schemaSQL, err := os.ReadFile(schemaPath)
 	if err != nil {
 		return err
 	}
 
-	_, err = db.Exec(string(schemaSQL))
-	return err
+	return repository.ApplySchema(context.Background(), db, string(schemaSQL))
 }
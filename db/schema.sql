@@ -13,12 +13,224 @@ CREATE TABLE IF NOT EXISTS cars (
     model TEXT NOT NULL,
     year INTEGER NOT NULL,
     color TEXT NOT NULL,
-    vin TEXT NOT NULL UNIQUE,
+    vin TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'in_stock' CHECK (status IN ('in_stock', 'reserved', 'sold', 'in_transit', 'retired')),
+    -- price is in minor units of currency, e.g. cents.
+    price INTEGER NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT '',
+    -- price_changed_at mirrors changed_at of the car's latest car_prices row.
+    price_changed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    mileage INTEGER NOT NULL DEFAULT 0,
+    -- reserved_until mirrors expires_at of the car's active reservation.
+    reserved_until DATETIME,
+    -- attributes is a JSON object of free-form string specs such as trim.
+    attributes TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(attributes)),
+    -- service_summary mirrors the car's service_records as a JSON object, or
+    -- NULL while it has none.
+    service_summary TEXT,
+    version INTEGER NOT NULL DEFAULT 1,
     created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME,
     FOREIGN KEY (inventory_id) REFERENCES inventory(id)
 );
 
+-- A VIN only has to be unique among cars that are not in the trash.
+CREATE UNIQUE INDEX IF NOT EXISTS cars_vin_active_idx ON cars (vin) WHERE deleted_at IS NULL;
+
+-- The aging stock report lists the cars of an inventory by price_changed_at.
+CREATE INDEX IF NOT EXISTS cars_price_changed_at_idx ON cars (inventory_id, price_changed_at) WHERE deleted_at IS NULL;
+
+-- Tags are stored lower-cased, so names are unique regardless of case.
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS car_tags (
+    car_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (car_id, tag_id),
+    FOREIGN KEY (car_id) REFERENCES cars(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS car_tags_tag_id_idx ON car_tags (tag_id, car_id);
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    car_id INTEGER NOT NULL,
+    customer TEXT NOT NULL,
+    reserved_by TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    released_at DATETIME,
+    release_reason TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (car_id) REFERENCES cars(id) ON DELETE CASCADE
+);
+
+-- A car can only be held by one reservation at a time.
+CREATE UNIQUE INDEX IF NOT EXISTS reservations_active_idx ON reservations (car_id) WHERE released_at IS NULL;
+CREATE INDEX IF NOT EXISTS reservations_expiry_idx ON reservations (expires_at) WHERE released_at IS NULL;
+
+-- attachments describe files kept under the server's attachment directory;
+-- storage_key and thumbnail_key are paths relative to it.
+CREATE TABLE IF NOT EXISTS attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    car_id INTEGER NOT NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    sha256 TEXT NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    thumbnail_key TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (car_id) REFERENCES cars(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS attachments_car_id_idx ON attachments (car_id, id);
+
+-- service_records log work done on a car. cost is in minor units of currency,
+-- which is shared by all records of a car.
+CREATE TABLE IF NOT EXISTS service_records (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    car_id INTEGER NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('inspection', 'maintenance', 'repair')),
+    description TEXT NOT NULL DEFAULT '',
+    performed_at DATETIME NOT NULL,
+    mileage INTEGER NOT NULL,
+    cost INTEGER NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT '',
+    vendor TEXT NOT NULL DEFAULT '',
+    recorded_by TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (car_id) REFERENCES cars(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS service_records_car_id_idx ON service_records (car_id, performed_at, id);
+
+-- car_prices keeps every asking price a car has been listed at, written
+-- whenever its price or currency changes.
+CREATE TABLE IF NOT EXISTS car_prices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    car_id INTEGER NOT NULL,
+    price INTEGER NOT NULL,
+    currency TEXT NOT NULL DEFAULT '',
+    changed_by TEXT NOT NULL,
+    changed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (car_id) REFERENCES cars(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS car_prices_car_id_idx ON car_prices (car_id, changed_at, id);
+
+-- sales outlive the car they record, so car_id carries no foreign key and a
+-- purged car keeps its sale. A car is sold at most once.
+CREATE TABLE IF NOT EXISTS sales (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    car_id INTEGER NOT NULL UNIQUE,
+    buyer_name TEXT NOT NULL,
+    sale_price INTEGER NOT NULL,
+    currency TEXT NOT NULL DEFAULT '',
+    sold_at DATETIME NOT NULL,
+    salesperson TEXT NOT NULL,
+    reservation_id INTEGER,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS sales_sold_at_idx ON sales (sold_at);
+
+-- car_history keeps a snapshot of a car before and after every change. It has
+-- no foreign key so that the history of a purged car survives it.
+CREATE TABLE IF NOT EXISTS car_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    car_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    old_values TEXT,
+    new_values TEXT,
+    changed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS car_history_car_id_idx ON car_history (car_id, id);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS cars_fts USING fts5(
+    make,
+    model,
+    color,
+    vin,
+    content='cars',
+    content_rowid='id'
+);
+
+CREATE TRIGGER IF NOT EXISTS cars_fts_after_insert AFTER INSERT ON cars BEGIN
+    INSERT INTO cars_fts (rowid, make, model, color, vin) VALUES (new.id, new.make, new.model, new.color, new.vin);
+END;
+
+CREATE TRIGGER IF NOT EXISTS cars_fts_after_delete AFTER DELETE ON cars BEGIN
+    INSERT INTO cars_fts (cars_fts, rowid, make, model, color, vin) VALUES ('delete', old.id, old.make, old.model, old.color, old.vin);
+END;
+
+CREATE TRIGGER IF NOT EXISTS cars_fts_after_update AFTER UPDATE ON cars BEGIN
+    INSERT INTO cars_fts (cars_fts, rowid, make, model, color, vin) VALUES ('delete', old.id, old.make, old.model, old.color, old.vin);
+    INSERT INTO cars_fts (rowid, make, model, color, vin) VALUES (new.id, new.make, new.model, new.color, new.vin);
+END;
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key TEXT PRIMARY KEY,
+    request_hash TEXT NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_headers TEXT NOT NULL DEFAULT '{}',
+    response_body BLOB,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reserved_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The reference catalog of makes, models and trims. The server seeds it at
+-- startup from the catalog embedded in it; names are unique regardless of case.
+CREATE TABLE IF NOT EXISTS makes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE
+);
+
+-- Other spellings of a make, such as 'VW' for Volkswagen.
+CREATE TABLE IF NOT EXISTS make_aliases (
+    alias TEXT PRIMARY KEY COLLATE NOCASE,
+    make_id INTEGER NOT NULL,
+    FOREIGN KEY (make_id) REFERENCES makes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS models (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    make_id INTEGER NOT NULL,
+    name TEXT NOT NULL COLLATE NOCASE,
+    UNIQUE (make_id, name),
+    FOREIGN KEY (make_id) REFERENCES makes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS trims (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    model_id INTEGER NOT NULL,
+    name TEXT NOT NULL COLLATE NOCASE,
+    UNIQUE (model_id, name),
+    FOREIGN KEY (model_id) REFERENCES models(id) ON DELETE CASCADE
+);
+
 INSERT INTO inventory (id, name)
 SELECT 1, 'Default Inventory'
 WHERE NOT EXISTS (SELECT 1 FROM inventory WHERE id = 1);
+
+-- Cars written before car_history existed get a history rebuilt from their
+-- current values, so that as_of queries can see them.
+INSERT INTO car_history (car_id, action, actor, old_values, new_values, changed_at)
+SELECT id, action, 'system', old_values, new_values, changed_at FROM (
+    SELECT id, 'create' AS action, NULL AS old_values, json_object('id', id, 'inventory_id', inventory_id, 'make', make, 'model', model, 'year', year, 'color', color, 'vin', vin, 'status', status, 'price', price, 'currency', currency, 'price_changed_at', strftime('%Y-%m-%dT%H:%M:%SZ', price_changed_at), 'mileage', mileage, 'reserved_until', strftime('%Y-%m-%dT%H:%M:%SZ', reserved_until), 'attributes', json(attributes), 'tags', json((SELECT json_group_array(t.name ORDER BY t.name) FROM car_tags ct JOIN tags t ON t.id = ct.tag_id WHERE ct.car_id = cars.id)), 'service_summary', json(service_summary), 'version', version, 'created_at', strftime('%Y-%m-%dT%H:%M:%SZ', created_at), 'updated_at', strftime('%Y-%m-%dT%H:%M:%SZ', updated_at)) AS new_values, created_at AS changed_at, 0 AS step FROM cars
+    UNION ALL
+    SELECT id, 'delete', json_object('id', id, 'inventory_id', inventory_id, 'make', make, 'model', model, 'year', year, 'color', color, 'vin', vin, 'status', status, 'price', price, 'currency', currency, 'price_changed_at', strftime('%Y-%m-%dT%H:%M:%SZ', price_changed_at), 'mileage', mileage, 'reserved_until', strftime('%Y-%m-%dT%H:%M:%SZ', reserved_until), 'attributes', json(attributes), 'tags', json((SELECT json_group_array(t.name ORDER BY t.name) FROM car_tags ct JOIN tags t ON t.id = ct.tag_id WHERE ct.car_id = cars.id)), 'service_summary', json(service_summary), 'version', version, 'created_at', strftime('%Y-%m-%dT%H:%M:%SZ', created_at), 'updated_at', strftime('%Y-%m-%dT%H:%M:%SZ', updated_at)), NULL, deleted_at, 1 FROM cars WHERE deleted_at IS NOT NULL
+) AS backfill
+WHERE NOT EXISTS (SELECT 1 FROM car_history WHERE car_history.car_id = backfill.id)
+ORDER BY id, step;
+
+-- Likewise, cars without a price history start one at their current price.
+INSERT INTO car_prices (car_id, price, currency, changed_by, changed_at)
+SELECT id, price, currency, 'system', price_changed_at FROM cars
+WHERE NOT EXISTS (SELECT 1 FROM car_prices WHERE car_prices.car_id = cars.id);
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"carsapi/internal/service"
+)
+
+const actorHeader = "X-Actor"
+
+// WithActor attributes the changes a request makes to the caller named in
+// its X-Actor header.
+func WithActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if actor := strings.TrimSpace(r.Header.Get(actorHeader)); actor != "" {
+			r = r.WithContext(service.ContextWithActor(r.Context(), actor))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
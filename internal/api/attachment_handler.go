@@ -0,0 +1,199 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"carsapi/internal/service"
+)
+
+const attachmentFormField = "file"
+
+type AttachmentHandler struct {
+	service service.AttachmentService
+}
+
+func NewAttachmentHandler(svc service.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{service: svc}
+}
+
+// HandleAttachments serves /api/cars/{id}/attachments,
+// /api/cars/{id}/attachments/{attachment_id} and
+// /api/cars/{id}/attachments/{attachment_id}/thumbnail.
+func (h *AttachmentHandler) HandleAttachments(w http.ResponseWriter, r *http.Request) {
+	carID, sub, err := parseSubresource(r.URL.Path, "/api/cars/")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rest := strings.TrimPrefix(strings.TrimPrefix(sub, "attachments"), "/")
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			h.listAttachments(w, r, carID)
+		case http.MethodPost:
+			h.uploadAttachment(w, r, carID)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	id, thumbnail, err := parseAttachmentPath(rest)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet:
+		h.downloadAttachment(w, r, carID, id, thumbnail)
+	case r.Method == http.MethodDelete && !thumbnail:
+		h.deleteAttachment(w, r, carID, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *AttachmentHandler) listAttachments(w http.ResponseWriter, r *http.Request, carID int64) {
+	attachments, err := h.service.List(r.Context(), carID)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch attachments")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, attachments)
+}
+
+func (h *AttachmentHandler) uploadAttachment(w http.ResponseWriter, r *http.Request, carID int64) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, errors.New("expected a multipart/form-data body"))
+		return
+	}
+
+	// Stream the first file part straight to the service instead of letting
+	// ParseMultipartForm spool the whole upload first.
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			writeFail(w, http.StatusBadRequest, fmt.Errorf("missing %q file part", attachmentFormField))
+			return
+		}
+		if err != nil {
+			writeFail(w, http.StatusBadRequest, errors.New("invalid multipart body"))
+			return
+		}
+		if part.FormName() != attachmentFormField {
+			continue
+		}
+
+		attachment, err := h.service.Upload(r.Context(), carID, part.FileName(), part)
+		if errors.Is(err, service.ErrValidation) {
+			writeFail(w, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, service.ErrCarNotFound) {
+			writeError(w, http.StatusNotFound, "car not found")
+			return
+		}
+		if errors.Is(err, service.ErrAttachmentTooLarge) {
+			writeFail(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if errors.Is(err, service.ErrUnsupportedMediaType) {
+			writeFail(w, http.StatusUnsupportedMediaType, err)
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to store attachment")
+			return
+		}
+
+		writeSuccess(w, http.StatusCreated, attachment)
+		return
+	}
+}
+
+func (h *AttachmentHandler) downloadAttachment(w http.ResponseWriter, r *http.Request, carID, id int64, thumbnail bool) {
+	attachment, file, err := h.service.Open(r.Context(), carID, id, thumbnail)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if errors.Is(err, service.ErrAttachmentNotFound) {
+		writeError(w, http.StatusNotFound, "attachment not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to open attachment")
+		return
+	}
+	defer file.Close()
+
+	etag := attachment.SHA256
+	if thumbnail {
+		etag += "-thumbnail"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("ETag", `"`+etag+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// ServeContent handles Range and the conditional request headers.
+	http.ServeContent(w, r, "", attachment.CreatedAt, file)
+}
+
+func (h *AttachmentHandler) deleteAttachment(w http.ResponseWriter, r *http.Request, carID, id int64) {
+	err := h.service.Delete(r.Context(), carID, id)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if errors.Is(err, service.ErrAttachmentNotFound) {
+		writeError(w, http.StatusNotFound, "attachment not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete attachment")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// parseAttachmentPath parses "{attachment_id}" or "{attachment_id}/thumbnail".
+func parseAttachmentPath(path string) (int64, bool, error) {
+	value, sub, hasSub := strings.Cut(path, "/")
+	if hasSub && sub != "thumbnail" {
+		return 0, false, fmt.Errorf("invalid attachment path")
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false, fmt.Errorf("invalid attachment id")
+	}
+
+	return id, hasSub, nil
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+type fakeAttachmentService struct {
+	attachments []*models.Attachment
+	contents    map[int64][]byte
+}
+
+func (f *fakeAttachmentService) Upload(_ context.Context, carID int64, filename string, r io.Reader) (*models.Attachment, error) {
+	if carID != 1 {
+		return nil, service.ErrCarNotFound
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(data) > 16:
+		return nil, service.ErrAttachmentTooLarge
+	case !strings.HasPrefix(string(data), "%PDF"):
+		return nil, service.ErrUnsupportedMediaType
+	}
+
+	attachment := &models.Attachment{
+		ID:          int64(len(f.attachments) + 1),
+		CarID:       carID,
+		Filename:    filename,
+		ContentType: "application/pdf",
+		Size:        int64(len(data)),
+		SHA256:      "abc123",
+		CreatedAt:   time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	f.attachments = append(f.attachments, attachment)
+	f.contents[attachment.ID] = data
+	return attachment, nil
+}
+
+func (f *fakeAttachmentService) List(_ context.Context, carID int64) ([]*models.Attachment, error) {
+	if carID != 1 {
+		return nil, service.ErrCarNotFound
+	}
+	return f.attachments, nil
+}
+
+func (f *fakeAttachmentService) Open(_ context.Context, carID, id int64, thumbnail bool) (*models.Attachment, io.ReadSeekCloser, error) {
+	for _, attachment := range f.attachments {
+		if attachment.ID == id && attachment.CarID == carID && !thumbnail {
+			return attachment, nopSeekCloser{bytes.NewReader(f.contents[id])}, nil
+		}
+	}
+	return nil, nil, service.ErrAttachmentNotFound
+}
+
+func (f *fakeAttachmentService) Delete(_ context.Context, carID, id int64) error {
+	for i, attachment := range f.attachments {
+		if attachment.ID == id && attachment.CarID == carID {
+			f.attachments = append(f.attachments[:i], f.attachments[i+1:]...)
+			return nil
+		}
+	}
+	return service.ErrAttachmentNotFound
+}
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+func multipartUpload(t *testing.T, path, field, content string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("note", "front")
+	part, err := writer.CreateFormFile(field, "title.pdf")
+	if err != nil {
+		t.Fatalf("CreateFormFile() error = %v", err)
+	}
+	_, _ = part.Write([]byte(content))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestUploadAttachmentHandler(t *testing.T) {
+	h := NewAttachmentHandler(&fakeAttachmentService{contents: map[int64][]byte{}})
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		wantCode int
+	}{
+		{name: "not multipart", req: httptest.NewRequest(http.MethodPost, "/api/cars/1/attachments", strings.NewReader("%PDF")), wantCode: http.StatusBadRequest},
+		{name: "missing file part", req: multipartUpload(t, "/api/cars/1/attachments", "photo", "%PDF"), wantCode: http.StatusBadRequest},
+		{name: "missing car", req: multipartUpload(t, "/api/cars/2/attachments", "file", "%PDF"), wantCode: http.StatusNotFound},
+		{name: "unsupported type", req: multipartUpload(t, "/api/cars/1/attachments", "file", "hello"), wantCode: http.StatusUnsupportedMediaType},
+		{name: "too large", req: multipartUpload(t, "/api/cars/1/attachments", "file", "%PDF-1.4 and a lot more"), wantCode: http.StatusRequestEntityTooLarge},
+		{name: "upload", req: multipartUpload(t, "/api/cars/1/attachments", "file", "%PDF-1.4"), wantCode: http.StatusCreated},
+		{name: "invalid car id", req: multipartUpload(t, "/api/cars/abc/attachments", "file", "%PDF-1.4"), wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleAttachments(rec, tt.req)
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body = %s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+	}
+}
+
+func TestAttachmentHandlers(t *testing.T) {
+	fake := &fakeAttachmentService{contents: map[int64][]byte{}}
+	h := NewAttachmentHandler(fake)
+	_, _ = fake.Upload(context.Background(), 1, "title.pdf", strings.NewReader("%PDF-1.4"))
+
+	rec := httptest.NewRecorder()
+	h.HandleAttachments(rec, httptest.NewRequest(http.MethodGet, "/api/cars/1/attachments/1", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "%PDF-1.4" {
+		t.Fatalf("download status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/pdf" {
+		t.Fatalf("Content-Type = %q, want application/pdf", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `inline; filename=title.pdf` {
+		t.Fatalf("Content-Disposition = %q", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/cars/1/attachments/1", nil)
+	req.Header.Set("If-None-Match", `"abc123"`)
+	rec = httptest.NewRecorder()
+	h.HandleAttachments(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("conditional download status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+	}{
+		{name: "list", method: http.MethodGet, path: "/api/cars/1/attachments", wantCode: http.StatusOK},
+		{name: "list missing car", method: http.MethodGet, path: "/api/cars/2/attachments", wantCode: http.StatusNotFound},
+		{name: "other car", method: http.MethodGet, path: "/api/cars/2/attachments/1", wantCode: http.StatusNotFound},
+		{name: "missing thumbnail", method: http.MethodGet, path: "/api/cars/1/attachments/1/thumbnail", wantCode: http.StatusNotFound},
+		{name: "invalid subpath", method: http.MethodGet, path: "/api/cars/1/attachments/1/original", wantCode: http.StatusNotFound},
+		{name: "invalid attachment id", method: http.MethodGet, path: "/api/cars/1/attachments/abc", wantCode: http.StatusNotFound},
+		{name: "put", method: http.MethodPut, path: "/api/cars/1/attachments", wantCode: http.StatusMethodNotAllowed},
+		{name: "delete thumbnail", method: http.MethodDelete, path: "/api/cars/1/attachments/1/thumbnail", wantCode: http.StatusMethodNotAllowed},
+		{name: "delete", method: http.MethodDelete, path: "/api/cars/1/attachments/1", wantCode: http.StatusOK},
+		{name: "delete again", method: http.MethodDelete, path: "/api/cars/1/attachments/1", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleAttachments(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+	}
+}
+
+func TestIsCarSubresource(t *testing.T) {
+	tests := map[string]bool{
+		"/api/cars/1/attachments":             true,
+		"/api/cars/1/attachments/2":           true,
+		"/api/cars/1/attachments/2/thumbnail": true,
+		"/api/cars/1":                         false,
+		"/api/cars/1/history":                 false,
+		"/api/cars/1/attachmentsx":            false,
+	}
+	for path, want := range tests {
+		if got := isCarSubresource(path, "attachments"); got != want {
+			t.Fatalf("isCarSubresource(%q, attachments) = %v, want %v", path, got, want)
+		}
+	}
+}
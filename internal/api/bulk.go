@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+type bulkItem struct {
+	Index   int                 `json:"index"`
+	Op      string              `json:"op"`
+	Status  int                 `json:"status"`
+	Car     *models.Car         `json:"car,omitempty"`
+	Message string              `json:"message,omitempty"`
+	Errors  map[string][]string `json:"errors,omitempty"`
+}
+
+// newBulkItem reports a bulk result with the status code the equivalent
+// single-car request would have answered with.
+func newBulkItem(index int, result service.BulkResult) bulkItem {
+	item := bulkItem{Index: index, Op: result.Op, Car: result.Car, Status: bulkStatus(result)}
+	if item.Status == http.StatusInternalServerError {
+		item.Message = "failed to apply operation"
+	} else if result.Err != nil {
+		item.Message = result.Err.Error()
+		item.Errors = failData(result.Err)
+	}
+
+	return item
+}
+
+func bulkStatus(result service.BulkResult) int {
+	err := result.Err
+	switch {
+	case err == nil && result.Op == "create":
+		return http.StatusCreated
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, service.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrCarNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrConflict), errors.Is(err, service.ErrBulkAborted):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrReferenceNotFound):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
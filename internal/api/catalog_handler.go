@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"carsapi/internal/service"
+)
+
+type CatalogHandler struct {
+	service service.CatalogService
+}
+
+func NewCatalogHandler(svc service.CatalogService) *CatalogHandler {
+	return &CatalogHandler{service: svc}
+}
+
+// HandleCatalog serves /api/catalog/makes and
+// /api/catalog/makes/{make}/models, where {make} may also be an alias.
+func (h *CatalogHandler) HandleCatalog(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.Path, "/api/catalog/makes")
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	makeName, sub, _ := strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+	if makeName != "" && sub != "models" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if makeName == "" {
+		makes, err := h.service.ListMakes(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to fetch makes")
+			return
+		}
+		writeSuccess(w, http.StatusOK, makes)
+		return
+	}
+
+	catalogModels, err := h.service.ListModels(r.Context(), makeName)
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		writeFail(w, http.StatusBadRequest, err)
+	case errors.Is(err, service.ErrMakeNotFound):
+		writeError(w, http.StatusNotFound, "make not found")
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "failed to fetch models")
+	default:
+		writeSuccess(w, http.StatusOK, catalogModels)
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+type fakeCatalogService struct{}
+
+func (fakeCatalogService) Seed(context.Context) error {
+	return nil
+}
+
+func (fakeCatalogService) ListMakes(context.Context) ([]*models.CatalogMake, error) {
+	return []*models.CatalogMake{{ID: 1, Name: "Volkswagen", Aliases: []string{"VW"}}}, nil
+}
+
+func (fakeCatalogService) ListModels(_ context.Context, makeName string) ([]*models.CatalogModel, error) {
+	switch strings.ToLower(makeName) {
+	case "volkswagen", "vw":
+		return []*models.CatalogModel{{ID: 1, Name: "Golf", Trims: []string{"GTI", "R"}}}, nil
+	case " ":
+		return nil, service.ValidationErrors{"make": {"is required"}}
+	default:
+		return nil, service.ErrMakeNotFound
+	}
+}
+
+func TestCatalogHandler(t *testing.T) {
+	h := NewCatalogHandler(fakeCatalogService{})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+	}{
+		{name: "makes", method: http.MethodGet, path: "/api/catalog/makes", wantCode: http.StatusOK},
+		{name: "models by alias", method: http.MethodGet, path: "/api/catalog/makes/vw/models", wantCode: http.StatusOK},
+		{name: "unknown make", method: http.MethodGet, path: "/api/catalog/makes/Trabant/models", wantCode: http.StatusNotFound},
+		{name: "blank make", method: http.MethodGet, path: "/api/catalog/makes/%20/models", wantCode: http.StatusBadRequest},
+		{name: "unknown subresource", method: http.MethodGet, path: "/api/catalog/makes/vw/trims", wantCode: http.StatusNotFound},
+		{name: "unknown catalog", method: http.MethodGet, path: "/api/catalog/colors", wantCode: http.StatusNotFound},
+		{name: "post", method: http.MethodPost, path: "/api/catalog/makes", wantCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleCatalog(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body = %s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.HandleCatalog(rec, httptest.NewRequest(http.MethodGet, "/api/catalog/makes/Volkswagen/models", nil))
+	var resp struct {
+		Data []models.CatalogModel `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Data) != 1 || resp.Data[0].Name != "Golf" {
+		t.Fatalf("models = %+v, decode error = %v", resp.Data, err)
+	}
+}
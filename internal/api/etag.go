@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"carsapi/internal/models"
+)
+
+func carETag(car *models.Car) string {
+	return `"` + strconv.FormatInt(car.Version, 10) + `"`
+}
+
+// parseIfMatch returns the car version required by the If-Match header, or
+// 0 when the header is absent or "*". ok is false when the header can never
+// match a car, e.g. a weak or malformed entity tag.
+func parseIfMatch(r *http.Request) (version int64, ok bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	if !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) || len(header) < 3 {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// ifNoneMatch reports whether the If-None-Match header matches etag using
+// the weak comparison required for GET.
+func ifNoneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeCar(w http.ResponseWriter, code int, car *models.Car) {
+	w.Header().Set("ETag", carETag(car))
+	writeSuccess(w, code, car)
+}
+
+func writePreconditionFailed(w http.ResponseWriter) {
+	writeFail(w, http.StatusPreconditionFailed, errors.New("car has been modified; fetch the latest version and retry"))
+}
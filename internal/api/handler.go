@@ -4,14 +4,23 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"carsapi/internal/models"
 	"carsapi/internal/service"
 )
 
+const (
+	mergePatchMediaType = "application/merge-patch+json"
+	jsonPatchMediaType  = "application/json-patch+json"
+)
+
 type CarHandler struct {
 	service service.CarService
 }
@@ -31,10 +40,185 @@ func (h *CarHandler) HandleCars(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *CarHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	limit, err := parseIntQuery(r.URL.Query(), "limit")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cars, err := h.service.Search(r.Context(), r.URL.Query().Get("q"), limit)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to search cars")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, cars)
+}
+
+func (h *CarHandler) HandleBulk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var in struct {
+		Mode       service.BulkMode       `json:"mode"`
+		Operations []models.BulkOperation `json:"operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeFail(w, http.StatusBadRequest, errInvalidJSONBody)
+		return
+	}
+
+	results, err := h.service.Bulk(r.Context(), in.Mode, in.Operations)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to apply bulk operations")
+		return
+	}
+
+	items := make([]bulkItem, len(results))
+	failed, status := 0, http.StatusOK
+	for i, result := range results {
+		items[i] = newBulkItem(i, result)
+		if result.Err != nil {
+			failed++
+		}
+		if result.Err != nil && !errors.Is(result.Err, service.ErrBulkAborted) {
+			status = items[i].Status
+		}
+	}
+
+	data := map[string]any{"failed": failed, "results": items}
+	if failed > 0 && in.Mode != service.BulkBestEffort {
+		// An atomic batch answers with the status of the operation that
+		// rolled it back.
+		writeJSON(w, status, jsendResponse{Status: "fail", Data: data, Message: "no operations were applied"})
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, data)
+}
+
+func (h *CarHandler) HandleTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	cars, err := h.service.ListDeleted(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch deleted cars")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, cars)
+}
+
+func (h *CarHandler) HandleTrashedCar(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r.URL.Path, "/api/cars/trash/")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	err = h.service.Purge(r.Context(), id)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found in trash")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to purge car")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, map[string]bool{"purged": true})
+}
+
 func (h *CarHandler) HandleCarByID(w http.ResponseWriter, r *http.Request) {
-	id, err := parseID(r.URL.Path, "/api/cars/")
+	id, sub, err := parseSubresource(r.URL.Path, "/api/cars/")
 	if err != nil {
-		writeFail(w, http.StatusBadRequest, err.Error())
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch sub {
+	case "":
+	case "transfer":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		h.transferCar(w, r, id)
+		return
+	case "restore":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		h.restoreCar(w, r, id)
+		return
+	case "release", "ship", "receive", "retire":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		h.transitionCar(w, r, id, sub)
+		return
+	case "reserve":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		h.reserveCar(w, r, id)
+		return
+	case "reservations":
+		switch r.Method {
+		case http.MethodGet:
+			h.listReservations(w, r, id)
+		case http.MethodPost:
+			h.reserveCar(w, r, id)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	case "history":
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		h.carHistory(w, r, id)
+		return
+	case "prices":
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		h.carPrices(w, r, id)
+		return
+	default:
+		writeError(w, http.StatusNotFound, "not found")
 		return
 	}
 
@@ -43,6 +227,8 @@ func (h *CarHandler) HandleCarByID(w http.ResponseWriter, r *http.Request) {
 		h.getCarByID(w, r, id)
 	case http.MethodPut:
 		h.updateCar(w, r, id)
+	case http.MethodPatch:
+		h.patchCar(w, r, id)
 	case http.MethodDelete:
 		h.deleteCar(w, r, id)
 	default:
@@ -50,24 +236,62 @@ func (h *CarHandler) HandleCarByID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *CarHandler) HandleCarByVIN(w http.ResponseWriter, r *http.Request) {
+	vin := strings.TrimPrefix(r.URL.Path, "/api/cars/vin/")
+	if vin == "" || strings.Contains(vin, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getCarByVIN(w, r, vin)
+	case http.MethodPut:
+		h.upsertCarByVIN(w, r, vin)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
 func (h *CarHandler) listCars(w http.ResponseWriter, r *http.Request) {
-	cars, err := h.service.GetAll(r.Context())
+	filter, err := parseCarFilter(r.URL.Query())
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := h.service.GetAll(r.Context(), filter)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to fetch cars")
 		return
 	}
 
-	writeSuccess(w, http.StatusOK, cars)
+	writeSuccess(w, http.StatusOK, page)
 }
 
 func (h *CarHandler) getCarByID(w http.ResponseWriter, r *http.Request, id int64) {
-	car, err := h.service.GetByID(r.Context(), id)
+	asOf, err := parseTimeQuery(r.URL.Query(), "as_of")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var car *models.Car
+	if asOf.IsZero() {
+		car, err = h.service.GetByID(r.Context(), id)
+	} else {
+		car, err = h.service.GetByIDAsOf(r.Context(), id, asOf)
+	}
 	if errors.Is(err, service.ErrCarNotFound) {
 		writeError(w, http.StatusNotFound, "car not found")
 		return
 	}
 	if errors.Is(err, service.ErrValidation) {
-		writeFail(w, http.StatusBadRequest, err.Error())
+		writeFail(w, http.StatusBadRequest, err)
 		return
 	}
 	if err != nil {
@@ -75,19 +299,57 @@ func (h *CarHandler) getCarByID(w http.ResponseWriter, r *http.Request, id int64
 		return
 	}
 
-	writeSuccess(w, http.StatusOK, car)
+	if ifNoneMatch(r, carETag(car)) {
+		w.Header().Set("ETag", carETag(car))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeCar(w, http.StatusOK, car)
+}
+
+func (h *CarHandler) getCarByVIN(w http.ResponseWriter, r *http.Request, vin string) {
+	car, err := h.service.GetByVIN(r.Context(), vin)
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch car")
+		return
+	}
+
+	if ifNoneMatch(r, carETag(car)) {
+		w.Header().Set("ETag", carETag(car))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeCar(w, http.StatusOK, car)
 }
 
 func (h *CarHandler) createCar(w http.ResponseWriter, r *http.Request) {
 	var in models.Car
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeFail(w, http.StatusBadRequest, "invalid json body")
+		writeFail(w, http.StatusBadRequest, errInvalidJSONBody)
 		return
 	}
 
 	created, err := h.service.Create(r.Context(), &in)
 	if errors.Is(err, service.ErrValidation) {
-		writeFail(w, http.StatusBadRequest, err.Error())
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrConflict) {
+		writeFail(w, http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, service.ErrReferenceNotFound) {
+		writeFail(w, http.StatusUnprocessableEntity, err)
 		return
 	}
 	if err != nil {
@@ -95,44 +357,332 @@ func (h *CarHandler) createCar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeSuccess(w, http.StatusCreated, created)
+	writeCar(w, http.StatusCreated, created)
 }
 
 func (h *CarHandler) updateCar(w http.ResponseWriter, r *http.Request, id int64) {
 	var in models.Car
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeFail(w, http.StatusBadRequest, "invalid json body")
+		writeFail(w, http.StatusBadRequest, errInvalidJSONBody)
 		return
 	}
 	in.ID = id
 
+	version, ok := parseIfMatch(r)
+	if !ok {
+		writePreconditionFailed(w)
+		return
+	}
+	in.Version = version
+
 	updated, err := h.service.Update(r.Context(), &in)
 	if errors.Is(err, service.ErrValidation) {
-		writeFail(w, http.StatusBadRequest, err.Error())
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrConflict) {
+		writeFail(w, http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, service.ErrReferenceNotFound) {
+		writeFail(w, http.StatusUnprocessableEntity, err)
 		return
 	}
 	if errors.Is(err, service.ErrCarNotFound) {
 		writeError(w, http.StatusNotFound, "car not found")
 		return
 	}
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		writePreconditionFailed(w)
+		return
+	}
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to update car")
 		return
 	}
 
-	writeSuccess(w, http.StatusOK, updated)
+	writeCar(w, http.StatusOK, updated)
+}
+
+func (h *CarHandler) upsertCarByVIN(w http.ResponseWriter, r *http.Request, vin string) {
+	var in models.Car
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeFail(w, http.StatusBadRequest, errInvalidJSONBody)
+		return
+	}
+
+	version, ok := parseIfMatch(r)
+	if !ok {
+		writePreconditionFailed(w)
+		return
+	}
+	in.Version = version
+
+	car, created, err := h.service.UpsertByVIN(r.Context(), vin, &in)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrConflict) {
+		writeFail(w, http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, service.ErrReferenceNotFound) {
+		writeFail(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		writePreconditionFailed(w)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to upsert car")
+		return
+	}
+
+	if created {
+		writeCar(w, http.StatusCreated, car)
+		return
+	}
+	writeCar(w, http.StatusOK, car)
+}
+
+func (h *CarHandler) patchCar(w http.ResponseWriter, r *http.Request, id int64) {
+	var kind service.PatchKind
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mergePatchMediaType:
+		kind = service.MergePatch
+	case jsonPatchMediaType:
+		kind = service.JSONPatch
+	default:
+		w.Header().Set("Accept-Patch", mergePatchMediaType+", "+jsonPatchMediaType)
+		writeFail(w, http.StatusUnsupportedMediaType, errors.New("unsupported patch content type"))
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, errors.New("invalid patch body"))
+		return
+	}
+
+	version, ok := parseIfMatch(r)
+	if !ok {
+		writePreconditionFailed(w)
+		return
+	}
+
+	patched, err := h.service.Patch(r.Context(), id, kind, patch, version)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrConflict) {
+		writeFail(w, http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, service.ErrReferenceNotFound) {
+		writeFail(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if errors.Is(err, service.ErrPatchTestFailed) {
+		writeFail(w, http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		writePreconditionFailed(w)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to patch car")
+		return
+	}
+
+	writeCar(w, http.StatusOK, patched)
+}
+
+func (h *CarHandler) transferCar(w http.ResponseWriter, r *http.Request, id int64) {
+	var in struct {
+		InventoryID int64 `json:"inventory_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeFail(w, http.StatusBadRequest, errInvalidJSONBody)
+		return
+	}
+
+	version, ok := parseIfMatch(r)
+	if !ok {
+		writePreconditionFailed(w)
+		return
+	}
+
+	transferred, err := h.service.Transfer(r.Context(), id, in.InventoryID, version)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrInventoryNotFound) {
+		writeFail(w, http.StatusUnprocessableEntity, errors.New("target inventory not found"))
+		return
+	}
+	if errors.Is(err, service.ErrReferenceNotFound) {
+		writeFail(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		writePreconditionFailed(w)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to transfer car")
+		return
+	}
+
+	writeCar(w, http.StatusOK, transferred)
+}
+
+func (h *CarHandler) transitionCar(w http.ResponseWriter, r *http.Request, id int64, name string) {
+	version, ok := parseIfMatch(r)
+	if !ok {
+		writePreconditionFailed(w)
+		return
+	}
+
+	changed, err := h.service.Transition(r.Context(), id, name, version)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		writePreconditionFailed(w)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidTransition) {
+		writeFail(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to change car status")
+		return
+	}
+
+	writeCar(w, http.StatusOK, changed)
+}
+
+func (h *CarHandler) reserveCar(w http.ResponseWriter, r *http.Request, id int64) {
+	var in struct {
+		Customer string `json:"customer"`
+		Hours    int    `json:"hours"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeFail(w, http.StatusBadRequest, errInvalidJSONBody)
+		return
+	}
+
+	version, ok := parseIfMatch(r)
+	if !ok {
+		writePreconditionFailed(w)
+		return
+	}
+
+	reservation, err := h.service.Reserve(r.Context(), id, in.Customer, in.Hours, version)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		writePreconditionFailed(w)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidTransition) || errors.Is(err, service.ErrConflict) {
+		writeFail(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to reserve car")
+		return
+	}
+
+	writeSuccess(w, http.StatusCreated, reservation)
+}
+
+func (h *CarHandler) listReservations(w http.ResponseWriter, r *http.Request, id int64) {
+	reservations, err := h.service.ListReservations(r.Context(), id)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch reservations")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, reservations)
+}
+
+func (h *CarHandler) restoreCar(w http.ResponseWriter, r *http.Request, id int64) {
+	restored, err := h.service.Restore(r.Context(), id)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found in trash")
+		return
+	}
+	if errors.Is(err, service.ErrConflict) {
+		writeFail(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to restore car")
+		return
+	}
+
+	writeCar(w, http.StatusOK, restored)
 }
 
 func (h *CarHandler) deleteCar(w http.ResponseWriter, r *http.Request, id int64) {
-	err := h.service.Delete(r.Context(), id)
+	version, ok := parseIfMatch(r)
+	if !ok {
+		writePreconditionFailed(w)
+		return
+	}
+
+	err := h.service.Delete(r.Context(), id, version)
 	if errors.Is(err, service.ErrValidation) {
-		writeFail(w, http.StatusBadRequest, err.Error())
+		writeFail(w, http.StatusBadRequest, err)
 		return
 	}
 	if errors.Is(err, service.ErrCarNotFound) {
 		writeError(w, http.StatusNotFound, "car not found")
 		return
 	}
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		writePreconditionFailed(w)
+		return
+	}
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to delete car")
 		return
@@ -141,6 +691,119 @@ func (h *CarHandler) deleteCar(w http.ResponseWriter, r *http.Request, id int64)
 	writeSuccess(w, http.StatusOK, map[string]bool{"deleted": true})
 }
 
+func parseCarFilter(values url.Values) (models.CarFilter, error) {
+	filter := models.CarFilter{
+		Make:   values.Get("make"),
+		Model:  values.Get("model"),
+		Color:  values.Get("color"),
+		Status: values.Get("status"),
+		SortBy: values.Get("sort"),
+		Cursor: values.Get("cursor"),
+		Tags:   values["tag"],
+	}
+	for name := range values {
+		if attribute, ok := strings.CutPrefix(name, "attr."); ok {
+			if filter.Attributes == nil {
+				filter.Attributes = map[string]string{}
+			}
+			filter.Attributes[attribute] = values.Get(name)
+		}
+	}
+
+	var err error
+	if filter.InventoryID, err = parseInt64Query(values, "inventory_id"); err != nil {
+		return filter, err
+	}
+	if filter.YearMin, err = parseIntQuery(values, "year_min"); err != nil {
+		return filter, err
+	}
+	if filter.YearMax, err = parseIntQuery(values, "year_max"); err != nil {
+		return filter, err
+	}
+	if filter.Limit, err = parseIntQuery(values, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.AsOf, err = parseTimeQuery(values, "as_of"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedAfter, err = parseTimeQuery(values, "created_after"); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedSince, err = parseTimeQuery(values, "updated_since"); err != nil {
+		return filter, err
+	}
+
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.SortDesc = true
+	default:
+		return filter, service.ValidationErrors{"order": {"must be asc or desc"}}
+	}
+
+	return filter, nil
+}
+
+func parseIntQuery(values url.Values, name string) (int, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, service.ValidationErrors{name: {"must be an integer"}}
+	}
+
+	return value, nil
+}
+
+func parseInt64Query(values url.Values, name string) (int64, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, service.ValidationErrors{name: {"must be an integer"}}
+	}
+
+	return value, nil
+}
+
+func parseTimeQuery(values url.Values, name string) (time.Time, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, service.ValidationErrors{name: {"must be an RFC 3339 timestamp"}}
+	}
+
+	return value, nil
+}
+
+func parseSubresource(path, prefix string) (int64, string, error) {
+	value, sub, _ := strings.Cut(strings.TrimPrefix(path, prefix), "/")
+
+	id, err := parseID(value, "")
+	if err != nil {
+		return 0, "", err
+	}
+
+	return id, sub, nil
+}
+
+// isCarSubresource reports whether path is /api/cars/{id}/{name} or below
+// it.
+func isCarSubresource(path, name string) bool {
+	_, rest, _ := strings.Cut(strings.TrimPrefix(path, "/api/cars/"), "/")
+	return rest == name || strings.HasPrefix(rest, name+"/")
+}
+
 func parseID(path, prefix string) (int64, error) {
 	value := strings.TrimPrefix(path, prefix)
 	if value == "" || strings.Contains(value, "/") {
@@ -154,3 +817,39 @@ func parseID(path, prefix string) (int64, error) {
 
 	return id, nil
 }
+
+func (h *CarHandler) carHistory(w http.ResponseWriter, r *http.Request, id int64) {
+	history, err := h.service.History(r.Context(), id)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch car history")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, history)
+}
+
+func (h *CarHandler) carPrices(w http.ResponseWriter, r *http.Request, id int64) {
+	prices, err := h.service.Prices(r.Context(), id)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrCarNotFound) {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch car prices")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, prices)
+}
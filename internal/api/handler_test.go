@@ -5,29 +5,60 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"carsapi/internal/models"
 	"carsapi/internal/service"
 )
 
 type fakeCarService struct {
-	cars   map[int64]*models.Car
-	nextID int64
+	cars         map[int64]*models.Car
+	deleted      map[int64]*models.Car
+	inventories  map[int64]bool
+	reservations map[int64][]*models.Reservation
+	nextID       int64
+	filter       models.CarFilter
+	upsertErr    error
 }
 
 func newFakeCarService() *fakeCarService {
-	return &fakeCarService{cars: map[int64]*models.Car{}, nextID: 1}
+	return &fakeCarService{cars: map[int64]*models.Car{}, deleted: map[int64]*models.Car{}, inventories: map[int64]bool{1: true}, reservations: map[int64][]*models.Reservation{}, nextID: 1}
 }
 
 func (f *fakeCarService) Create(_ context.Context, car *models.Car) (*models.Car, error) {
-	if car.InventoryID <= 0 || car.Make == "" || car.Model == "" || car.Year == 0 || car.Color == "" || car.VIN == "" {
-		return nil, service.ErrValidation
+	problems := service.ValidationErrors{}
+	if car.InventoryID <= 0 {
+		problems.Add("inventory_id", "must be positive")
+	}
+	if car.Year == 0 {
+		problems.Add("year", "is required")
+	}
+	for field, value := range map[string]string{"make": car.Make, "model": car.Model, "color": car.Color, "vin": car.VIN} {
+		if value == "" {
+			problems.Add(field, "is required")
+		}
+	}
+	if err := problems.Err(); err != nil {
+		return nil, err
+	}
+	if !f.inventories[car.InventoryID] {
+		return nil, &service.FieldError{Field: "inventory_id", Err: service.ErrReferenceNotFound}
+	}
+	for _, existing := range f.cars {
+		if existing.VIN == car.VIN {
+			return nil, &service.FieldError{Field: "vin", Err: service.ErrConflict}
+		}
 	}
 	car.ID = f.nextID
+	car.Version = 1
 	f.nextID++
 	copyCar := *car
 	f.cars[car.ID] = &copyCar
@@ -43,10 +74,32 @@ func (f *fakeCarService) GetByID(_ context.Context, id int64) (*models.Car, erro
 	return &copyCar, nil
 }
 
-func (f *fakeCarService) GetAll(_ context.Context) ([]*models.Car, error) {
+func (f *fakeCarService) GetByIDAsOf(ctx context.Context, id int64, _ time.Time) (*models.Car, error) {
+	return f.GetByID(ctx, id)
+}
+
+func (f *fakeCarService) GetAll(_ context.Context, filter models.CarFilter) (*models.CarPage, error) {
+	f.filter = filter
+	if filter.SortBy != "" && filter.SortBy != "id" && filter.SortBy != "year" {
+		return nil, service.ErrValidation
+	}
 	out := make([]*models.Car, 0, len(f.cars))
 	for id := int64(1); id <= f.nextID; id++ {
-		if car, ok := f.cars[id]; ok {
+		if car, ok := f.cars[id]; ok && (filter.Make == "" || car.Make == filter.Make) {
+			copyCar := *car
+			out = append(out, &copyCar)
+		}
+	}
+	return &models.CarPage{Cars: out, Total: int64(len(out))}, nil
+}
+
+func (f *fakeCarService) Search(_ context.Context, text string, _ int) ([]*models.Car, error) {
+	if text == "" {
+		return nil, service.ErrValidation
+	}
+	out := make([]*models.Car, 0)
+	for id := int64(1); id <= f.nextID; id++ {
+		if car, ok := f.cars[id]; ok && strings.Contains(strings.ToLower(car.Model), strings.ToLower(text)) {
 			copyCar := *car
 			out = append(out, &copyCar)
 		}
@@ -55,25 +108,219 @@ func (f *fakeCarService) GetAll(_ context.Context) ([]*models.Car, error) {
 }
 
 func (f *fakeCarService) Update(_ context.Context, car *models.Car) (*models.Car, error) {
-	if _, ok := f.cars[car.ID]; !ok {
+	current, ok := f.cars[car.ID]
+	if !ok {
 		return nil, service.ErrCarNotFound
 	}
-	if car.InventoryID <= 0 || car.Make == "" || car.Model == "" || car.Year == 0 || car.Color == "" || car.VIN == "" {
-		return nil, service.ErrValidation
+	problems := service.ValidationErrors{}
+	if car.InventoryID <= 0 {
+		problems.Add("inventory_id", "must be positive")
+	}
+	if car.Year == 0 {
+		problems.Add("year", "is required")
+	}
+	for field, value := range map[string]string{"make": car.Make, "model": car.Model, "color": car.Color, "vin": car.VIN} {
+		if value == "" {
+			problems.Add(field, "is required")
+		}
+	}
+	if err := problems.Err(); err != nil {
+		return nil, err
+	}
+	if car.Version != 0 && car.Version != current.Version {
+		return nil, service.ErrPreconditionFailed
 	}
 	copyCar := *car
+	copyCar.Version = current.Version + 1
 	f.cars[car.ID] = &copyCar
 	return &copyCar, nil
 }
 
-func (f *fakeCarService) Delete(_ context.Context, id int64) error {
-	if _, ok := f.cars[id]; !ok {
+func (f *fakeCarService) GetByVIN(_ context.Context, vin string) (*models.Car, error) {
+	for _, car := range f.cars {
+		if car.VIN == vin {
+			copyCar := *car
+			return &copyCar, nil
+		}
+	}
+	return nil, service.ErrCarNotFound
+}
+
+func (f *fakeCarService) UpsertByVIN(ctx context.Context, vin string, car *models.Car) (*models.Car, bool, error) {
+	if f.upsertErr != nil {
+		return nil, false, f.upsertErr
+	}
+	car.VIN = vin
+	existing, err := f.GetByVIN(ctx, vin)
+	if err != nil {
+		created, err := f.Create(ctx, car)
+		return created, err == nil, err
+	}
+	car.ID = existing.ID
+	updated, err := f.Update(ctx, car)
+	return updated, false, err
+}
+
+func (f *fakeCarService) Bulk(ctx context.Context, mode service.BulkMode, ops []models.BulkOperation) ([]service.BulkResult, error) {
+	results := make([]service.BulkResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		results[i].Op = op.Op
+		switch op.Op {
+		case "create":
+			results[i].Car, results[i].Err = f.Create(ctx, op.Car)
+		case "delete":
+			results[i].Err = f.Delete(ctx, op.ID, op.Version)
+		default:
+			results[i].Err = service.ValidationErrors{"op": {"must be create, update or delete"}}
+		}
+		failed = failed || results[i].Err != nil
+	}
+
+	if failed && mode != service.BulkBestEffort {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = service.BulkResult{Op: results[i].Op, Err: service.ErrBulkAborted}
+			}
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeCarService) Patch(_ context.Context, id int64, _ service.PatchKind, patch []byte, _ int64) (*models.Car, error) {
+	car, ok := f.cars[id]
+	if !ok {
+		return nil, service.ErrCarNotFound
+	}
+	copyCar := *car
+	if err := json.Unmarshal(patch, &copyCar); err != nil {
+		return nil, service.ErrValidation
+	}
+	f.cars[id] = &copyCar
+	return &copyCar, nil
+}
+
+func (f *fakeCarService) Transfer(_ context.Context, id, inventoryID, version int64) (*models.Car, error) {
+	if !f.inventories[inventoryID] {
+		return nil, service.ErrInventoryNotFound
+	}
+	car, ok := f.cars[id]
+	if !ok {
+		return nil, service.ErrCarNotFound
+	}
+	if version != 0 && version != car.Version {
+		return nil, service.ErrPreconditionFailed
+	}
+	car.InventoryID = inventoryID
+	car.Version++
+	copyCar := *car
+	return &copyCar, nil
+}
+
+func (f *fakeCarService) Delete(_ context.Context, id, version int64) error {
+	car, ok := f.cars[id]
+	if !ok {
 		return service.ErrCarNotFound
 	}
+	if version != 0 && version != car.Version {
+		return service.ErrPreconditionFailed
+	}
+	f.deleted[id] = car
 	delete(f.cars, id)
 	return nil
 }
 
+func (f *fakeCarService) History(_ context.Context, id int64) ([]*models.CarHistoryEntry, error) {
+	car, ok := f.cars[id]
+	if !ok {
+		return nil, service.ErrCarNotFound
+	}
+	return []*models.CarHistoryEntry{{ID: 1, CarID: id, Action: models.CarCreated, Actor: "anonymous", Changes: []models.FieldChange{{Field: "vin", New: car.VIN}}}}, nil
+}
+
+func (f *fakeCarService) Prices(_ context.Context, id int64) ([]*models.PriceChange, error) {
+	car, ok := f.cars[id]
+	if !ok {
+		return nil, service.ErrCarNotFound
+	}
+	return []*models.PriceChange{{ID: 1, CarID: id, Price: car.Price, Currency: car.Currency, ChangedBy: "anonymous"}}, nil
+}
+
+func (f *fakeCarService) Transition(_ context.Context, id int64, name string, _ int64) (*models.Car, error) {
+	car, ok := f.cars[id]
+	if !ok {
+		return nil, service.ErrCarNotFound
+	}
+	if car.Status == models.StatusRetired {
+		return nil, service.ErrInvalidTransition
+	}
+	car.Status = map[string]string{"release": models.StatusInStock, "retire": models.StatusRetired}[name]
+	car.Version++
+	return car, nil
+}
+
+func (f *fakeCarService) Reserve(_ context.Context, id int64, customer string, hours int, version int64) (*models.Reservation, error) {
+	car, ok := f.cars[id]
+	if !ok {
+		return nil, service.ErrCarNotFound
+	}
+	if customer == "" || hours <= 0 {
+		return nil, service.ValidationErrors{"customer": {"is required"}}
+	}
+	if version != 0 && version != car.Version {
+		return nil, service.ErrPreconditionFailed
+	}
+	if car.Status == models.StatusReserved {
+		return nil, service.ErrInvalidTransition
+	}
+	expiresAt := time.Now().Add(time.Duration(hours) * time.Hour)
+	reservation := &models.Reservation{ID: int64(len(f.reservations[id]) + 1), CarID: id, Customer: customer, ExpiresAt: expiresAt}
+	f.reservations[id] = append(f.reservations[id], reservation)
+	car.Status, car.ReservedUntil = models.StatusReserved, &expiresAt
+	car.Version++
+	return reservation, nil
+}
+
+func (f *fakeCarService) ListReservations(_ context.Context, id int64) ([]*models.Reservation, error) {
+	if _, ok := f.cars[id]; !ok {
+		return nil, service.ErrCarNotFound
+	}
+	return append([]*models.Reservation{}, f.reservations[id]...), nil
+}
+
+func (f *fakeCarService) ExpireReservations(context.Context, time.Time) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeCarService) ListDeleted(_ context.Context) ([]*models.Car, error) {
+	out := make([]*models.Car, 0, len(f.deleted))
+	for _, car := range f.deleted {
+		out = append(out, car)
+	}
+	return out, nil
+}
+
+func (f *fakeCarService) Restore(ctx context.Context, id int64) (*models.Car, error) {
+	car, ok := f.deleted[id]
+	if !ok {
+		return nil, service.ErrCarNotFound
+	}
+	if _, err := f.GetByVIN(ctx, car.VIN); err == nil {
+		return nil, &service.FieldError{Field: "vin", Err: service.ErrConflict}
+	}
+	f.cars[id] = car
+	delete(f.deleted, id)
+	return car, nil
+}
+
+func (f *fakeCarService) Purge(_ context.Context, id int64) error {
+	if _, ok := f.deleted[id]; !ok {
+		return service.ErrCarNotFound
+	}
+	delete(f.deleted, id)
+	return nil
+}
+
 func TestCreateCarHandler(t *testing.T) {
 	h := NewCarHandler(newFakeCarService())
 	body := []byte(`{"inventory_id":1,"make":"Ford","model":"Fiesta","year":2018,"color":"Blue","vin":"VIN-API-1"}`)
@@ -87,6 +334,67 @@ func TestCreateCarHandler(t *testing.T) {
 	}
 }
 
+func TestCreateCarHandlerValidationErrors(t *testing.T) {
+	h := NewCarHandler(newFakeCarService())
+	req := httptest.NewRequest(http.MethodPost, "/api/cars", strings.NewReader(`{"inventory_id":1,"model":"Fiesta","year":2018,"color":"Blue"}`))
+	rec := httptest.NewRecorder()
+
+	h.HandleCars(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	var resp struct {
+		Status string              `json:"status"`
+		Data   map[string][]string `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Status != "fail" || len(resp.Data) != 2 || len(resp.Data["make"]) != 1 || len(resp.Data["vin"]) != 1 {
+		t.Fatalf("response = %+v, want make and vin problems", resp)
+	}
+}
+
+func TestCreateCarHandlerConstraintViolations(t *testing.T) {
+	h := NewCarHandler(newFakeCarService())
+	tests := []struct {
+		body  string
+		code  int
+		field string
+	}{
+		{`{"inventory_id":1,"make":"Ford","model":"Fiesta","year":2018,"color":"Blue","vin":"VIN-API-2"}`, http.StatusCreated, ""},
+		{`{"inventory_id":1,"make":"Ford","model":"Focus","year":2019,"color":"Red","vin":"VIN-API-2"}`, http.StatusConflict, "vin"},
+		{`{"inventory_id":9,"make":"Ford","model":"Focus","year":2019,"color":"Red","vin":"VIN-API-3"}`, http.StatusUnprocessableEntity, "inventory_id"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/cars", strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+
+		h.HandleCars(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("status = %d, want %d", rec.Code, tt.code)
+		}
+		if tt.field == "" {
+			continue
+		}
+
+		var resp struct {
+			Status string              `json:"status"`
+			Data   map[string][]string `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if resp.Status != "fail" || len(resp.Data[tt.field]) != 1 {
+			t.Fatalf("response = %+v, want fail naming %s", resp, tt.field)
+		}
+	}
+}
+
 func TestGetCarByIDHandlerNotFound(t *testing.T) {
 	h := NewCarHandler(newFakeCarService())
 	req := httptest.NewRequest(http.MethodGet, "/api/cars/99", nil)
@@ -121,6 +429,50 @@ func TestListCarsHandler(t *testing.T) {
 	}
 }
 
+func TestListCarsHandlerFilters(t *testing.T) {
+	fake := newFakeCarService()
+	_, _ = fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "BMW", Model: "M3", Year: 2021, Color: "Black", VIN: "VIN-API-5"})
+	_, _ = fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Audi", Model: "A3", Year: 2020, Color: "White", VIN: "VIN-API-6"})
+	h := NewCarHandler(fake)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/cars?make=Audi&sort=year&order=desc&limit=10", nil)
+	rec := httptest.NewRecorder()
+	h.HandleCars(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp struct {
+		Data models.CarPage `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response error = %v", err)
+	}
+	if resp.Data.Total != 1 || len(resp.Data.Cars) != 1 || resp.Data.Cars[0].Make != "Audi" {
+		t.Fatalf("data = %+v, want only the Audi", resp.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleCars(rec, httptest.NewRequest(http.MethodGet, "/api/cars?tag=certified&tag=demo&attr.trim=LX&attr.drive=awd&attribute=x", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("tag filter status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	wantAttributes := map[string]string{"trim": "LX", "drive": "awd"}
+	if !slices.Equal(fake.filter.Tags, []string{"certified", "demo"}) || !maps.Equal(fake.filter.Attributes, wantAttributes) {
+		t.Fatalf("filter tags = %v, attributes = %v; want [certified demo] and %v", fake.filter.Tags, fake.filter.Attributes, wantAttributes)
+	}
+
+	for _, query := range []string{"year_min=abc", "order=sideways", "sort=price"} {
+		badReq := httptest.NewRequest(http.MethodGet, "/api/cars?"+query, nil)
+		badRec := httptest.NewRecorder()
+		h.HandleCars(badRec, badReq)
+		if badRec.Code != http.StatusBadRequest {
+			t.Fatalf("%s status = %d, want %d", query, badRec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
 func TestUpdateAndDeleteHandlers(t *testing.T) {
 	fake := newFakeCarService()
 	created, _ := fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Kia", Model: "Soul", Year: 2020, Color: "Yellow", VIN: "VIN-API-3"})
@@ -143,11 +495,493 @@ func TestUpdateAndDeleteHandlers(t *testing.T) {
 		t.Fatalf("delete status = %d, want %d", deleteRec.Code, http.StatusOK)
 	}
 
-	if err := fake.Delete(context.Background(), created.ID); !errors.Is(err, service.ErrCarNotFound) {
+	if err := fake.Delete(context.Background(), created.ID, 0); !errors.Is(err, service.ErrCarNotFound) {
 		t.Fatalf("expected not found after delete, got %v", err)
 	}
 }
 
+func TestTrashHandlers(t *testing.T) {
+	fake := newFakeCarService()
+	ctx := context.Background()
+	first, _ := fake.Create(ctx, &models.Car{InventoryID: 1, Make: "Kia", Model: "Rio", Year: 2019, Color: "White", VIN: "VIN-API-TRASH"})
+	_ = fake.Delete(ctx, first.ID, 0)
+	h := NewCarHandler(fake)
+
+	listRec := httptest.NewRecorder()
+	h.HandleTrash(listRec, httptest.NewRequest(http.MethodGet, "/api/cars/trash", nil))
+	if listRec.Code != http.StatusOK || !strings.Contains(listRec.Body.String(), "VIN-API-TRASH") {
+		t.Fatalf("trash status = %d, body = %s", listRec.Code, listRec.Body.String())
+	}
+
+	second, _ := fake.Create(ctx, &models.Car{InventoryID: 1, Make: "Kia", Model: "Rio", Year: 2020, Color: "Black", VIN: "VIN-API-TRASH"})
+	restoreRec := httptest.NewRecorder()
+	h.HandleCarByID(restoreRec, httptest.NewRequest(http.MethodPost, "/api/cars/"+toString(first.ID)+"/restore", nil))
+	if restoreRec.Code != http.StatusConflict {
+		t.Fatalf("restore with a reused VIN status = %d, want %d", restoreRec.Code, http.StatusConflict)
+	}
+
+	_ = fake.Delete(ctx, second.ID, 0)
+	restoreRec = httptest.NewRecorder()
+	h.HandleCarByID(restoreRec, httptest.NewRequest(http.MethodPost, "/api/cars/"+toString(first.ID)+"/restore", nil))
+	if restoreRec.Code != http.StatusOK {
+		t.Fatalf("restore status = %d, want %d", restoreRec.Code, http.StatusOK)
+	}
+
+	purgeRec := httptest.NewRecorder()
+	h.HandleTrashedCar(purgeRec, httptest.NewRequest(http.MethodDelete, "/api/cars/trash/"+toString(second.ID), nil))
+	if purgeRec.Code != http.StatusOK {
+		t.Fatalf("purge status = %d, want %d", purgeRec.Code, http.StatusOK)
+	}
+
+	purgeRec = httptest.NewRecorder()
+	h.HandleTrashedCar(purgeRec, httptest.NewRequest(http.MethodDelete, "/api/cars/trash/"+toString(first.ID), nil))
+	if purgeRec.Code != http.StatusNotFound {
+		t.Fatalf("purge of a live car status = %d, want %d", purgeRec.Code, http.StatusNotFound)
+	}
+}
+
+func TestCarsTimeQueryHandlers(t *testing.T) {
+	fake := newFakeCarService()
+	created, _ := fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Kia", Model: "Niro", Year: 2022, Color: "Blue", VIN: "VIN-API-ASOF"})
+	h := NewCarHandler(fake)
+
+	rec := httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodGet, "/api/cars/"+toString(created.ID)+"?as_of=2026-01-31T23:59:59Z", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get as_of status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	for _, target := range []string{"/api/cars?as_of=2026-01-31", "/api/cars?created_after=last-week", "/api/cars?updated_since=2026-13-01T00:00:00Z", "/api/cars/" + toString(created.ID) + "?as_of=yesterday"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if strings.HasPrefix(target, "/api/cars?") {
+			h.HandleCars(rec, req)
+		} else {
+			h.HandleCarByID(rec, req)
+		}
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s status = %d, body = %s", target, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestCarTransitionHandlers(t *testing.T) {
+	fake := newFakeCarService()
+	created, _ := fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Kia", Model: "EV6", Year: 2023, Color: "White", VIN: "VIN-API-RETIRE"})
+	h := NewCarHandler(fake)
+
+	retire := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/cars/"+toString(created.ID)+"/retire", nil)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.HandleCarByID(rec, req)
+		return rec
+	}
+
+	if rec := retire("W/\"1\""); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("retire with a weak If-Match status = %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+	if rec := retire(`"1"`); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"retired"`) {
+		t.Fatalf("retire status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := retire(""); rec.Code != http.StatusConflict {
+		t.Fatalf("second retire status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	rec := httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodGet, "/api/cars/"+toString(created.ID)+"/release", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET release status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	// Cars are sold through the sale handler.
+	rec = httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodPost, "/api/cars/"+toString(created.ID)+"/sell", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("POST sell status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestCarReservationHandlers(t *testing.T) {
+	fake := newFakeCarService()
+	created, _ := fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Kia", Model: "Niro", Year: 2022, Color: "Red", VIN: "VIN-API-HOLD"})
+	h := NewCarHandler(fake)
+	path := "/api/cars/" + toString(created.ID) + "/reservations"
+
+	reserve := func(body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.HandleCarByID(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		ifMatch  string
+		wantCode int
+	}{
+		{name: "invalid json", body: `{`, wantCode: http.StatusBadRequest},
+		{name: "missing customer", body: `{"hours":24}`, wantCode: http.StatusBadRequest},
+		{name: "stale version", body: `{"customer":"Kim","hours":24}`, ifMatch: `"9"`, wantCode: http.StatusPreconditionFailed},
+		{name: "reserve", body: `{"customer":"Kim","hours":24}`, ifMatch: `"1"`, wantCode: http.StatusCreated},
+		{name: "already reserved", body: `{"customer":"Lee","hours":2}`, wantCode: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		if rec := reserve(tt.body, tt.ifMatch); rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body = %s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+	}
+
+	// POST /reserve is the same as POST /reservations.
+	rec := httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodPost, "/api/cars/"+toString(created.ID)+"/reserve", strings.NewReader(`{"customer":"Lee","hours":2}`)))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("POST reserve of a reserved car status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	rec = httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodGet, "/api/cars/"+toString(created.ID)+"/reserve", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET reserve status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodGet, "/api/cars/"+toString(created.ID), nil))
+	if !strings.Contains(rec.Body.String(), `"status":"reserved"`) || !strings.Contains(rec.Body.String(), `"reserved_until":`) {
+		t.Fatalf("car body = %s, want it reserved with reserved_until", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var resp struct {
+		Data []models.Reservation `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d, decode error = %v", rec.Code, err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Customer != "Kim" {
+		t.Fatalf("reservations = %+v, want Kim's", resp.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodGet, "/api/cars/999/reservations", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown car status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	rec = httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("DELETE status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestCarHistoryHandler(t *testing.T) {
+	fake := newFakeCarService()
+	created, _ := fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Kia", Model: "Ceed", Year: 2021, Color: "Blue", VIN: "VIN-API-HIST"})
+	h := NewCarHandler(fake)
+
+	rec := httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodGet, "/api/cars/"+toString(created.ID)+"/history", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp struct {
+		Data []models.CarHistoryEntry `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Action != models.CarCreated || resp.Data[0].Changes[0].New != "VIN-API-HIST" {
+		t.Fatalf("history = %+v", resp.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodGet, "/api/cars/999/history", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown car status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestCarPricesHandler(t *testing.T) {
+	fake := newFakeCarService()
+	created, _ := fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Kia", Model: "Niro", Year: 2022, Color: "White", VIN: "VIN-API-PRICE", Price: 2500000, Currency: "EUR"})
+	h := NewCarHandler(fake)
+
+	rec := httptest.NewRecorder()
+	h.HandleCarByID(rec, httptest.NewRequest(http.MethodGet, "/api/cars/"+toString(created.ID)+"/prices", nil))
+	var resp struct {
+		Data []models.PriceChange `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, decode error = %v", rec.Code, err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Price != 2500000 || resp.Data[0].Currency != "EUR" {
+		t.Fatalf("prices = %+v", resp.Data)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+	}{
+		{name: "unknown car", method: http.MethodGet, path: "/api/cars/999/prices", wantCode: http.StatusNotFound},
+		{name: "post", method: http.MethodPost, path: "/api/cars/" + toString(created.ID) + "/prices", wantCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleCarByID(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+	}
+}
+
+func TestPatchCarHandler(t *testing.T) {
+	fake := newFakeCarService()
+	created, _ := fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Jeep", Model: "Wrangler", Year: 2020, Color: "Sand", VIN: "VIN-API-8"})
+	h := NewCarHandler(fake)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/cars/"+toString(created.ID), bytes.NewReader([]byte(`{"color":"Olive"}`)))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	h.HandleCarByID(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if fake.cars[created.ID].Color != "Olive" {
+		t.Fatalf("color = %q, want Olive", fake.cars[created.ID].Color)
+	}
+
+	plainReq := httptest.NewRequest(http.MethodPatch, "/api/cars/"+toString(created.ID), bytes.NewReader([]byte(`{"color":"Red"}`)))
+	plainReq.Header.Set("Content-Type", "application/json")
+	plainRec := httptest.NewRecorder()
+	h.HandleCarByID(plainRec, plainReq)
+
+	if plainRec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("plain json status = %d, want %d", plainRec.Code, http.StatusUnsupportedMediaType)
+	}
+	if plainRec.Header().Get("Accept-Patch") == "" {
+		t.Fatalf("missing Accept-Patch header on 415 response")
+	}
+}
+
+func TestCarConditionalRequests(t *testing.T) {
+	fake := newFakeCarService()
+	created, _ := fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Mazda", Model: "MX-5", Year: 2022, Color: "Red", VIN: "VIN-API-9"})
+	h := NewCarHandler(fake)
+	path := "/api/cars/" + toString(created.ID)
+
+	getRec := httptest.NewRecorder()
+	h.HandleCarByID(getRec, httptest.NewRequest(http.MethodGet, path, nil))
+	etag := getRec.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("ETag = %q, want %q", etag, `"1"`)
+	}
+
+	cachedReq := httptest.NewRequest(http.MethodGet, path, nil)
+	cachedReq.Header.Set("If-None-Match", etag)
+	cachedRec := httptest.NewRecorder()
+	h.HandleCarByID(cachedRec, cachedReq)
+	if cachedRec.Code != http.StatusNotModified {
+		t.Fatalf("If-None-Match status = %d, want %d", cachedRec.Code, http.StatusNotModified)
+	}
+
+	body := `{"inventory_id":1,"make":"Mazda","model":"MX-5","year":2022,"color":"Blue","vin":"VIN-API-9"}`
+	updateReq := httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(body)))
+	updateReq.Header.Set("If-Match", etag)
+	updateRec := httptest.NewRecorder()
+	h.HandleCarByID(updateRec, updateReq)
+	if updateRec.Code != http.StatusOK || updateRec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("update status = %d ETag = %q, want %d and %q", updateRec.Code, updateRec.Header().Get("ETag"), http.StatusOK, `"2"`)
+	}
+
+	staleReq := httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(body)))
+	staleReq.Header.Set("If-Match", etag)
+	staleRec := httptest.NewRecorder()
+	h.HandleCarByID(staleRec, staleReq)
+	if staleRec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale update status = %d, want %d", staleRec.Code, http.StatusPreconditionFailed)
+	}
+
+	weakReq := httptest.NewRequest(http.MethodDelete, path, nil)
+	weakReq.Header.Set("If-Match", `W/"2"`)
+	weakRec := httptest.NewRecorder()
+	h.HandleCarByID(weakRec, weakReq)
+	if weakRec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("weak If-Match status = %d, want %d", weakRec.Code, http.StatusPreconditionFailed)
+	}
+}
+
+func TestTransferCarHandler(t *testing.T) {
+	fake := newFakeCarService()
+	fake.inventories[2] = true
+	created, _ := fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Subaru", Model: "Outback", Year: 2021, Color: "Green", VIN: "VIN-API-4"})
+	h := NewCarHandler(fake)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/cars/"+toString(created.ID)+"/transfer", bytes.NewReader([]byte(`{"inventory_id":2}`)))
+	rec := httptest.NewRecorder()
+	h.HandleCarByID(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if fake.cars[created.ID].InventoryID != 2 {
+		t.Fatalf("inventory_id = %d, want 2", fake.cars[created.ID].InventoryID)
+	}
+
+	unknownReq := httptest.NewRequest(http.MethodPost, "/api/cars/"+toString(created.ID)+"/transfer", bytes.NewReader([]byte(`{"inventory_id":7}`)))
+	unknownRec := httptest.NewRecorder()
+	h.HandleCarByID(unknownRec, unknownReq)
+
+	if unknownRec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown inventory status = %d, want %d", unknownRec.Code, http.StatusUnprocessableEntity)
+	}
+
+	transfer := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/cars/"+toString(created.ID)+"/transfer", bytes.NewReader([]byte(`{"inventory_id":1}`)))
+		req.Header.Set("If-Match", ifMatch)
+		rec := httptest.NewRecorder()
+		h.HandleCarByID(rec, req)
+		return rec
+	}
+	if rec := transfer(`"1"`); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale transfer status = %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+	if rec := transfer(`"2"`); rec.Code != http.StatusOK || fake.cars[created.ID].InventoryID != 1 {
+		t.Fatalf("transfer at the current version status = %d, inventory_id = %d; want %d, 1", rec.Code, fake.cars[created.ID].InventoryID, http.StatusOK)
+	}
+}
+
+func TestSearchCarsHandler(t *testing.T) {
+	fake := newFakeCarService()
+	_, _ = fake.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Honda", Model: "Civic", Year: 2019, Color: "Blue", VIN: "VIN-API-7"})
+	h := NewCarHandler(fake)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/cars/search?q=civ", nil)
+	rec := httptest.NewRecorder()
+	h.HandleSearch(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp struct {
+		Data []models.Car `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response error = %v", err)
+	}
+	if len(resp.Data) != 1 {
+		t.Fatalf("results = %d, want 1", len(resp.Data))
+	}
+
+	emptyReq := httptest.NewRequest(http.MethodGet, "/api/cars/search", nil)
+	emptyRec := httptest.NewRecorder()
+	h.HandleSearch(emptyRec, emptyReq)
+	if emptyRec.Code != http.StatusBadRequest {
+		t.Fatalf("empty query status = %d, want %d", emptyRec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCarByVINHandlers(t *testing.T) {
+	h := NewCarHandler(newFakeCarService())
+	body := `{"inventory_id":1,"make":"Honda","model":"Accord","year":2003,"color":"Silver"}`
+
+	put := func(payload, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/cars/vin/1HGCM82633A004352", strings.NewReader(payload))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.HandleCarByVIN(rec, req)
+		return rec
+	}
+
+	if rec := put(body, ""); rec.Code != http.StatusCreated || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("first PUT status = %d etag = %q, want %d", rec.Code, rec.Header().Get("ETag"), http.StatusCreated)
+	}
+	if rec := put(strings.Replace(body, "Silver", "Black", 1), `"1"`); rec.Code != http.StatusOK {
+		t.Fatalf("second PUT status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := put(body, `"1"`); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale PUT status = %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+
+	rec := httptest.NewRecorder()
+	h.HandleCarByVIN(rec, httptest.NewRequest(http.MethodGet, "/api/cars/vin/1HGCM82633A004352", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Black") {
+		t.Fatalf("GET status = %d body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleCarByVIN(rec, httptest.NewRequest(http.MethodGet, "/api/cars/vin/1M8GDM9AXKP042788", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("GET unknown vin status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestCarByVINHandlerConflict(t *testing.T) {
+	svc := newFakeCarService()
+	svc.upsertErr = &service.FieldError{Field: "vin", Err: service.ErrConflict}
+	h := NewCarHandler(svc)
+
+	body := `{"inventory_id":1,"make":"Honda","model":"Accord","year":2003,"color":"Silver"}`
+	rec := httptest.NewRecorder()
+	h.HandleCarByVIN(rec, httptest.NewRequest(http.MethodPut, "/api/cars/vin/1HGCM82633A004352", strings.NewReader(body)))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("PUT status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if !strings.Contains(rec.Body.String(), `"vin"`) {
+		t.Fatalf("PUT body = %s, want vin field", rec.Body.String())
+	}
+}
+
+func TestBulkCarsHandler(t *testing.T) {
+	h := NewCarHandler(newFakeCarService())
+	body := `{"mode":%q,"operations":[{"op":"create","car":{"inventory_id":1,"make":"Kia","model":"Rio","year":2017,"color":"White","vin":"VIN-BULK-%[2]d"}},{"op":"delete","id":99}]}`
+
+	var resp struct {
+		Status string `json:"status"`
+		Data   struct {
+			Failed  int        `json:"failed"`
+			Results []bulkItem `json:"results"`
+		} `json:"data"`
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/cars/bulk", strings.NewReader(fmt.Sprintf(body, "atomic", 1)))
+	rec := httptest.NewRecorder()
+	h.HandleBulk(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("atomic status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Status != "fail" || resp.Data.Failed != 2 || resp.Data.Results[0].Status != http.StatusConflict {
+		t.Fatalf("atomic response = %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/cars/bulk", strings.NewReader(fmt.Sprintf(body, "best_effort", 2)))
+	rec = httptest.NewRecorder()
+	h.HandleBulk(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("best effort status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Status != "success" || resp.Data.Failed != 1 || resp.Data.Results[0].Status != http.StatusCreated || resp.Data.Results[1].Status != http.StatusNotFound {
+		t.Fatalf("best effort response = %+v", resp)
+	}
+}
+
 func toString(id int64) string {
 	return strconv.FormatInt(id, 10)
 }
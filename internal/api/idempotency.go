@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+var replayedHeaders = []string{"Content-Type", "ETag"}
+
+// Idempotent wraps a handler so that POST requests carrying an
+// Idempotency-Key header are processed at most once; retries with the same
+// key and body replay the stored response.
+func Idempotent(svc service.IdempotencyService, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeFail(w, http.StatusBadRequest, errors.New("invalid request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		record, err := svc.Begin(r.Context(), key, requestHash)
+		if errors.Is(err, service.ErrValidation) {
+			writeFail(w, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, service.ErrIdempotencyKeyReused) {
+			writeFail(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if errors.Is(err, service.ErrIdempotencyInProgress) {
+			writeFail(w, http.StatusConflict, err)
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to check idempotency key")
+			return
+		}
+
+		if record != nil {
+			for name, value := range record.Headers {
+				w.Header().Set(name, value)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.StatusCode)
+			_, _ = w.Write(record.Body)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+
+		// The outcome is stored even if the client has already gone away;
+		// that is exactly the retry this is protecting against.
+		ctx := context.WithoutCancel(r.Context())
+		if rec.status >= http.StatusInternalServerError {
+			err = svc.Abandon(ctx, key)
+		} else {
+			headers := make(map[string]string, len(replayedHeaders))
+			for _, name := range replayedHeaders {
+				if value := rec.Header().Get(name); value != "" {
+					headers[name] = value
+				}
+			}
+			err = svc.Complete(ctx, &models.IdempotencyRecord{Key: key, RequestHash: requestHash, StatusCode: rec.status, Headers: headers, Body: rec.body.Bytes()})
+		}
+		if err != nil {
+			log.Printf("store idempotency key %q: %v", key, err)
+		}
+	}
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+type fakeIdempotencyService struct {
+	records map[string]*models.IdempotencyRecord
+	hashes  map[string]string
+}
+
+func newFakeIdempotencyService() *fakeIdempotencyService {
+	return &fakeIdempotencyService{records: map[string]*models.IdempotencyRecord{}, hashes: map[string]string{}}
+}
+
+func (f *fakeIdempotencyService) Begin(_ context.Context, key, requestHash string) (*models.IdempotencyRecord, error) {
+	hash, ok := f.hashes[key]
+	if !ok {
+		f.hashes[key] = requestHash
+		return nil, nil
+	}
+	if hash != requestHash {
+		return nil, service.ErrIdempotencyKeyReused
+	}
+	return f.records[key], nil
+}
+
+func (f *fakeIdempotencyService) Complete(_ context.Context, record *models.IdempotencyRecord) error {
+	f.records[record.Key] = record
+	return nil
+}
+
+func (f *fakeIdempotencyService) Abandon(_ context.Context, key string) error {
+	delete(f.hashes, key)
+	return nil
+}
+
+func TestIdempotentCreateCar(t *testing.T) {
+	cars := newFakeCarService()
+	handler := Idempotent(newFakeIdempotencyService(), NewCarHandler(cars).HandleCars)
+	body := `{"inventory_id":1,"make":"Ford","model":"Maverick","year":2023,"color":"Blue","vin":"VIN-API-10"}`
+
+	post := func(payload string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/cars", bytes.NewReader([]byte(payload)))
+		req.Header.Set("Idempotency-Key", "retry-1")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	first := post(body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want %d", first.Code, http.StatusCreated)
+	}
+
+	retry := post(body)
+	if retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retry status = %d replayed = %q, want replayed %d", retry.Code, retry.Header().Get("Idempotent-Replayed"), http.StatusCreated)
+	}
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Fatalf("retry response differs from original")
+	}
+	if len(cars.cars) != 1 {
+		t.Fatalf("cars created = %d, want 1", len(cars.cars))
+	}
+
+	reused := post(`{"inventory_id":1,"make":"Ford","model":"Ranger","year":2023,"color":"Blue","vin":"VIN-API-11"}`)
+	if reused.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reused key status = %d, want %d", reused.Code, http.StatusUnprocessableEntity)
+	}
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+type InventoryHandler struct {
+	service service.InventoryService
+}
+
+func NewInventoryHandler(svc service.InventoryService) *InventoryHandler {
+	return &InventoryHandler{service: svc}
+}
+
+func (h *InventoryHandler) HandleInventories(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listInventories(w, r)
+	case http.MethodPost:
+		h.createInventory(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *InventoryHandler) HandleInventoryByID(w http.ResponseWriter, r *http.Request) {
+	id, sub, err := parseSubresource(r.URL.Path, "/api/inventories/")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch sub {
+	case "":
+	case "cars":
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		h.listInventoryCars(w, r, id)
+		return
+	case "aging":
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		h.agingStock(w, r, id)
+		return
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getInventoryByID(w, r, id)
+	case http.MethodPut:
+		h.updateInventory(w, r, id)
+	case http.MethodDelete:
+		h.deleteInventory(w, r, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *InventoryHandler) listInventories(w http.ResponseWriter, r *http.Request) {
+	inventories, err := h.service.GetAll(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch inventories")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, inventories)
+}
+
+func (h *InventoryHandler) getInventoryByID(w http.ResponseWriter, r *http.Request, id int64) {
+	inventory, err := h.service.GetByID(r.Context(), id)
+	if errors.Is(err, service.ErrInventoryNotFound) {
+		writeError(w, http.StatusNotFound, "inventory not found")
+		return
+	}
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch inventory")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, inventory)
+}
+
+func (h *InventoryHandler) listInventoryCars(w http.ResponseWriter, r *http.Request, id int64) {
+	cars, err := h.service.ListCars(r.Context(), id)
+	if errors.Is(err, service.ErrInventoryNotFound) {
+		writeError(w, http.StatusNotFound, "inventory not found")
+		return
+	}
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch inventory cars")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, cars)
+}
+
+func (h *InventoryHandler) agingStock(w http.ResponseWriter, r *http.Request, id int64) {
+	values := r.URL.Query()
+	days, err := parseIntQuery(values, "days")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := parseIntQuery(values, "limit")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := h.service.AgingStock(r.Context(), id, days, limit, values.Get("cursor"))
+	if errors.Is(err, service.ErrInventoryNotFound) {
+		writeError(w, http.StatusNotFound, "inventory not found")
+		return
+	}
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch aging stock")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, page)
+}
+
+func (h *InventoryHandler) createInventory(w http.ResponseWriter, r *http.Request) {
+	var in models.Inventory
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeFail(w, http.StatusBadRequest, errInvalidJSONBody)
+		return
+	}
+
+	created, err := h.service.Create(r.Context(), &in)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create inventory")
+		return
+	}
+
+	writeSuccess(w, http.StatusCreated, created)
+}
+
+func (h *InventoryHandler) updateInventory(w http.ResponseWriter, r *http.Request, id int64) {
+	var in models.Inventory
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeFail(w, http.StatusBadRequest, errInvalidJSONBody)
+		return
+	}
+	in.ID = id
+
+	updated, err := h.service.Update(r.Context(), &in)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrInventoryNotFound) {
+		writeError(w, http.StatusNotFound, "inventory not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to update inventory")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, updated)
+}
+
+func (h *InventoryHandler) deleteInventory(w http.ResponseWriter, r *http.Request, id int64) {
+	err := h.service.Delete(r.Context(), id)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrInventoryNotFound) {
+		writeError(w, http.StatusNotFound, "inventory not found")
+		return
+	}
+	if errors.Is(err, service.ErrInventoryInUse) {
+		writeFail(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete inventory")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, map[string]bool{"deleted": true})
+}
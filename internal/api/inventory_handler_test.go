@@ -0,0 +1,208 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+type fakeInventoryService struct {
+	inventories map[int64]*models.Inventory
+	withCars    map[int64]bool
+	nextID      int64
+	aging       []any
+}
+
+func newFakeInventoryService() *fakeInventoryService {
+	return &fakeInventoryService{inventories: map[int64]*models.Inventory{}, nextID: 1}
+}
+
+func (f *fakeInventoryService) Create(_ context.Context, inventory *models.Inventory) (*models.Inventory, error) {
+	if inventory.Name == "" {
+		return nil, service.ErrValidation
+	}
+	inventory.ID = f.nextID
+	f.nextID++
+	copyInventory := *inventory
+	f.inventories[inventory.ID] = &copyInventory
+	return &copyInventory, nil
+}
+
+func (f *fakeInventoryService) GetByID(_ context.Context, id int64) (*models.Inventory, error) {
+	inventory, ok := f.inventories[id]
+	if !ok {
+		return nil, service.ErrInventoryNotFound
+	}
+	copyInventory := *inventory
+	return &copyInventory, nil
+}
+
+func (f *fakeInventoryService) GetAll(_ context.Context) ([]*models.Inventory, error) {
+	out := make([]*models.Inventory, 0, len(f.inventories))
+	for id := int64(1); id <= f.nextID; id++ {
+		if inventory, ok := f.inventories[id]; ok {
+			copyInventory := *inventory
+			out = append(out, &copyInventory)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeInventoryService) Update(_ context.Context, inventory *models.Inventory) (*models.Inventory, error) {
+	if _, ok := f.inventories[inventory.ID]; !ok {
+		return nil, service.ErrInventoryNotFound
+	}
+	if inventory.Name == "" {
+		return nil, service.ErrValidation
+	}
+	copyInventory := *inventory
+	f.inventories[inventory.ID] = &copyInventory
+	return &copyInventory, nil
+}
+
+func (f *fakeInventoryService) Delete(_ context.Context, id int64) error {
+	if _, ok := f.inventories[id]; !ok {
+		return service.ErrInventoryNotFound
+	}
+	if f.withCars[id] {
+		return service.ErrInventoryInUse
+	}
+	delete(f.inventories, id)
+	return nil
+}
+
+func (f *fakeInventoryService) ListCars(_ context.Context, id int64) ([]*models.Car, error) {
+	if _, ok := f.inventories[id]; !ok {
+		return nil, service.ErrInventoryNotFound
+	}
+	return []*models.Car{{ID: 1, InventoryID: id, Make: "Mini", Model: "Cooper", Year: 2020, Color: "Red", VIN: "VIN-INV-API-1"}}, nil
+}
+
+func (f *fakeInventoryService) AgingStock(_ context.Context, id int64, days, limit int, cursor string) (*models.CarPage, error) {
+	if _, ok := f.inventories[id]; !ok {
+		return nil, service.ErrInventoryNotFound
+	}
+	if days < 0 {
+		return nil, service.ValidationErrors{"days": {"must be between 1 and 3650"}}
+	}
+	f.aging = []any{days, limit, cursor}
+	return &models.CarPage{Cars: []*models.Car{{ID: 1, InventoryID: id, Make: "Mini", Model: "Cooper", Year: 2020, Color: "Red", VIN: "VIN-INV-API-1"}}, Total: 1}, nil
+}
+
+func TestCreateInventoryHandler(t *testing.T) {
+	h := NewInventoryHandler(newFakeInventoryService())
+	req := httptest.NewRequest(http.MethodPost, "/api/inventories", bytes.NewReader([]byte(`{"name":"North Lot"}`)))
+	rec := httptest.NewRecorder()
+
+	h.HandleInventories(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+}
+
+func TestInventoryByIDHandlers(t *testing.T) {
+	fake := newFakeInventoryService()
+	created, _ := fake.Create(context.Background(), &models.Inventory{Name: "South Lot"})
+	h := NewInventoryHandler(fake)
+
+	updateReq := httptest.NewRequest(http.MethodPut, "/api/inventories/"+toString(created.ID), bytes.NewReader([]byte(`{"name":"South Annex"}`)))
+	updateRec := httptest.NewRecorder()
+	h.HandleInventoryByID(updateRec, updateReq)
+	if updateRec.Code != http.StatusOK {
+		t.Fatalf("update status = %d, want %d", updateRec.Code, http.StatusOK)
+	}
+
+	deleteReq := httptest.NewRequest(http.MethodDelete, "/api/inventories/"+toString(created.ID), nil)
+	deleteRec := httptest.NewRecorder()
+	h.HandleInventoryByID(deleteRec, deleteReq)
+	if deleteRec.Code != http.StatusOK {
+		t.Fatalf("delete status = %d, want %d", deleteRec.Code, http.StatusOK)
+	}
+
+	getReq := httptest.NewRequest(http.MethodGet, "/api/inventories/"+toString(created.ID), nil)
+	getRec := httptest.NewRecorder()
+	h.HandleInventoryByID(getRec, getReq)
+	if getRec.Code != http.StatusNotFound {
+		t.Fatalf("get status = %d, want %d", getRec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDeleteInventoryWithCarsHandler(t *testing.T) {
+	fake := newFakeInventoryService()
+	created, _ := fake.Create(context.Background(), &models.Inventory{Name: "West Lot"})
+	fake.withCars = map[int64]bool{created.ID: true}
+	h := NewInventoryHandler(fake)
+
+	rec := httptest.NewRecorder()
+	h.HandleInventoryByID(rec, httptest.NewRequest(http.MethodDelete, "/api/inventories/"+toString(created.ID), nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("delete status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	var resp struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Status != "fail" || resp.Message != service.ErrInventoryInUse.Error() {
+		t.Fatalf("delete body = %+v, %v; want a fail saying the inventory still has cars", resp, err)
+	}
+}
+
+func TestListInventoryCarsHandler(t *testing.T) {
+	fake := newFakeInventoryService()
+	created, _ := fake.Create(context.Background(), &models.Inventory{Name: "East Lot"})
+	h := NewInventoryHandler(fake)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/inventories/"+toString(created.ID)+"/cars", nil)
+	rec := httptest.NewRecorder()
+	h.HandleInventoryByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	missingReq := httptest.NewRequest(http.MethodGet, "/api/inventories/99/cars", nil)
+	missingRec := httptest.NewRecorder()
+	h.HandleInventoryByID(missingRec, missingReq)
+	if missingRec.Code != http.StatusNotFound {
+		t.Fatalf("missing status = %d, want %d", missingRec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAgingStockHandler(t *testing.T) {
+	fake := newFakeInventoryService()
+	created, _ := fake.Create(context.Background(), &models.Inventory{Name: "East Lot"})
+	h := NewInventoryHandler(fake)
+
+	rec := httptest.NewRecorder()
+	h.HandleInventoryByID(rec, httptest.NewRequest(http.MethodGet, "/api/inventories/"+toString(created.ID)+"/aging?days=60&limit=10&cursor=abc", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !slices.Equal(fake.aging, []any{60, 10, "abc"}) {
+		t.Fatalf("AgingStock() called with %v, want days 60, limit 10 and cursor abc", fake.aging)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+	}{
+		{name: "invalid days", path: "/api/inventories/" + toString(created.ID) + "/aging?days=many", wantCode: http.StatusBadRequest},
+		{name: "negative days", path: "/api/inventories/" + toString(created.ID) + "/aging?days=-1", wantCode: http.StatusBadRequest},
+		{name: "unknown inventory", path: "/api/inventories/99/aging", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleInventoryByID(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+	}
+}
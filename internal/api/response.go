@@ -2,9 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"carsapi/internal/service"
 )
 
+var errInvalidJSONBody = errors.New("invalid json body")
+
 type jsendResponse struct {
 	Status  string `json:"status"`
 	Data    any    `json:"data,omitempty"`
@@ -15,8 +20,15 @@ func writeSuccess(w http.ResponseWriter, code int, data any) {
 	writeJSON(w, code, jsendResponse{Status: "success", Data: data})
 }
 
-func writeFail(w http.ResponseWriter, code int, message string) {
-	writeJSON(w, code, jsendResponse{Status: "fail", Message: message})
+// writeFail reports err as a JSend fail. Errors that name request fields are
+// also emitted in data as a field to messages map.
+func writeFail(w http.ResponseWriter, code int, err error) {
+	resp := jsendResponse{Status: "fail", Message: err.Error()}
+	if data := failData(err); data != nil {
+		resp.Data = data
+	}
+
+	writeJSON(w, code, resp)
 }
 
 func writeError(w http.ResponseWriter, code int, message string) {
@@ -28,3 +40,17 @@ func writeJSON(w http.ResponseWriter, code int, payload jsendResponse) {
 	w.WriteHeader(code)
 	_ = json.NewEncoder(w).Encode(payload)
 }
+
+func failData(err error) map[string][]string {
+	var problems service.ValidationErrors
+	if errors.As(err, &problems) {
+		return problems
+	}
+
+	var fieldErr *service.FieldError
+	if errors.As(err, &fieldErr) {
+		return map[string][]string{fieldErr.Field: {fieldErr.Err.Error()}}
+	}
+
+	return nil
+}
@@ -1,8 +1,35 @@
 package api
 
-import "net/http"
+import (
+	"net/http"
 
-func RegisterRoutes(mux *http.ServeMux, handler *CarHandler) {
-	mux.HandleFunc("/api/cars", handler.HandleCars)
-	mux.HandleFunc("/api/cars/", handler.HandleCarByID)
+	"carsapi/internal/service"
+)
+
+func RegisterRoutes(mux *http.ServeMux, cars *CarHandler, attachments *AttachmentHandler, serviceRecords *ServiceRecordHandler, inventories *InventoryHandler, sales *SaleHandler, vins *VINHandler, catalog *CatalogHandler, idempotency service.IdempotencyService) {
+	mux.HandleFunc("/api/cars", Idempotent(idempotency, cars.HandleCars))
+	mux.HandleFunc("/api/cars/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case isCarSubresource(r.URL.Path, "attachments"):
+			attachments.HandleAttachments(w, r)
+		case isCarSubresource(r.URL.Path, "service-records"):
+			serviceRecords.HandleServiceRecords(w, r)
+		case isCarSubresource(r.URL.Path, "sell"):
+			Idempotent(idempotency, sales.HandleSellCar)(w, r)
+		default:
+			cars.HandleCarByID(w, r)
+		}
+	})
+	mux.HandleFunc("/api/cars/bulk", cars.HandleBulk)
+	mux.HandleFunc("/api/cars/search", cars.HandleSearch)
+	mux.HandleFunc("/api/cars/trash", cars.HandleTrash)
+	mux.HandleFunc("/api/cars/trash/", cars.HandleTrashedCar)
+	mux.HandleFunc("/api/cars/vin/", cars.HandleCarByVIN)
+	mux.HandleFunc("/api/inventories", inventories.HandleInventories)
+	mux.HandleFunc("/api/inventories/", inventories.HandleInventoryByID)
+	mux.HandleFunc("/api/sales", Idempotent(idempotency, sales.HandleSales))
+	mux.HandleFunc("/api/sales/", sales.HandleSaleByID)
+	mux.HandleFunc("/api/sales/car/", sales.HandleCarSale)
+	mux.HandleFunc("/api/vin/", vins.HandleVIN)
+	mux.HandleFunc("/api/catalog/", catalog.HandleCatalog)
 }
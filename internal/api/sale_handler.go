@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+type SaleHandler struct {
+	service service.SaleService
+}
+
+func NewSaleHandler(svc service.SaleService) *SaleHandler {
+	return &SaleHandler{service: svc}
+}
+
+func (h *SaleHandler) HandleSales(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listSales(w, r)
+	case http.MethodPost:
+		h.createSale(w, r, 0)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *SaleHandler) HandleSaleByID(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r.URL.Path, "/api/sales/")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	sale, err := h.service.GetByID(r.Context(), id)
+	h.writeSale(w, sale, err)
+}
+
+func (h *SaleHandler) HandleCarSale(w http.ResponseWriter, r *http.Request) {
+	carID, err := parseID(r.URL.Path, "/api/sales/car/")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	sale, err := h.service.GetByCarID(r.Context(), carID)
+	h.writeSale(w, sale, err)
+}
+
+// HandleSellCar serves POST /api/cars/{id}/sell, which records a sale of the
+// car in the path just as POST /api/sales does.
+func (h *SaleHandler) HandleSellCar(w http.ResponseWriter, r *http.Request) {
+	carID, sub, err := parseSubresource(r.URL.Path, "/api/cars/")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if sub != "sell" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	h.createSale(w, r, carID)
+}
+
+func (h *SaleHandler) writeSale(w http.ResponseWriter, sale *models.Sale, err error) {
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrSaleNotFound) {
+		writeError(w, http.StatusNotFound, "sale not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch sale")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, sale)
+}
+
+func (h *SaleHandler) listSales(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseSaleFilter(r.URL.Query())
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sales, err := h.service.GetAll(r.Context(), filter)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch sales")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, sales)
+}
+
+// createSale records the sale in the request body. A carID other than 0
+// names the car sold, in place of the car_id of the body.
+func (h *SaleHandler) createSale(w http.ResponseWriter, r *http.Request, carID int64) {
+	var in models.Sale
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeFail(w, http.StatusBadRequest, errInvalidJSONBody)
+		return
+	}
+	if carID != 0 {
+		in.CarID = carID
+	}
+
+	created, err := h.service.Create(r.Context(), &in)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	var fieldErr *service.FieldError
+	if carID != 0 && errors.Is(err, service.ErrReferenceNotFound) && errors.As(err, &fieldErr) && fieldErr.Field == "car_id" {
+		writeError(w, http.StatusNotFound, "car not found")
+		return
+	}
+	if errors.Is(err, service.ErrReferenceNotFound) {
+		writeFail(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidTransition) || errors.Is(err, service.ErrConflict) {
+		writeFail(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to record sale")
+		return
+	}
+
+	writeSuccess(w, http.StatusCreated, created)
+}
+
+func parseSaleFilter(values url.Values) (models.SaleFilter, error) {
+	filter := models.SaleFilter{Salesperson: values.Get("salesperson")}
+
+	var err error
+	if filter.SoldFrom, err = parseTimeQuery(values, "sold_from"); err != nil {
+		return filter, err
+	}
+	if filter.SoldTo, err = parseTimeQuery(values, "sold_to"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
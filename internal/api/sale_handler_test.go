@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+type fakeSaleService struct {
+	sales  []*models.Sale
+	filter models.SaleFilter
+}
+
+func (f *fakeSaleService) Create(_ context.Context, sale *models.Sale) (*models.Sale, error) {
+	if sale.BuyerName == "" {
+		return nil, service.ValidationErrors{"buyer_name": {"is required"}}
+	}
+	if sale.CarID == 999 {
+		return nil, &service.FieldError{Field: "car_id", Err: service.ErrReferenceNotFound}
+	}
+	for _, existing := range f.sales {
+		if existing.CarID == sale.CarID {
+			return nil, service.ErrInvalidTransition
+		}
+	}
+	sale.ID = int64(len(f.sales) + 1)
+	f.sales = append(f.sales, sale)
+	return sale, nil
+}
+
+func (f *fakeSaleService) GetByID(_ context.Context, id int64) (*models.Sale, error) {
+	for _, sale := range f.sales {
+		if sale.ID == id {
+			return sale, nil
+		}
+	}
+	return nil, service.ErrSaleNotFound
+}
+
+func (f *fakeSaleService) GetByCarID(_ context.Context, carID int64) (*models.Sale, error) {
+	for _, sale := range f.sales {
+		if sale.CarID == carID {
+			return sale, nil
+		}
+	}
+	return nil, service.ErrSaleNotFound
+}
+
+func (f *fakeSaleService) GetAll(_ context.Context, filter models.SaleFilter) ([]*models.Sale, error) {
+	f.filter = filter
+	return f.sales, nil
+}
+
+func TestCreateSaleHandler(t *testing.T) {
+	h := NewSaleHandler(&fakeSaleService{})
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "invalid json", body: `{`, wantCode: http.StatusBadRequest},
+		{name: "invalid sold_at", body: `{"car_id":1,"buyer_name":"Ana","sold_at":"yesterday"}`, wantCode: http.StatusBadRequest},
+		{name: "missing buyer", body: `{"car_id":1}`, wantCode: http.StatusBadRequest},
+		{name: "unknown car", body: `{"car_id":999,"buyer_name":"Ana"}`, wantCode: http.StatusUnprocessableEntity},
+		{name: "sale", body: `{"car_id":1,"buyer_name":"Ana","sale_price":2350000,"sold_at":"2026-02-14T10:00:00Z"}`, wantCode: http.StatusCreated},
+		{name: "already sold", body: `{"car_id":1,"buyer_name":"Ben"}`, wantCode: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleSales(rec, httptest.NewRequest(http.MethodPost, "/api/sales", strings.NewReader(tt.body)))
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body = %s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+	}
+}
+
+func TestSellCarHandler(t *testing.T) {
+	fake := &fakeSaleService{}
+	h := NewSaleHandler(fake)
+
+	tests := []struct {
+		name     string
+		path     string
+		method   string
+		body     string
+		wantCode int
+	}{
+		{name: "invalid id", path: "/api/cars/abc/sell", method: http.MethodPost, body: `{}`, wantCode: http.StatusBadRequest},
+		{name: "wrong method", path: "/api/cars/1/sell", method: http.MethodGet, wantCode: http.StatusMethodNotAllowed},
+		{name: "unknown car", path: "/api/cars/999/sell", method: http.MethodPost, body: `{"buyer_name":"Ana"}`, wantCode: http.StatusNotFound},
+		{name: "sale", path: "/api/cars/1/sell", method: http.MethodPost, body: `{"car_id":7,"buyer_name":"Ana","sale_price":2350000}`, wantCode: http.StatusCreated},
+		{name: "already sold", path: "/api/cars/1/sell", method: http.MethodPost, body: `{"buyer_name":"Ben"}`, wantCode: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleSellCar(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body = %s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+	}
+
+	// The car in the path wins over a car_id in the body.
+	if len(fake.sales) != 1 || fake.sales[0].CarID != 1 {
+		t.Fatalf("sales = %+v, want one of car 1", fake.sales)
+	}
+}
+
+func TestSaleLookupHandlers(t *testing.T) {
+	fake := &fakeSaleService{}
+	_, _ = fake.Create(context.Background(), &models.Sale{CarID: 7, BuyerName: "Ana"})
+	h := NewSaleHandler(fake)
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		path     string
+		wantCode int
+	}{
+		{name: "by id", handler: h.HandleSaleByID, path: "/api/sales/1", wantCode: http.StatusOK},
+		{name: "missing id", handler: h.HandleSaleByID, path: "/api/sales/2", wantCode: http.StatusNotFound},
+		{name: "invalid id", handler: h.HandleSaleByID, path: "/api/sales/abc", wantCode: http.StatusBadRequest},
+		{name: "by car", handler: h.HandleCarSale, path: "/api/sales/car/7", wantCode: http.StatusOK},
+		{name: "unsold car", handler: h.HandleCarSale, path: "/api/sales/car/8", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.HandleSales(rec, httptest.NewRequest(http.MethodGet, "/api/sales?salesperson=sam&sold_from=2026-02-01T00:00:00Z", nil))
+	var resp struct {
+		Data []models.Sale `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK || len(resp.Data) != 1 {
+		t.Fatalf("list status = %d, sales = %+v, decode error = %v", rec.Code, resp.Data, err)
+	}
+	if fake.filter.Salesperson != "sam" || fake.filter.SoldFrom.IsZero() {
+		t.Fatalf("filter = %+v, want salesperson and sold_from set", fake.filter)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleSales(rec, httptest.NewRequest(http.MethodGet, "/api/sales?sold_to=March", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid sold_to status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
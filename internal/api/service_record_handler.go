@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+type ServiceRecordHandler struct {
+	service service.ServiceRecordService
+}
+
+func NewServiceRecordHandler(svc service.ServiceRecordService) *ServiceRecordHandler {
+	return &ServiceRecordHandler{service: svc}
+}
+
+// HandleServiceRecords serves /api/cars/{id}/service-records and
+// /api/cars/{id}/service-records/{record_id}.
+func (h *ServiceRecordHandler) HandleServiceRecords(w http.ResponseWriter, r *http.Request) {
+	carID, sub, err := parseSubresource(r.URL.Path, "/api/cars/")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rest := strings.TrimPrefix(strings.TrimPrefix(sub, "service-records"), "/")
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			h.listServiceRecords(w, r, carID)
+		case http.MethodPost:
+			h.createServiceRecord(w, r, carID)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	id, err := parseID(rest, "")
+	if err != nil {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		record, err := h.service.GetByID(r.Context(), carID, id)
+		if err != nil {
+			writeServiceRecordError(w, err, "failed to fetch service record")
+			return
+		}
+		writeSuccess(w, http.StatusOK, record)
+	case http.MethodDelete:
+		if err := h.service.Delete(r.Context(), carID, id); err != nil {
+			writeServiceRecordError(w, err, "failed to delete service record")
+			return
+		}
+		writeSuccess(w, http.StatusOK, map[string]bool{"deleted": true})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *ServiceRecordHandler) listServiceRecords(w http.ResponseWriter, r *http.Request, carID int64) {
+	records, err := h.service.List(r.Context(), carID)
+	if err != nil {
+		writeServiceRecordError(w, err, "failed to fetch service records")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, records)
+}
+
+func (h *ServiceRecordHandler) createServiceRecord(w http.ResponseWriter, r *http.Request, carID int64) {
+	var in models.ServiceRecord
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeFail(w, http.StatusBadRequest, errInvalidJSONBody)
+		return
+	}
+	in.CarID = carID
+
+	created, err := h.service.Create(r.Context(), &in)
+	if err != nil {
+		writeServiceRecordError(w, err, "failed to record service")
+		return
+	}
+
+	writeSuccess(w, http.StatusCreated, created)
+}
+
+func writeServiceRecordError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		writeFail(w, http.StatusBadRequest, err)
+	case errors.Is(err, service.ErrCarNotFound):
+		writeError(w, http.StatusNotFound, "car not found")
+	case errors.Is(err, service.ErrServiceRecordNotFound):
+		writeError(w, http.StatusNotFound, "service record not found")
+	default:
+		writeError(w, http.StatusInternalServerError, message)
+	}
+}
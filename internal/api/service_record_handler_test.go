@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+type fakeServiceRecordService struct {
+	records []*models.ServiceRecord
+}
+
+func (f *fakeServiceRecordService) Create(_ context.Context, record *models.ServiceRecord) (*models.ServiceRecord, error) {
+	if record.CarID == 999 {
+		return nil, service.ErrCarNotFound
+	}
+	if record.Kind == "" {
+		return nil, service.ValidationErrors{"kind": {"must be one of inspection, maintenance, repair"}}
+	}
+	record.ID = int64(len(f.records) + 1)
+	f.records = append(f.records, record)
+	return record, nil
+}
+
+func (f *fakeServiceRecordService) List(_ context.Context, carID int64) ([]*models.ServiceRecord, error) {
+	if carID == 999 {
+		return nil, service.ErrCarNotFound
+	}
+	out := make([]*models.ServiceRecord, 0)
+	for _, record := range f.records {
+		if record.CarID == carID {
+			out = append(out, record)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeServiceRecordService) GetByID(_ context.Context, carID, id int64) (*models.ServiceRecord, error) {
+	for _, record := range f.records {
+		if record.ID == id && record.CarID == carID {
+			return record, nil
+		}
+	}
+	return nil, service.ErrServiceRecordNotFound
+}
+
+func (f *fakeServiceRecordService) Delete(ctx context.Context, carID, id int64) error {
+	_, err := f.GetByID(ctx, carID, id)
+	return err
+}
+
+func TestServiceRecordHandler(t *testing.T) {
+	fake := &fakeServiceRecordService{}
+	h := NewServiceRecordHandler(fake)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{name: "invalid json", method: http.MethodPost, path: "/api/cars/1/service-records", body: `{`, wantCode: http.StatusBadRequest},
+		{name: "invalid performed_at", method: http.MethodPost, path: "/api/cars/1/service-records", body: `{"kind":"repair","performed_at":"last week"}`, wantCode: http.StatusBadRequest},
+		{name: "missing kind", method: http.MethodPost, path: "/api/cars/1/service-records", body: `{"mileage":1000}`, wantCode: http.StatusBadRequest},
+		{name: "unknown car", method: http.MethodPost, path: "/api/cars/999/service-records", body: `{"kind":"repair"}`, wantCode: http.StatusNotFound},
+		{name: "create", method: http.MethodPost, path: "/api/cars/1/service-records", body: `{"kind":"repair","mileage":1000,"cost":5000}`, wantCode: http.StatusCreated},
+		{name: "get", method: http.MethodGet, path: "/api/cars/1/service-records/1", wantCode: http.StatusOK},
+		{name: "other car", method: http.MethodGet, path: "/api/cars/2/service-records/1", wantCode: http.StatusNotFound},
+		{name: "invalid id", method: http.MethodGet, path: "/api/cars/1/service-records/abc", wantCode: http.StatusBadRequest},
+		{name: "list missing car", method: http.MethodGet, path: "/api/cars/999/service-records", wantCode: http.StatusNotFound},
+		{name: "delete", method: http.MethodDelete, path: "/api/cars/1/service-records/1", wantCode: http.StatusOK},
+		{name: "put", method: http.MethodPut, path: "/api/cars/1/service-records/1", wantCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		var body io.Reader
+		if tt.body != "" {
+			body = strings.NewReader(tt.body)
+		}
+		rec := httptest.NewRecorder()
+		h.HandleServiceRecords(rec, httptest.NewRequest(tt.method, tt.path, body))
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body = %s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.HandleServiceRecords(rec, httptest.NewRequest(http.MethodGet, "/api/cars/1/service-records", nil))
+	var resp struct {
+		Data []models.ServiceRecord `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK || len(resp.Data) != 1 || resp.Data[0].CarID != 1 {
+		t.Fatalf("list status = %d, records = %+v, decode error = %v", rec.Code, resp.Data, err)
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"carsapi/internal/service"
+)
+
+type VINHandler struct {
+	service service.VINService
+}
+
+func NewVINHandler(svc service.VINService) *VINHandler {
+	return &VINHandler{service: svc}
+}
+
+func (h *VINHandler) HandleVIN(w http.ResponseWriter, r *http.Request) {
+	vin, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/vin/"), "/")
+	if sub != "decode" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	decoded, err := h.service.Decode(r.Context(), vin)
+	if errors.Is(err, service.ErrValidation) {
+		writeFail(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to decode vin")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, decoded)
+}
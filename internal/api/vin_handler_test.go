@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"carsapi/internal/models"
+	"carsapi/internal/service"
+)
+
+func TestDecodeVINHandler(t *testing.T) {
+	h := NewVINHandler(service.NewVINService())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/vin/1M8GDM9AXKP042788/decode", nil)
+	rec := httptest.NewRecorder()
+	h.HandleVIN(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp struct {
+		Data models.DecodedVIN `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.ModelYear != 1989 || resp.Data.WMI != "1M8" || !resp.Data.CheckDigitValid {
+		t.Fatalf("decoded = %+v", resp.Data)
+	}
+
+	for path, want := range map[string]int{
+		"/api/vin/TOO-SHORT/decode":       http.StatusBadRequest,
+		"/api/vin/1M8GDM9AXKP042788":      http.StatusNotFound,
+		"/api/vin/1M8GDM9AXKP042788/info": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		h.HandleVIN(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Fatalf("GET %s status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
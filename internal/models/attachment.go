@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Attachment is a photo or document stored for a car. The file itself lives
+// in a file store under StorageKey, with an optional thumbnail for images.
+type Attachment struct {
+	ID           int64     `json:"id"`
+	CarID        int64     `json:"car_id"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	HasThumbnail bool      `json:"has_thumbnail"`
+	CreatedAt    time.Time `json:"created_at"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+}
@@ -0,0 +1,8 @@
+package models
+
+type BulkOperation struct {
+	Op      string `json:"op"`
+	ID      int64  `json:"id,omitempty"`
+	Version int64  `json:"version,omitempty"`
+	Car     *Car   `json:"car,omitempty"`
+}
@@ -1,11 +1,34 @@
 package models
 
+import "time"
+
+const (
+	StatusInStock   = "in_stock"
+	StatusReserved  = "reserved"
+	StatusSold      = "sold"
+	StatusInTransit = "in_transit"
+	StatusRetired   = "retired"
+)
+
 type Car struct {
-	ID          int64  `json:"id"`
-	InventoryID int64  `json:"inventory_id"`
-	Make        string `json:"make"`
-	Model       string `json:"model"`
-	Year        int    `json:"year"`
-	Color       string `json:"color"`
-	VIN         string `json:"vin"`
+	ID             int64             `json:"id"`
+	InventoryID    int64             `json:"inventory_id"`
+	Make           string            `json:"make"`
+	Model          string            `json:"model"`
+	Year           int               `json:"year"`
+	Color          string            `json:"color"`
+	VIN            string            `json:"vin"`
+	Status         string            `json:"status"`
+	Price          int64             `json:"price"`
+	Currency       string            `json:"currency"`
+	PriceChangedAt time.Time         `json:"price_changed_at"`
+	Mileage        int64             `json:"mileage"`
+	ReservedUntil  *time.Time        `json:"reserved_until,omitempty"`
+	Attributes     map[string]string `json:"attributes"`
+	Tags           []string          `json:"tags"`
+	ServiceSummary *ServiceSummary   `json:"service_summary,omitempty"`
+	Version        int64             `json:"version"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	DeletedAt      *time.Time        `json:"deleted_at,omitempty"`
 }
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+type CarFilter struct {
+	Make               string
+	Model              string
+	Color              string
+	Status             string
+	InventoryID        int64
+	YearMin            int
+	YearMax            int
+	SortBy             string
+	SortDesc           bool
+	Limit              int
+	Cursor             string
+	AsOf               time.Time
+	CreatedAfter       time.Time
+	UpdatedSince       time.Time
+	PriceChangedBefore time.Time
+	Tags               []string
+	Attributes         map[string]string
+}
+
+type CarPage struct {
+	Cars       []*Car `json:"cars"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
+}
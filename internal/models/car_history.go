@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	CarCreated     = "create"
+	CarUpdated     = "update"
+	CarTransferred = "transfer"
+	CarDeleted     = "delete"
+	CarRestored    = "restore"
+	CarPurged      = "purge"
+	CarServiced    = "service"
+)
+
+// CarHistoryEntry is one recorded change to a car. Old is nil for a create
+// and New is nil when the change removed the car; Changes is derived from
+// the two when the history is read.
+type CarHistoryEntry struct {
+	ID        int64         `json:"id"`
+	CarID     int64         `json:"car_id"`
+	Action    string        `json:"action"`
+	Actor     string        `json:"actor"`
+	ChangedAt time.Time     `json:"changed_at"`
+	Changes   []FieldChange `json:"changes"`
+	Old       *Car          `json:"-"`
+	New       *Car          `json:"-"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
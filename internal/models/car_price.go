@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// PriceChange records a car's asking price from ChangedAt on. Price is in
+// minor units of Currency, like Car.Price.
+type PriceChange struct {
+	ID        int64     `json:"id"`
+	CarID     int64     `json:"car_id"`
+	Price     int64     `json:"price"`
+	Currency  string    `json:"currency"`
+	ChangedBy string    `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// CatalogMake is a make in the reference catalog. Aliases are other
+// spellings of it, such as "VW" for Volkswagen.
+type CatalogMake struct {
+	ID      int64          `json:"id"`
+	Name    string         `json:"name"`
+	Aliases []string       `json:"aliases"`
+	Models  []CatalogModel `json:"models,omitempty"`
+}
+
+type CatalogModel struct {
+	ID    int64    `json:"id"`
+	Name  string   `json:"name"`
+	Trims []string `json:"trims"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is the stored outcome of a request made with an
+// Idempotency-Key header. StatusCode is 0 while the original request is
+// still being processed, which was last claimed at ReservedAt.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	StatusCode  int
+	Headers     map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+	ReservedAt  time.Time
+}
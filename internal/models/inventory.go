@@ -0,0 +1,6 @@
+package models
+
+type Inventory struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
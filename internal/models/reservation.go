@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Reservation holds a car for a customer until ExpiresAt. It stays active
+// until ReleasedAt is set, with ReleaseReason naming what ended it.
+type Reservation struct {
+	ID            int64      `json:"id"`
+	CarID         int64      `json:"car_id"`
+	Customer      string     `json:"customer"`
+	ReservedBy    string     `json:"reserved_by"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty"`
+	ReleaseReason string     `json:"release_reason,omitempty"`
+}
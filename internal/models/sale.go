@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Sale records a car leaving the lot. SalePrice is in minor units of
+// Currency, like Car.Price. ReservationID is set when the sale converted a
+// reservation that held the car.
+type Sale struct {
+	ID            int64     `json:"id"`
+	CarID         int64     `json:"car_id"`
+	BuyerName     string    `json:"buyer_name"`
+	SalePrice     int64     `json:"sale_price"`
+	Currency      string    `json:"currency"`
+	SoldAt        time.Time `json:"sold_at"`
+	Salesperson   string    `json:"salesperson"`
+	ReservationID *int64    `json:"reservation_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type SaleFilter struct {
+	Salesperson string
+	SoldFrom    time.Time
+	SoldTo      time.Time
+}
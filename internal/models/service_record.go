@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+const (
+	ServiceInspection  = "inspection"
+	ServiceMaintenance = "maintenance"
+	ServiceRepair      = "repair"
+)
+
+// ServiceRecord logs work done on a car. Cost is in minor units of
+// Currency, like Car.Price.
+type ServiceRecord struct {
+	ID          int64     `json:"id"`
+	CarID       int64     `json:"car_id"`
+	Kind        string    `json:"kind"`
+	Description string    `json:"description"`
+	PerformedAt time.Time `json:"performed_at"`
+	Mileage     int64     `json:"mileage"`
+	Cost        int64     `json:"cost"`
+	Currency    string    `json:"currency"`
+	Vendor      string    `json:"vendor,omitempty"`
+	RecordedBy  string    `json:"recorded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ServiceSummary totals the service records of a car. ReconditioningCost is
+// the sum of their costs.
+type ServiceSummary struct {
+	Records            int64     `json:"records"`
+	ReconditioningCost int64     `json:"reconditioning_cost"`
+	Currency           string    `json:"currency"`
+	LastServiceAt      time.Time `json:"last_service_at"`
+}
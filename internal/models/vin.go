@@ -0,0 +1,12 @@
+package models
+
+type DecodedVIN struct {
+	VIN             string `json:"vin"`
+	WMI             string `json:"wmi"`
+	Manufacturer    string `json:"manufacturer,omitempty"`
+	Country         string `json:"country,omitempty"`
+	ModelYear       int    `json:"model_year"`
+	PlantCode       string `json:"plant_code"`
+	SerialNumber    string `json:"serial_number"`
+	CheckDigitValid bool   `json:"check_digit_valid"`
+}
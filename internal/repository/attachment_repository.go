@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"carsapi/internal/models"
+)
+
+type AttachmentRepository interface {
+	Create(ctx context.Context, attachment *models.Attachment) error
+	GetByID(ctx context.Context, id int64) (*models.Attachment, error)
+	GetByCarID(ctx context.Context, carID int64) ([]*models.Attachment, error)
+	Delete(ctx context.Context, id int64) error
+}
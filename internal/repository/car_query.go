@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"carsapi/internal/models"
+)
+
+const (
+	selectCarsQuery = `SELECT id, inventory_id, make, model, year, color, vin, status, price, currency, price_changed_at, mileage, reserved_until, attributes, ` + carTagsColumn + `, service_summary, version, created_at, updated_at FROM cars`
+	countCarsQuery  = `SELECT COUNT(*) FROM cars`
+	searchCarsQuery = `SELECT cars.id, cars.inventory_id, cars.make, cars.model, cars.year, cars.color, cars.vin, cars.status, cars.price, cars.currency, cars.price_changed_at, cars.mileage, cars.reserved_until, cars.attributes, ` + carTagsColumn + `, cars.service_summary, cars.version, cars.created_at, cars.updated_at
+FROM cars_fts
+JOIN cars ON cars.id = cars_fts.rowid
+WHERE cars_fts MATCH ? AND cars.deleted_at IS NULL
+ORDER BY bm25(cars_fts, 2.0, 2.0, 1.0, 1.0), cars.id
+LIMIT ?`
+	upsertCarByVINQuery = `INSERT INTO cars (inventory_id, make, model, year, color, vin, status, price, currency, price_changed_at, mileage, attributes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?, ?)
+ON CONFLICT (vin) WHERE deleted_at IS NULL DO UPDATE SET inventory_id = excluded.inventory_id, make = excluded.make, model = excluded.model, year = excluded.year, color = excluded.color, price = excluded.price, currency = excluded.currency, price_changed_at = CASE WHEN price = excluded.price AND currency = excluded.currency THEN price_changed_at ELSE CURRENT_TIMESTAMP END, mileage = excluded.mileage, attributes = excluded.attributes, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE ? = 0 OR version = ?
+RETURNING id, version`
+	// carsAsOfQuery shadows the cars table with every car as it was last
+	// recorded in car_history at or before a moment, so that the regular car
+	// queries can be run against a past state. Cars that had been deleted by
+	// then have no new_values and drop out. car_tags and tags are shadowed
+	// the same way, with each tag name standing in for its id.
+	carsAsOfQuery = `WITH car_snapshots AS (
+SELECT new_values FROM car_history
+WHERE car_history.id IN (SELECT MAX(id) FROM car_history WHERE changed_at <= ? GROUP BY car_id) AND new_values IS NOT NULL
+), cars AS (
+SELECT json_extract(new_values, '$.id') AS id, json_extract(new_values, '$.inventory_id') AS inventory_id, json_extract(new_values, '$.make') AS make, json_extract(new_values, '$.model') AS model, json_extract(new_values, '$.year') AS year, json_extract(new_values, '$.color') AS color, json_extract(new_values, '$.vin') AS vin, COALESCE(json_extract(new_values, '$.status'), 'in_stock') AS status, COALESCE(json_extract(new_values, '$.price'), 0) AS price, COALESCE(json_extract(new_values, '$.currency'), '') AS currency, COALESCE(datetime(json_extract(new_values, '$.price_changed_at')), datetime(json_extract(new_values, '$.created_at'))) AS price_changed_at, COALESCE(json_extract(new_values, '$.mileage'), 0) AS mileage, datetime(json_extract(new_values, '$.reserved_until')) AS reserved_until, COALESCE(json_extract(new_values, '$.attributes'), '{}') AS attributes, json_extract(new_values, '$.service_summary') AS service_summary, json_extract(new_values, '$.version') AS version, datetime(json_extract(new_values, '$.created_at')) AS created_at, datetime(json_extract(new_values, '$.updated_at')) AS updated_at, NULL AS deleted_at
+FROM car_snapshots
+), car_tags AS (
+SELECT json_extract(new_values, '$.id') AS car_id, tag.value AS tag_id FROM car_snapshots, json_each(new_values, '$.tags') AS tag
+), tags AS (
+SELECT DISTINCT tag_id AS id, tag_id AS name FROM car_tags
+) `
+)
+
+type carSortColumn struct {
+	column  string
+	numeric bool
+}
+
+var carSortColumns = map[string]carSortColumn{
+	"id":           {column: "id", numeric: true},
+	"inventory_id": {column: "inventory_id", numeric: true},
+	"make":         {column: "make"},
+	"model":        {column: "model"},
+	"year":         {column: "year", numeric: true},
+	"color":        {column: "color"},
+	"vin":          {column: "vin"},
+	// price_changed_at is stored as CURRENT_TIMESTAMP text, so it sorts and
+	// compares as a string.
+	"price_changed_at": {column: "price_changed_at"},
+}
+
+// carCursor is the keyset position encoded into the opaque next_cursor
+// token. It records the sort it was produced for so that a cursor cannot be
+// replayed against a different ordering.
+type carCursor struct {
+	SortBy   string          `json:"s"`
+	SortDesc bool            `json:"d,omitempty"`
+	Value    json.RawMessage `json:"v"`
+	ID       int64           `json:"i"`
+}
+
+func buildListCarsQuery(filter models.CarFilter) (string, []any, error) {
+	sort, ok := carSortColumns[filter.SortBy]
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported sort field %q", filter.SortBy)
+	}
+
+	conditions, args := buildCarConditions(filter)
+
+	if filter.Cursor != "" {
+		value, id, err := decodeCarCursor(filter, sort)
+		if err != nil {
+			return "", nil, err
+		}
+
+		op := ">"
+		if filter.SortDesc {
+			op = "<"
+		}
+		if sort.column == "id" {
+			conditions = append(conditions, "id "+op+" ?")
+			args = append(args, id)
+		} else {
+			conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sort.column, op))
+			args = append(args, value, value, id)
+		}
+	}
+
+	dir := "ASC"
+	if filter.SortDesc {
+		dir = "DESC"
+	}
+
+	var b strings.Builder
+	args = writeAsOf(&b, filter.AsOf, args)
+	b.WriteString(selectCarsQuery)
+	writeWhere(&b, conditions)
+	if sort.column == "id" {
+		fmt.Fprintf(&b, " ORDER BY id %s", dir)
+	} else {
+		fmt.Fprintf(&b, " ORDER BY %s %s, id %s", sort.column, dir, dir)
+	}
+	b.WriteString(" LIMIT ?")
+	args = append(args, filter.Limit+1)
+
+	return b.String(), args, nil
+}
+
+func buildCountCarsQuery(filter models.CarFilter) (string, []any) {
+	conditions, args := buildCarConditions(filter)
+
+	var b strings.Builder
+	args = writeAsOf(&b, filter.AsOf, args)
+	b.WriteString(countCarsQuery)
+	writeWhere(&b, conditions)
+
+	return b.String(), args
+}
+
+func buildCarConditions(filter models.CarFilter) ([]string, []any) {
+	conditions := []string{"deleted_at IS NULL"}
+	args := make([]any, 0)
+
+	if filter.Make != "" {
+		conditions = append(conditions, "make = ? COLLATE NOCASE")
+		args = append(args, filter.Make)
+	}
+	if filter.Model != "" {
+		conditions = append(conditions, "model = ? COLLATE NOCASE")
+		args = append(args, filter.Model)
+	}
+	if filter.Color != "" {
+		conditions = append(conditions, "color = ? COLLATE NOCASE")
+		args = append(args, filter.Color)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.InventoryID > 0 {
+		conditions = append(conditions, "inventory_id = ?")
+		args = append(args, filter.InventoryID)
+	}
+	if filter.YearMin > 0 {
+		conditions = append(conditions, "year >= ?")
+		args = append(args, filter.YearMin)
+	}
+	if filter.YearMax > 0 {
+		conditions = append(conditions, "year <= ?")
+		args = append(args, filter.YearMax)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at > ?")
+		args = append(args, sqliteTime(filter.CreatedAfter))
+	}
+	if !filter.UpdatedSince.IsZero() {
+		conditions = append(conditions, "updated_at >= ?")
+		args = append(args, sqliteTime(filter.UpdatedSince))
+	}
+	if !filter.PriceChangedBefore.IsZero() {
+		conditions = append(conditions, "price_changed_at <= ?")
+		args = append(args, sqliteTime(filter.PriceChangedBefore))
+	}
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, carHasTagCondition)
+		args = append(args, tag)
+	}
+	names := make([]string, 0, len(filter.Attributes))
+	for name := range filter.Attributes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		conditions = append(conditions, "json_extract(attributes, ?) = ? COLLATE NOCASE")
+		args = append(args, attributePath(name), filter.Attributes[name])
+	}
+
+	return conditions, args
+}
+
+// writeAsOf points the query being built at the cars as they were at asOf,
+// unless it is zero, and returns args with the moment prepended.
+func writeAsOf(b *strings.Builder, asOf time.Time, args []any) []any {
+	if asOf.IsZero() {
+		return args
+	}
+
+	b.WriteString(carsAsOfQuery)
+	return append([]any{sqliteTime(asOf)}, args...)
+}
+
+func writeWhere(b *strings.Builder, conditions []string) {
+	if len(conditions) == 0 {
+		return
+	}
+
+	b.WriteString(" WHERE ")
+	b.WriteString(strings.Join(conditions, " AND "))
+}
+
+// buildCarSearchMatch turns free text such as "blu civ" into an FTS5 query
+// where every term is a quoted prefix match, so user input can never be
+// interpreted as FTS5 operators.
+func buildCarSearchMatch(text string) string {
+	terms := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, term := range terms {
+		terms[i] = `"` + term + `"*`
+	}
+
+	return strings.Join(terms, " AND ")
+}
+
+func encodeCarCursor(filter models.CarFilter, car *models.Car) (string, error) {
+	value, err := json.Marshal(carSortValue(car, filter.SortBy))
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(carCursor{SortBy: filter.SortBy, SortDesc: filter.SortDesc, Value: value, ID: car.ID})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+func decodeCarCursor(filter models.CarFilter, sort carSortColumn) (any, int64, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	var cursor carCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	if cursor.SortBy != filter.SortBy || cursor.SortDesc != filter.SortDesc || cursor.ID <= 0 {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	if sort.numeric {
+		var value int64
+		if err := json.Unmarshal(cursor.Value, &value); err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return value, cursor.ID, nil
+	}
+
+	var value string
+	if err := json.Unmarshal(cursor.Value, &value); err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	return value, cursor.ID, nil
+}
+
+func carSortValue(car *models.Car, sortBy string) any {
+	switch sortBy {
+	case "inventory_id":
+		return car.InventoryID
+	case "make":
+		return car.Make
+	case "model":
+		return car.Model
+	case "year":
+		return car.Year
+	case "color":
+		return car.Color
+	case "vin":
+		return car.VIN
+	case "price_changed_at":
+		return sqliteTime(car.PriceChangedAt)
+	default:
+		return car.ID
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"carsapi/internal/models"
 )
@@ -22,12 +23,41 @@ type DB interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 	QueryRowContext(ctx context.Context, query string, args ...any) Row
 	QueryContext(ctx context.Context, query string, args ...any) (Rows, error)
+	BeginTx(ctx context.Context) (Tx, error)
+}
+
+// Tx is a DB whose writes only become visible on Commit. Calling BeginTx on
+// a Tx nests a savepoint that can be rolled back on its own.
+type Tx interface {
+	DB
+	Commit() error
+	Rollback() error
 }
 
 type CarRepository interface {
 	Create(ctx context.Context, car *models.Car) error
 	GetByID(ctx context.Context, id int64) (*models.Car, error)
-	GetAll(ctx context.Context) ([]*models.Car, error)
+	GetByIDAsOf(ctx context.Context, id int64, asOf time.Time) (*models.Car, error)
+	GetByVIN(ctx context.Context, vin string) (*models.Car, error)
+	GetAll(ctx context.Context, filter models.CarFilter) ([]*models.Car, string, error)
+	Count(ctx context.Context, filter models.CarFilter) (int64, error)
+	Search(ctx context.Context, text string, limit int) ([]*models.Car, error)
+	GetByInventoryID(ctx context.Context, inventoryID int64) ([]*models.Car, error)
 	Update(ctx context.Context, car *models.Car) error
-	Delete(ctx context.Context, id int64) error
+	UpsertByVIN(ctx context.Context, car *models.Car) (bool, error)
+	UpdateStatus(ctx context.Context, car *models.Car) error
+	Transfer(ctx context.Context, id, inventoryID int64) error
+	Delete(ctx context.Context, id, version int64) error
+	GetDeleted(ctx context.Context) ([]*models.Car, error)
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, id int64) error
+	AddHistory(ctx context.Context, entry *models.CarHistoryEntry) error
+	GetHistory(ctx context.Context, carID int64) ([]*models.CarHistoryEntry, error)
+	AddPriceChange(ctx context.Context, change *models.PriceChange) error
+	GetPriceHistory(ctx context.Context, carID int64) ([]*models.PriceChange, error)
+	CreateReservation(ctx context.Context, reservation *models.Reservation) error
+	GetReservations(ctx context.Context, carID int64) ([]*models.Reservation, error)
+	GetExpiredReservations(ctx context.Context, now time.Time) ([]*models.Reservation, error)
+	ReleaseReservation(ctx context.Context, carID int64, reason string) error
+	WithinTx(ctx context.Context, fn func(CarRepository) error) error
 }
@@ -3,8 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"carsapi/internal/models"
 )
@@ -35,6 +39,12 @@ func (r *fakeRow) Scan(dest ...any) error {
 			*d = r.values[i].(int)
 		case *string:
 			*d = r.values[i].(string)
+		case *time.Time:
+			*d = r.values[i].(time.Time)
+		case sql.Scanner:
+			if err := d.Scan(r.values[i]); err != nil {
+				return err
+			}
 		default:
 			return errors.New("unsupported scan destination")
 		}
@@ -67,6 +77,12 @@ func (r *fakeRows) Scan(dest ...any) error {
 			*d = row[i].(int)
 		case *string:
 			*d = row[i].(string)
+		case *time.Time:
+			*d = row[i].(time.Time)
+		case sql.Scanner:
+			if err := d.Scan(row[i]); err != nil {
+				return err
+			}
 		default:
 			return errors.New("unsupported scan destination")
 		}
@@ -79,8 +95,33 @@ func (r *fakeRows) Close() error { return nil }
 func (r *fakeRows) Err() error   { return r.err }
 
 type fakeDB struct {
-	nextID int64
-	cars   map[int64]*models.Car
+	nextID       int64
+	cars         map[int64]*models.Car
+	history      [][]any
+	reservations [][]any
+	sales        [][]any
+	services     [][]any
+	prices       [][]any
+}
+
+func carRow(car *models.Car) []any {
+	var reservedUntil any
+	if car.ReservedUntil != nil {
+		reservedUntil = *car.ReservedUntil
+	}
+	attributes, _ := marshalAttributes(car.Attributes)
+	tags, _ := json.Marshal(append([]string{}, car.Tags...))
+	var serviceSummary any
+	if car.ServiceSummary != nil {
+		summary, _ := json.Marshal(car.ServiceSummary)
+		serviceSummary = string(summary)
+	}
+	return []any{car.ID, car.InventoryID, car.Make, car.Model, car.Year, car.Color, car.VIN, car.Status, car.Price, car.Currency, car.PriceChangedAt, car.Mileage, reservedUntil, attributes, string(tags), serviceSummary, car.Version, car.CreatedAt, car.UpdatedAt}
+}
+
+func (f *fakeDB) setAttributes(car *models.Car, attributes any) {
+	car.Attributes = nil
+	_ = json.Unmarshal([]byte(attributes.(string)), &car.Attributes)
 }
 
 func newFakeDB() *fakeDB {
@@ -100,24 +141,162 @@ func (f *fakeDB) ExecContext(_ context.Context, query string, args ...any) (sql.
 			Year:        args[3].(int),
 			Color:       args[4].(string),
 			VIN:         args[5].(string),
+			Status:      args[6].(string),
+			Price:       args[7].(int64),
+			Currency:    args[8].(string),
+			Mileage:     args[9].(int64),
+			Version:     1,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
 		}
+		f.cars[id].PriceChangedAt = f.cars[id].CreatedAt
+		f.setAttributes(f.cars[id], args[10])
 		return fakeResult{lastInsertID: id, rowsAffected: 1}, nil
 	case updateCarQuery:
-		id := args[6].(int64)
-		car, ok := f.cars[id]
+		id := args[10].(int64)
+		car, ok := f.live(id)
 		if !ok {
 			return fakeResult{rowsAffected: 0}, nil
 		}
+		if version := args[11].(int64); version != 0 && version != car.Version {
+			return fakeResult{rowsAffected: 0}, nil
+		}
+		car.Version++
+		car.UpdatedAt = time.Now()
+		if car.Price != args[6].(int64) || car.Currency != args[7].(string) {
+			car.PriceChangedAt = car.UpdatedAt
+		}
 		car.InventoryID = args[0].(int64)
 		car.Make = args[1].(string)
 		car.Model = args[2].(string)
 		car.Year = args[3].(int)
 		car.Color = args[4].(string)
 		car.VIN = args[5].(string)
+		car.Price = args[6].(int64)
+		car.Currency = args[7].(string)
+		car.Mileage = args[8].(int64)
+		f.setAttributes(car, args[9])
+		return fakeResult{rowsAffected: 1}, nil
+	case updateCarStatusQuery:
+		car, ok := f.live(args[2].(int64))
+		if !ok {
+			return fakeResult{rowsAffected: 0}, nil
+		}
+		if version := args[3].(int64); version != 0 && version != car.Version {
+			return fakeResult{rowsAffected: 0}, nil
+		}
+		car.Status = args[0].(string)
+		car.ReservedUntil = nil
+		if until, ok := args[1].(string); ok {
+			parsed, _ := time.Parse(sqliteTimeLayout, until)
+			car.ReservedUntil = &parsed
+		}
+		car.Version++
+		return fakeResult{rowsAffected: 1}, nil
+	case transferCarQuery:
+		id := args[1].(int64)
+		car, ok := f.live(id)
+		if !ok {
+			return fakeResult{rowsAffected: 0}, nil
+		}
+		car.InventoryID = args[0].(int64)
+		car.Version++
 		return fakeResult{rowsAffected: 1}, nil
 	case deleteCarQuery:
 		id := args[0].(int64)
-		if _, ok := f.cars[id]; !ok {
+		car, ok := f.live(id)
+		if !ok {
+			return fakeResult{rowsAffected: 0}, nil
+		}
+		if version := args[1].(int64); version != 0 && version != car.Version {
+			return fakeResult{rowsAffected: 0}, nil
+		}
+		deletedAt := time.Now()
+		car.DeletedAt = &deletedAt
+		car.Version++
+		return fakeResult{rowsAffected: 1}, nil
+	case restoreCarQuery:
+		car, ok := f.cars[args[0].(int64)]
+		if !ok || car.DeletedAt == nil {
+			return fakeResult{rowsAffected: 0}, nil
+		}
+		for _, other := range f.cars {
+			if other.DeletedAt == nil && other.VIN == car.VIN {
+				return nil, &ConstraintError{Err: ErrUniqueViolation, Column: "vin"}
+			}
+		}
+		car.DeletedAt = nil
+		car.Version++
+		return fakeResult{rowsAffected: 1}, nil
+	case deleteCarTagsQuery:
+		if car, ok := f.cars[args[0].(int64)]; ok {
+			car.Tags = nil
+		}
+		return fakeResult{rowsAffected: 1}, nil
+	case insertTagQuery:
+		return fakeResult{rowsAffected: 1}, nil
+	case insertCarTagQuery:
+		car := f.cars[args[0].(int64)]
+		car.Tags = append(car.Tags, args[1].(string))
+		return fakeResult{rowsAffected: 1}, nil
+	case deleteServiceRecordQuery:
+		for i, row := range f.services {
+			if row[0] == args[0] {
+				f.services = append(f.services[:i], f.services[i+1:]...)
+				return fakeResult{rowsAffected: 1}, nil
+			}
+		}
+		return fakeResult{rowsAffected: 0}, nil
+	case updateServiceSummaryQuery:
+		car, ok := f.live(args[0].(int64))
+		if !ok {
+			return fakeResult{rowsAffected: 0}, nil
+		}
+		car.ServiceSummary = nil
+		for _, row := range f.services {
+			if row[1] != car.ID {
+				continue
+			}
+			if car.ServiceSummary == nil {
+				car.ServiceSummary = &models.ServiceSummary{}
+			}
+			performedAt, _ := time.Parse(sqliteTimeLayout, row[4].(string))
+			car.ServiceSummary.Records++
+			car.ServiceSummary.ReconditioningCost += row[6].(int64)
+			car.ServiceSummary.Currency = max(car.ServiceSummary.Currency, row[7].(string))
+			if performedAt.After(car.ServiceSummary.LastServiceAt) {
+				car.ServiceSummary.LastServiceAt = performedAt
+			}
+		}
+		car.Version++
+		return fakeResult{rowsAffected: 1}, nil
+	case insertCarHistoryQuery:
+		id := int64(len(f.history) + 1)
+		values := []any{id, args[0], args[1], args[2], "", "", time.Now()}
+		for i, snapshot := range args[3:] {
+			if snapshot != nil {
+				values[4+i] = snapshot
+			}
+		}
+		f.history = append(f.history, values)
+		return fakeResult{lastInsertID: id, rowsAffected: 1}, nil
+	case insertCarPriceQuery:
+		id := int64(len(f.prices) + 1)
+		f.prices = append(f.prices, append([]any{id}, args...))
+		return fakeResult{lastInsertID: id, rowsAffected: 1}, nil
+	case releaseReservationQuery:
+		var released int64
+		for _, row := range f.reservations {
+			if row[1] == args[1] && row[6] == nil {
+				row[6], row[7] = time.Now(), args[0]
+				released++
+			}
+		}
+		return fakeResult{rowsAffected: released}, nil
+	case purgeCarQuery:
+		id := args[0].(int64)
+		car, ok := f.cars[id]
+		if !ok || car.DeletedAt == nil {
 			return fakeResult{rowsAffected: 0}, nil
 		}
 		delete(f.cars, id)
@@ -128,27 +307,211 @@ func (f *fakeDB) ExecContext(_ context.Context, query string, args ...any) (sql.
 }
 
 func (f *fakeDB) QueryRowContext(_ context.Context, query string, args ...any) Row {
-	if query != getCarByIDQuery {
+	if strings.HasPrefix(query, countCarsQuery) {
+		return &fakeRow{values: []any{int64(len(f.cars))}}
+	}
+	if query == createSaleQuery {
+		for _, row := range f.sales {
+			if row[1] == args[0] {
+				return &fakeRow{err: &ConstraintError{Err: ErrUniqueViolation, Column: "car_id"}}
+			}
+		}
+		id, createdAt := int64(len(f.sales)+1), time.Now()
+		f.sales = append(f.sales, append([]any{id}, append(args, createdAt)...))
+		return &fakeRow{values: []any{id, createdAt}}
+	}
+	if query == getSaleQuery || query == getCarSaleQuery {
+		column := 0
+		if query == getCarSaleQuery {
+			column = 1
+		}
+		for _, row := range f.sales {
+			if row[column] == args[0] {
+				return &fakeRow{values: row}
+			}
+		}
+		return &fakeRow{err: sql.ErrNoRows}
+	}
+	if query == createServiceRecordQuery {
+		id, createdAt := int64(len(f.services)+1), time.Now()
+		f.services = append(f.services, append([]any{id}, append(args, createdAt)...))
+		return &fakeRow{values: []any{id, createdAt}}
+	}
+	if query == getServiceRecordQuery {
+		for _, row := range f.services {
+			if row[0] == args[0] {
+				return &fakeRow{values: row}
+			}
+		}
+		return &fakeRow{err: sql.ErrNoRows}
+	}
+	if query == createReservationQuery {
+		for _, row := range f.reservations {
+			if row[1] == args[0] && row[6] == nil {
+				return &fakeRow{err: &ConstraintError{Err: ErrUniqueViolation, Column: "car_id"}}
+			}
+		}
+		id, createdAt := int64(len(f.reservations)+1), time.Now()
+		f.reservations = append(f.reservations, []any{id, args[0], args[1], args[2], args[3], createdAt, nil, ""})
+		return &fakeRow{values: []any{id, createdAt}}
+	}
+	if query == upsertCarByVINQuery {
+		return f.upsertByVIN(args)
+	}
+	if query == getCarByVINQuery {
+		for _, car := range f.cars {
+			if car.DeletedAt == nil && car.VIN == args[0].(string) {
+				return &fakeRow{values: carRow(car)}
+			}
+		}
+		return &fakeRow{err: sql.ErrNoRows}
+	}
+	if query != getCarByIDQuery && query != getCarVersionQuery {
 		return &fakeRow{err: errors.New("unsupported query")}
 	}
-	id := args[0].(int64)
-	car, ok := f.cars[id]
+	car, ok := f.live(args[0].(int64))
 	if !ok {
 		return &fakeRow{err: sql.ErrNoRows}
 	}
+	if query == getCarVersionQuery {
+		return &fakeRow{values: []any{car.Version}}
+	}
+
+	return &fakeRow{values: carRow(car)}
+}
+
+// live returns the car with id unless it is missing or soft-deleted.
+func (f *fakeDB) live(id int64) (*models.Car, bool) {
+	car, ok := f.cars[id]
+	if !ok || car.DeletedAt != nil {
+		return nil, false
+	}
+	return car, true
+}
+
+func (f *fakeDB) upsertByVIN(args []any) Row {
+	for _, car := range f.cars {
+		if car.DeletedAt != nil || car.VIN != args[5].(string) {
+			continue
+		}
+		if version := args[11].(int64); version != 0 && version != car.Version {
+			return &fakeRow{err: sql.ErrNoRows}
+		}
+		car.InventoryID = args[0].(int64)
+		car.Make = args[1].(string)
+		car.Model = args[2].(string)
+		car.Year = args[3].(int)
+		car.Color = args[4].(string)
+		if car.Price != args[7].(int64) || car.Currency != args[8].(string) {
+			car.PriceChangedAt = time.Now()
+		}
+		car.Price = args[7].(int64)
+		car.Currency = args[8].(string)
+		car.Mileage = args[9].(int64)
+		f.setAttributes(car, args[10])
+		car.Version++
+		return &fakeRow{values: []any{car.ID, car.Version}}
+	}
+
+	result, _ := f.ExecContext(context.Background(), createCarQuery, args[:11]...)
+	id, _ := result.LastInsertId()
+	return &fakeRow{values: []any{id, int64(1)}}
+}
+
+func (f *fakeDB) BeginTx(_ context.Context) (Tx, error) {
+	snapshot := make(map[int64]models.Car, len(f.cars))
+	for id, car := range f.cars {
+		snapshot[id] = *car
+	}
+	return &fakeTx{fakeDB: f, snapshot: snapshot, nextID: f.nextID}, nil
+}
+
+// fakeTx writes straight through to its fakeDB and restores the snapshot
+// taken at BeginTx on rollback.
+type fakeTx struct {
+	*fakeDB
+	snapshot map[int64]models.Car
+	nextID   int64
+}
+
+func (t *fakeTx) Commit() error { return nil }
 
-	return &fakeRow{values: []any{car.ID, car.InventoryID, car.Make, car.Model, car.Year, car.Color, car.VIN}}
+func (t *fakeTx) Rollback() error {
+	t.cars = make(map[int64]*models.Car, len(t.snapshot))
+	for id, car := range t.snapshot {
+		copyCar := car
+		t.cars[id] = &copyCar
+	}
+	t.fakeDB.nextID = t.nextID
+	return nil
 }
 
-func (f *fakeDB) QueryContext(_ context.Context, query string, _ ...any) (Rows, error) {
-	if query != getAllCarsQuery {
+func (f *fakeDB) QueryContext(_ context.Context, query string, args ...any) (Rows, error) {
+	if query == getCarHistoryQuery {
+		values := make([][]any, 0)
+		for _, row := range f.history {
+			if row[1] == args[0] {
+				values = append(values, row)
+			}
+		}
+		return &fakeRows{values: values}, nil
+	}
+	if strings.HasPrefix(query, selectSalesQuery) {
+		values := make([][]any, 0)
+		for i := len(f.sales) - 1; i >= 0; i-- {
+			values = append(values, f.sales[i])
+		}
+		return &fakeRows{values: values}, nil
+	}
+	if query == getCarPricesQuery {
+		values := make([][]any, 0)
+		for _, row := range f.prices {
+			if row[1] == args[0] {
+				values = append(values, row)
+			}
+		}
+		return &fakeRows{values: values}, nil
+	}
+	if query == getServiceRecordsQuery {
+		values := make([][]any, 0)
+		for _, row := range f.services {
+			if row[1] == args[0] {
+				values = append(values, row)
+			}
+		}
+		return &fakeRows{values: values}, nil
+	}
+	if query == getReservationsQuery {
+		values := make([][]any, 0)
+		for i := len(f.reservations) - 1; i >= 0; i-- {
+			if f.reservations[i][1] == args[0] {
+				values = append(values, f.reservations[i])
+			}
+		}
+		return &fakeRows{values: values}, nil
+	}
+	if query == getExpiredReservationsQuery {
+		values := make([][]any, 0)
+		for _, row := range f.reservations {
+			if row[6] == nil && row[4].(string) <= args[0].(string) {
+				values = append(values, row)
+			}
+		}
+		return &fakeRows{values: values}, nil
+	}
+	if !strings.HasPrefix(query, selectCarsQuery) {
 		return nil, errors.New("unsupported query")
 	}
 
+	limit := len(f.cars)
+	if strings.HasSuffix(query, "LIMIT ?") {
+		limit = args[len(args)-1].(int)
+	}
+
 	values := make([][]any, 0, len(f.cars))
-	for id := int64(1); id <= f.nextID; id++ {
+	for id := int64(1); id <= f.nextID && len(values) < limit; id++ {
 		if car, ok := f.cars[id]; ok {
-			values = append(values, []any{car.ID, car.InventoryID, car.Make, car.Model, car.Year, car.Color, car.VIN})
+			values = append(values, carRow(car))
 		}
 	}
 
@@ -181,7 +544,7 @@ func TestSQLiteCarRepositoryGetAll(t *testing.T) {
 	_ = repo.Create(ctx, &models.Car{InventoryID: 1, Make: "Ford", Model: "Focus", Year: 2019, Color: "Gray", VIN: "VIN-2"})
 	_ = repo.Create(ctx, &models.Car{InventoryID: 1, Make: "Toyota", Model: "Corolla", Year: 2021, Color: "White", VIN: "VIN-3"})
 
-	cars, err := repo.GetAll(ctx)
+	cars, next, err := repo.GetAll(ctx, models.CarFilter{SortBy: "id", Limit: 50})
 	if err != nil {
 		t.Fatalf("GetAll() error = %v", err)
 	}
@@ -189,6 +552,117 @@ func TestSQLiteCarRepositoryGetAll(t *testing.T) {
 	if len(cars) != 2 {
 		t.Fatalf("GetAll() len = %d, want 2", len(cars))
 	}
+	if next != "" {
+		t.Fatalf("GetAll() next cursor = %q, want empty", next)
+	}
+}
+
+func TestSQLiteCarRepositoryGetAllPaginates(t *testing.T) {
+	repo := NewSQLiteCarRepository(newFakeDB())
+	ctx := context.Background()
+
+	_ = repo.Create(ctx, &models.Car{InventoryID: 1, Make: "Ford", Model: "Focus", Year: 2019, Color: "Gray", VIN: "VIN-7"})
+	_ = repo.Create(ctx, &models.Car{InventoryID: 1, Make: "Toyota", Model: "Corolla", Year: 2021, Color: "White", VIN: "VIN-8"})
+
+	filter := models.CarFilter{SortBy: "year", SortDesc: true, Limit: 1}
+	cars, next, err := repo.GetAll(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(cars) != 1 || next == "" {
+		t.Fatalf("GetAll() len = %d next = %q, want 1 car and a cursor", len(cars), next)
+	}
+
+	filter.Cursor = next
+	query, args, err := buildListCarsQuery(filter)
+	if err != nil {
+		t.Fatalf("buildListCarsQuery() error = %v", err)
+	}
+	wantQuery := selectCarsQuery + " WHERE deleted_at IS NULL AND (year < ? OR (year = ? AND id < ?)) ORDER BY year DESC, id DESC LIMIT ?"
+	if query != wantQuery {
+		t.Fatalf("buildListCarsQuery() query = %q, want %q", query, wantQuery)
+	}
+	if len(args) != 4 || args[0] != int64(cars[0].Year) || args[2] != cars[0].ID || args[3] != 2 {
+		t.Fatalf("buildListCarsQuery() args = %v", args)
+	}
+
+	filter.SortDesc = false
+	if _, _, err := buildListCarsQuery(filter); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("buildListCarsQuery() error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestBuildCountCarsQuery(t *testing.T) {
+	query, args := buildCountCarsQuery(models.CarFilter{Make: "honda", InventoryID: 2, YearMin: 2015, YearMax: 2020})
+
+	wantQuery := countCarsQuery + " WHERE deleted_at IS NULL AND make = ? COLLATE NOCASE AND inventory_id = ? AND year >= ? AND year <= ?"
+	if query != wantQuery {
+		t.Fatalf("buildCountCarsQuery() query = %q, want %q", query, wantQuery)
+	}
+	if len(args) != 4 {
+		t.Fatalf("buildCountCarsQuery() args = %v, want 4 args", args)
+	}
+}
+
+func TestBuildCountCarsQueryTagsAndAttributes(t *testing.T) {
+	query, args := buildCountCarsQuery(models.CarFilter{Tags: []string{"certified", "demo"}, Attributes: map[string]string{"trim": "LX", "drive": "awd"}})
+
+	wantQuery := countCarsQuery + " WHERE deleted_at IS NULL AND " + carHasTagCondition + " AND " + carHasTagCondition +
+		" AND json_extract(attributes, ?) = ? COLLATE NOCASE AND json_extract(attributes, ?) = ? COLLATE NOCASE"
+	if query != wantQuery {
+		t.Fatalf("buildCountCarsQuery() query = %q, want %q", query, wantQuery)
+	}
+	wantArgs := []any{"certified", "demo", `$."drive"`, "awd", `$."trim"`, "LX"}
+	if fmt.Sprint(args) != fmt.Sprint(wantArgs) {
+		t.Fatalf("buildCountCarsQuery() args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestBuildListCarsQueryPriceChangedBefore(t *testing.T) {
+	car := &models.Car{ID: 4, PriceChangedAt: time.Date(2026, 1, 15, 8, 0, 0, 0, time.UTC)}
+	filter := models.CarFilter{InventoryID: 2, PriceChangedBefore: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), SortBy: "price_changed_at", Limit: 10}
+	cursor, err := encodeCarCursor(filter, car)
+	if err != nil {
+		t.Fatalf("encodeCarCursor() error = %v", err)
+	}
+	filter.Cursor = cursor
+
+	query, args, err := buildListCarsQuery(filter)
+	if err != nil {
+		t.Fatalf("buildListCarsQuery() error = %v", err)
+	}
+	wantQuery := selectCarsQuery + " WHERE deleted_at IS NULL AND inventory_id = ? AND price_changed_at <= ? AND (price_changed_at > ? OR (price_changed_at = ? AND id > ?)) ORDER BY price_changed_at ASC, id ASC LIMIT ?"
+	if query != wantQuery {
+		t.Fatalf("buildListCarsQuery() query = %q, want %q", query, wantQuery)
+	}
+	wantArgs := []any{int64(2), "2026-02-01 00:00:00", "2026-01-15 08:00:00", "2026-01-15 08:00:00", int64(4), 11}
+	if fmt.Sprint(args) != fmt.Sprint(wantArgs) {
+		t.Fatalf("buildListCarsQuery() args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestSQLiteCarRepositoryTagsAndAttributes(t *testing.T) {
+	repo := NewSQLiteCarRepository(newFakeDB())
+	ctx := context.Background()
+
+	car := &models.Car{InventoryID: 1, Make: "Volvo", Model: "V70", Year: 2015, Color: "Red", VIN: "VIN-20", Tags: []string{"certified", "demo"}, Attributes: map[string]string{"trim": "LX"}}
+	if err := repo.Create(ctx, car); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, car.ID)
+	if err != nil || fmt.Sprint(got.Tags) != "[certified demo]" || got.Attributes["trim"] != "LX" {
+		t.Fatalf("GetByID() = %+v, %v; want tags and attributes", got, err)
+	}
+
+	car.Tags, car.Attributes = nil, nil
+	if err := repo.Update(ctx, car); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, _ = repo.GetByID(ctx, car.ID)
+	if got.Tags == nil || len(got.Tags) != 0 || got.Attributes == nil || len(got.Attributes) != 0 {
+		t.Fatalf("GetByID() after clearing = tags %#v, attributes %#v; want both empty", got.Tags, got.Attributes)
+	}
 }
 
 func TestSQLiteCarRepositoryUpdate(t *testing.T) {
@@ -209,6 +683,126 @@ func TestSQLiteCarRepositoryUpdate(t *testing.T) {
 	}
 }
 
+func TestSQLiteCarRepositoryUpdateStaleVersion(t *testing.T) {
+	repo := NewSQLiteCarRepository(newFakeDB())
+	ctx := context.Background()
+
+	car := &models.Car{InventoryID: 1, Make: "Nissan", Model: "Leaf", Year: 2019, Color: "Blue", VIN: "VIN-9"}
+	_ = repo.Create(ctx, car)
+
+	car.Version = 1
+	car.Color = "White"
+	if err := repo.Update(ctx, car); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	car.Color = "Black"
+	if err := repo.Update(ctx, car); !errors.Is(err, ErrStaleVersion) {
+		t.Fatalf("Update() error = %v, want ErrStaleVersion", err)
+	}
+	if err := repo.Delete(ctx, car.ID, 1); !errors.Is(err, ErrStaleVersion) {
+		t.Fatalf("Delete() error = %v, want ErrStaleVersion", err)
+	}
+	if err := repo.Delete(ctx, 99, 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("Delete() error = %v, want sql.ErrNoRows", err)
+	}
+
+	got, _ := repo.GetByID(ctx, car.ID)
+	if got.Color != "White" || got.Version != 2 {
+		t.Fatalf("GetByID() = color %q version %d, want White and 2", got.Color, got.Version)
+	}
+}
+
+func TestSQLiteCarRepositoryUpsertByVIN(t *testing.T) {
+	repo := NewSQLiteCarRepository(newFakeDB())
+	ctx := context.Background()
+
+	car := &models.Car{InventoryID: 1, Make: "Honda", Model: "Accord", Year: 2003, Color: "Silver", VIN: "1HGCM82633A004352"}
+	created, err := repo.UpsertByVIN(ctx, car)
+	if err != nil || !created || car.ID == 0 || car.Version != 1 {
+		t.Fatalf("UpsertByVIN() = %v, %v; car = %+v; want a created car", created, err, car)
+	}
+
+	replacement := &models.Car{InventoryID: 2, Make: "Honda", Model: "Accord", Year: 2003, Color: "Black", VIN: car.VIN}
+	created, err = repo.UpsertByVIN(ctx, replacement)
+	if err != nil || created || replacement.ID != car.ID || replacement.Version != 2 {
+		t.Fatalf("UpsertByVIN() = %v, %v; car = %+v; want car %d replaced", created, err, replacement, car.ID)
+	}
+
+	stale := &models.Car{InventoryID: 1, Make: "Honda", Model: "Accord", Year: 2003, Color: "Red", VIN: car.VIN, Version: 1}
+	if _, err := repo.UpsertByVIN(ctx, stale); !errors.Is(err, ErrStaleVersion) {
+		t.Fatalf("UpsertByVIN() stale error = %v, want ErrStaleVersion", err)
+	}
+
+	fetched, err := repo.GetByVIN(ctx, car.VIN)
+	if err != nil || fetched.Color != "Black" {
+		t.Fatalf("GetByVIN() = %+v, %v; want the replacement", fetched, err)
+	}
+}
+
+func TestSQLiteCarRepositoryWithinTx(t *testing.T) {
+	db := newFakeDB()
+	repo := NewSQLiteCarRepository(db)
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	err := repo.WithinTx(ctx, func(tx CarRepository) error {
+		if err := tx.Create(ctx, &models.Car{InventoryID: 1, Make: "Kia", Model: "Rio", Year: 2017, Color: "White", VIN: "VIN-TX-1"}); err != nil {
+			return err
+		}
+
+		// A failed nested unit of work only undoes its own writes.
+		nestedErr := tx.WithinTx(ctx, func(nested CarRepository) error {
+			_ = nested.Create(ctx, &models.Car{InventoryID: 1, Make: "Kia", Model: "Soul", Year: 2018, Color: "Green", VIN: "VIN-TX-2"})
+			return errAbort
+		})
+		if !errors.Is(nestedErr, errAbort) {
+			t.Fatalf("nested WithinTx() error = %v, want errAbort", nestedErr)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+	if len(db.cars) != 1 || db.cars[1].VIN != "VIN-TX-1" {
+		t.Fatalf("cars = %v, want only VIN-TX-1", db.cars)
+	}
+
+	err = repo.WithinTx(ctx, func(tx CarRepository) error {
+		if err := tx.Delete(ctx, 1, 0); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("WithinTx() error = %v, want errAbort", err)
+	}
+	if _, err := repo.GetByID(ctx, 1); err != nil {
+		t.Fatalf("GetByID() after rollback error = %v", err)
+	}
+}
+
+func TestSQLiteCarRepositoryTransfer(t *testing.T) {
+	repo := NewSQLiteCarRepository(newFakeDB())
+	ctx := context.Background()
+
+	car := &models.Car{InventoryID: 1, Make: "Volvo", Model: "XC40", Year: 2023, Color: "White", VIN: "VIN-6"}
+	_ = repo.Create(ctx, car)
+
+	if err := repo.Transfer(ctx, car.ID, 2); err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+
+	got, _ := repo.GetByID(ctx, car.ID)
+	if got.InventoryID != 2 {
+		t.Fatalf("Transfer() inventory_id = %d, want 2", got.InventoryID)
+	}
+
+	if err := repo.Transfer(ctx, 99, 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("Transfer() error = %v, want sql.ErrNoRows", err)
+	}
+}
+
 func TestSQLiteCarRepositoryDelete(t *testing.T) {
 	repo := NewSQLiteCarRepository(newFakeDB())
 	ctx := context.Background()
@@ -216,7 +810,7 @@ func TestSQLiteCarRepositoryDelete(t *testing.T) {
 	car := &models.Car{InventoryID: 1, Make: "Mazda", Model: "3", Year: 2022, Color: "Green", VIN: "VIN-5"}
 	_ = repo.Create(ctx, car)
 
-	if err := repo.Delete(ctx, car.ID); err != nil {
+	if err := repo.Delete(ctx, car.ID, 0); err != nil {
 		t.Fatalf("Delete() error = %v", err)
 	}
 
@@ -224,4 +818,353 @@ func TestSQLiteCarRepositoryDelete(t *testing.T) {
 	if !errors.Is(err, sql.ErrNoRows) {
 		t.Fatalf("GetByID() error = %v, want sql.ErrNoRows", err)
 	}
+	if err := repo.Delete(ctx, car.ID, 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("second Delete() error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestSQLiteCarRepositoryUpdateStatus(t *testing.T) {
+	repo := NewSQLiteCarRepository(newFakeDB())
+	ctx := context.Background()
+
+	car := &models.Car{InventoryID: 1, Make: "Lada", Model: "Niva", Year: 2015, Color: "Green", VIN: "VIN-STATUS", Status: models.StatusInStock, Price: 500000, Currency: "EUR"}
+	_ = repo.Create(ctx, car)
+
+	until := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	if err := repo.UpdateStatus(ctx, &models.Car{ID: car.ID, Status: models.StatusReserved, ReservedUntil: &until, Version: 1}); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	got, _ := repo.GetByID(ctx, car.ID)
+	if got.Status != models.StatusReserved || got.ReservedUntil == nil || !got.ReservedUntil.Equal(until) || got.Version != 2 || got.Price != 500000 {
+		t.Fatalf("GetByID() = %+v, want reserved until %v at version 2", got, until)
+	}
+
+	if err := repo.UpdateStatus(ctx, &models.Car{ID: car.ID, Status: models.StatusSold, Version: 1}); !errors.Is(err, ErrStaleVersion) {
+		t.Fatalf("UpdateStatus() error = %v, want ErrStaleVersion", err)
+	}
+	if err := repo.UpdateStatus(ctx, &models.Car{ID: 99, Status: models.StatusSold}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("UpdateStatus() error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestSQLiteCarRepositoryReservations(t *testing.T) {
+	repo := NewSQLiteCarRepository(newFakeDB())
+	ctx := context.Background()
+
+	expiresAt := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	reservation := &models.Reservation{CarID: 7, Customer: "Kim", ReservedBy: "dana", ExpiresAt: expiresAt}
+	if err := repo.CreateReservation(ctx, reservation); err != nil || reservation.ID != 1 || reservation.CreatedAt.IsZero() {
+		t.Fatalf("CreateReservation() = %+v, %v; want id 1 with created_at", reservation, err)
+	}
+	var constraint *ConstraintError
+	err := repo.CreateReservation(ctx, &models.Reservation{CarID: 7, Customer: "Lee", ExpiresAt: expiresAt})
+	if !errors.As(err, &constraint) || constraint.Column != "car_id" {
+		t.Fatalf("CreateReservation() for a held car error = %v, want a car_id unique violation", err)
+	}
+
+	expired, err := repo.GetExpiredReservations(ctx, expiresAt.Add(-time.Second))
+	if err != nil || len(expired) != 0 {
+		t.Fatalf("GetExpiredReservations() before expiry = %+v, %v; want none", expired, err)
+	}
+	expired, err = repo.GetExpiredReservations(ctx, expiresAt)
+	if err != nil || len(expired) != 1 || expired[0].Customer != "Kim" || !expired[0].ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("GetExpiredReservations() = %+v, %v; want Kim's reservation", expired, err)
+	}
+
+	if err := repo.ReleaseReservation(ctx, 7, "expire"); err != nil {
+		t.Fatalf("ReleaseReservation() error = %v", err)
+	}
+	reservations, err := repo.GetReservations(ctx, 7)
+	if err != nil || len(reservations) != 1 || reservations[0].ReleasedAt == nil || reservations[0].ReleaseReason != "expire" {
+		t.Fatalf("GetReservations() = %+v, %v; want one expired reservation", reservations, err)
+	}
+	if expired, _ := repo.GetExpiredReservations(ctx, expiresAt); len(expired) != 0 {
+		t.Fatalf("GetExpiredReservations() after release = %+v, want none", expired)
+	}
+}
+
+func TestSQLiteCarRepositoryRestoreAndPurge(t *testing.T) {
+	repo := NewSQLiteCarRepository(newFakeDB())
+	ctx := context.Background()
+
+	car := &models.Car{InventoryID: 1, Make: "Mazda", Model: "CX-5", Year: 2021, Color: "Red", VIN: "VIN-TRASH"}
+	_ = repo.Create(ctx, car)
+	if err := repo.Restore(ctx, car.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("Restore() of a live car error = %v, want sql.ErrNoRows", err)
+	}
+	_ = repo.Delete(ctx, car.ID, 0)
+
+	reused := &models.Car{InventoryID: 1, Make: "Mazda", Model: "CX-30", Year: 2022, Color: "Grey", VIN: "VIN-TRASH"}
+	_ = repo.Create(ctx, reused)
+	var constraintErr *ConstraintError
+	if err := repo.Restore(ctx, car.ID); !errors.As(err, &constraintErr) || constraintErr.Column != "vin" {
+		t.Fatalf("Restore() error = %v, want a vin ConstraintError", err)
+	}
+
+	_ = repo.Delete(ctx, reused.ID, 0)
+	if err := repo.Restore(ctx, car.ID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if _, err := repo.GetByID(ctx, car.ID); err != nil {
+		t.Fatalf("GetByID() after restore error = %v", err)
+	}
+
+	if err := repo.Purge(ctx, car.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("Purge() of a live car error = %v, want sql.ErrNoRows", err)
+	}
+	if err := repo.Purge(ctx, reused.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+}
+
+func TestSQLiteCarRepositoryHistory(t *testing.T) {
+	repo := NewSQLiteCarRepository(newFakeDB())
+	ctx := context.Background()
+
+	car := &models.Car{ID: 1, InventoryID: 1, Make: "Seat", Model: "Leon", Year: 2020, Color: "Red", VIN: "VIN-HIST", Version: 1}
+	if err := repo.AddHistory(ctx, &models.CarHistoryEntry{CarID: 1, Action: models.CarCreated, Actor: "alice", New: car}); err != nil {
+		t.Fatalf("AddHistory() error = %v", err)
+	}
+	if err := repo.AddHistory(ctx, &models.CarHistoryEntry{CarID: 1, Action: models.CarDeleted, Actor: "bob", Old: car}); err != nil {
+		t.Fatalf("AddHistory() error = %v", err)
+	}
+
+	history, err := repo.GetHistory(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("GetHistory() returned %d entries, want 2", len(history))
+	}
+	if history[0].Old != nil || history[0].New == nil || history[0].New.VIN != "VIN-HIST" || history[0].Actor != "alice" {
+		t.Fatalf("first entry = %+v", history[0])
+	}
+	if history[1].Old == nil || history[1].New != nil || history[1].Action != models.CarDeleted {
+		t.Fatalf("second entry = %+v", history[1])
+	}
+
+	if other, _ := repo.GetHistory(ctx, 2); len(other) != 0 {
+		t.Fatalf("GetHistory() of another car = %v, want empty", other)
+	}
+}
+
+func TestBuildCarsQueriesAsOf(t *testing.T) {
+	asOf := time.Date(2026, 3, 31, 23, 59, 59, 0, time.FixedZone("CEST", 2*60*60))
+	filter := models.CarFilter{SortBy: "id", Limit: 10, Make: "honda", AsOf: asOf}
+
+	query, args, err := buildListCarsQuery(filter)
+	if err != nil {
+		t.Fatalf("buildListCarsQuery() error = %v", err)
+	}
+	if want := carsAsOfQuery + selectCarsQuery + " WHERE deleted_at IS NULL AND make = ? COLLATE NOCASE ORDER BY id ASC LIMIT ?"; query != want {
+		t.Fatalf("buildListCarsQuery() query = %q, want %q", query, want)
+	}
+	if len(args) != 3 || args[0] != "2026-03-31 21:59:59" || args[1] != "honda" {
+		t.Fatalf("buildListCarsQuery() args = %v", args)
+	}
+
+	countQuery, countArgs := buildCountCarsQuery(filter)
+	if !strings.HasPrefix(countQuery, carsAsOfQuery+countCarsQuery) || len(countArgs) != 2 || countArgs[0] != "2026-03-31 21:59:59" {
+		t.Fatalf("buildCountCarsQuery() = %q, %v", countQuery, countArgs)
+	}
+}
+
+func TestBuildCarConditionsTimestamps(t *testing.T) {
+	filter := models.CarFilter{
+		CreatedAfter: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedSince: time.Date(2026, 3, 1, 12, 30, 0, 0, time.FixedZone("EST", -5*60*60)),
+	}
+
+	query, args := buildCountCarsQuery(filter)
+	if want := countCarsQuery + " WHERE deleted_at IS NULL AND created_at > ? AND updated_at >= ?"; query != want {
+		t.Fatalf("buildCountCarsQuery() query = %q, want %q", query, want)
+	}
+	if len(args) != 2 || args[0] != "2026-02-01 00:00:00" || args[1] != "2026-03-01 17:30:00" {
+		t.Fatalf("buildCountCarsQuery() args = %v", args)
+	}
+}
+
+func TestBuildSalesQuery(t *testing.T) {
+	query, args := buildSalesQuery(models.SaleFilter{})
+	if want := selectSalesQuery + " ORDER BY sold_at DESC, id DESC"; query != want || len(args) != 0 {
+		t.Fatalf("buildSalesQuery() = %q, %v; want %q", query, args, want)
+	}
+
+	query, args = buildSalesQuery(models.SaleFilter{
+		Salesperson: "sam",
+		SoldFrom:    time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		SoldTo:      time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if want := selectSalesQuery + " WHERE salesperson = ? AND sold_at >= ? AND sold_at < ? ORDER BY sold_at DESC, id DESC"; query != want {
+		t.Fatalf("buildSalesQuery() query = %q, want %q", query, want)
+	}
+	if len(args) != 3 || args[0] != "sam" || args[1] != "2026-02-01 00:00:00" || args[2] != "2026-03-01 00:00:00" {
+		t.Fatalf("buildSalesQuery() args = %v", args)
+	}
+}
+
+func TestSQLiteSaleRepository(t *testing.T) {
+	repo := NewSQLiteSaleRepository(newFakeDB())
+	ctx := context.Background()
+
+	soldAt := time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC)
+	reservationID := int64(3)
+	sale := &models.Sale{CarID: 7, BuyerName: "Ana", SalePrice: 2350000, Currency: "USD", SoldAt: soldAt, Salesperson: "sam", ReservationID: &reservationID}
+	if err := repo.Create(ctx, sale); err != nil || sale.ID != 1 || sale.CreatedAt.IsZero() {
+		t.Fatalf("Create() = %+v, %v; want id 1 with created_at", sale, err)
+	}
+	var constraint *ConstraintError
+	if err := repo.Create(ctx, &models.Sale{CarID: 7, BuyerName: "Ben", SoldAt: soldAt}); !errors.As(err, &constraint) || constraint.Column != "car_id" {
+		t.Fatalf("Create() for a sold car error = %v, want a car_id unique violation", err)
+	}
+	if err := repo.Create(ctx, &models.Sale{CarID: 8, BuyerName: "Ben", SoldAt: soldAt, Salesperson: "sam"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := repo.GetByCarID(ctx, 7)
+	if err != nil || got.BuyerName != "Ana" || !got.SoldAt.Equal(soldAt) || got.ReservationID == nil || *got.ReservationID != 3 {
+		t.Fatalf("GetByCarID() = %+v, %v; want Ana's sale", got, err)
+	}
+	if got, err := repo.GetByID(ctx, 2); err != nil || got.CarID != 8 || got.ReservationID != nil {
+		t.Fatalf("GetByID() = %+v, %v; want the sale of car 8", got, err)
+	}
+	if _, err := repo.GetByID(ctx, 99); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetByID() error = %v, want sql.ErrNoRows", err)
+	}
+
+	sales, err := repo.GetAll(ctx, models.SaleFilter{})
+	if err != nil || len(sales) != 2 || sales[0].CarID != 8 {
+		t.Fatalf("GetAll() = %+v, %v; want both sales, newest first", sales, err)
+	}
+}
+
+func TestSQLiteServiceRecordRepository(t *testing.T) {
+	db := newFakeDB()
+	repo := NewSQLiteCarRepository(db)
+	records := NewSQLiteServiceRecordRepository(db)
+	ctx := context.Background()
+
+	car := &models.Car{InventoryID: 1, Make: "Volvo", Model: "V70", Year: 2015, Color: "Red", VIN: "VIN-30"}
+	_ = repo.Create(ctx, car)
+
+	performedAt := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	record := &models.ServiceRecord{CarID: car.ID, Kind: models.ServiceRepair, Description: "Brake pads", PerformedAt: performedAt, Mileage: 61000, Cost: 32000, Currency: "USD", RecordedBy: "sam"}
+	if err := records.Create(ctx, record); err != nil || record.ID != 1 || record.CreatedAt.IsZero() {
+		t.Fatalf("Create() = %+v, %v; want id 1 with created_at", record, err)
+	}
+	if err := records.UpdateSummary(ctx, car.ID); err != nil {
+		t.Fatalf("UpdateSummary() error = %v", err)
+	}
+
+	got, err := records.GetByID(ctx, record.ID)
+	if err != nil || got.Description != "Brake pads" || !got.PerformedAt.Equal(performedAt) || got.Cost != 32000 {
+		t.Fatalf("GetByID() = %+v, %v; want the brake repair", got, err)
+	}
+	if records, err := records.GetByCarID(ctx, car.ID); err != nil || len(records) != 1 {
+		t.Fatalf("GetByCarID() = %+v, %v; want one record", records, err)
+	}
+
+	serviced, _ := repo.GetByID(ctx, car.ID)
+	summary := serviced.ServiceSummary
+	if summary == nil || summary.Records != 1 || summary.ReconditioningCost != 32000 || summary.Currency != "USD" || !summary.LastServiceAt.Equal(performedAt) || serviced.Version != 2 {
+		t.Fatalf("GetByID() = %+v with summary %+v; want the record summarized at version 2", serviced, summary)
+	}
+
+	if err := records.Delete(ctx, record.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := records.Delete(ctx, record.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("second Delete() error = %v, want sql.ErrNoRows", err)
+	}
+	_ = records.UpdateSummary(ctx, car.ID)
+	if serviced, _ := repo.GetByID(ctx, car.ID); serviced.ServiceSummary != nil {
+		t.Fatalf("GetByID() summary = %+v, want none once the last record is deleted", serviced.ServiceSummary)
+	}
+}
+
+func TestSQLiteCarRepositoryPriceHistory(t *testing.T) {
+	repo := NewSQLiteCarRepository(newFakeDB())
+	ctx := context.Background()
+
+	car := &models.Car{InventoryID: 1, Make: "Volvo", Model: "V70", Year: 2015, Color: "Red", VIN: "VIN-40", Price: 1500000, Currency: "EUR"}
+	_ = repo.Create(ctx, car)
+	created, _ := repo.GetByID(ctx, car.ID)
+
+	car.Mileage = 1000
+	_ = repo.Update(ctx, car)
+	if got, _ := repo.GetByID(ctx, car.ID); !got.PriceChangedAt.Equal(created.PriceChangedAt) {
+		t.Fatalf("price_changed_at after a mileage update = %v, want %v", got.PriceChangedAt, created.PriceChangedAt)
+	}
+	car.Price, car.Version = 1400000, 0
+	_ = repo.Update(ctx, car)
+	repriced, _ := repo.GetByID(ctx, car.ID)
+	if !repriced.PriceChangedAt.After(created.PriceChangedAt) {
+		t.Fatalf("price_changed_at after a price update = %v, want later than %v", repriced.PriceChangedAt, created.PriceChangedAt)
+	}
+
+	changedAt := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	change := &models.PriceChange{CarID: car.ID, Price: 1400000, Currency: "EUR", ChangedBy: "sam", ChangedAt: changedAt}
+	if err := repo.AddPriceChange(ctx, change); err != nil || change.ID != 1 {
+		t.Fatalf("AddPriceChange() = %+v, %v; want id 1", change, err)
+	}
+
+	prices, err := repo.GetPriceHistory(ctx, car.ID)
+	if err != nil || len(prices) != 1 || prices[0].Price != 1400000 || prices[0].ChangedBy != "sam" || !prices[0].ChangedAt.Equal(changedAt) {
+		t.Fatalf("GetPriceHistory() = %+v, %v; want the markdown by sam", prices, err)
+	}
+	if other, _ := repo.GetPriceHistory(ctx, 2); len(other) != 0 {
+		t.Fatalf("GetPriceHistory() of another car = %v, want empty", other)
+	}
+}
+
+func TestTimestampScan(t *testing.T) {
+	want := time.Date(2026, 4, 2, 8, 15, 0, 0, time.UTC)
+	tests := map[string]any{
+		"datetime column": want,
+		"computed text":   "2026-04-02 08:15:00",
+	}
+
+	for name, src := range tests {
+		var got time.Time
+		if err := (timestamp{&got}).Scan(src); err != nil || !got.Equal(want) {
+			t.Fatalf("%s: Scan() = %v, %v; want %v", name, got, err, want)
+		}
+	}
+
+	got := want
+	if err := (timestamp{&got}).Scan(nil); err != nil || !got.IsZero() {
+		t.Fatalf("Scan(nil) = %v, %v; want zero time", got, err)
+	}
+	if err := (timestamp{&got}).Scan("yesterday"); err == nil {
+		t.Fatal("Scan() of malformed text succeeded")
+	}
+}
+
+func TestBuildCarSearchMatch(t *testing.T) {
+	tests := map[string]string{
+		"blu civ":       `"blu"* AND "civ"*`,
+		"  Honda-Civic": `"Honda"* AND "Civic"*`,
+		`"AND (*`:       `"AND"*`,
+		`(*) -`:         ``,
+	}
+
+	for text, want := range tests {
+		if got := buildCarSearchMatch(text); got != want {
+			t.Fatalf("buildCarSearchMatch(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestConstraintColumn(t *testing.T) {
+	tests := map[string]string{
+		"constraint failed: UNIQUE constraint failed: cars.vin (2067)":              "vin",
+		"constraint failed: UNIQUE constraint failed: cars.make, cars.model (2067)": "make",
+		"constraint failed: FOREIGN KEY constraint failed (787)":                    "",
+	}
+
+	for message, want := range tests {
+		if got := constraintColumn(message); got != want {
+			t.Fatalf("constraintColumn(%q) = %q, want %q", message, got, want)
+		}
+	}
 }
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"carsapi/internal/models"
+)
+
+type CatalogRepository interface {
+	Seed(ctx context.Context, makes []models.CatalogMake) error
+	GetMakes(ctx context.Context) ([]*models.CatalogMake, error)
+	GetMake(ctx context.Context, name string) (*models.CatalogMake, error)
+	GetModels(ctx context.Context, makeID int64) ([]*models.CatalogModel, error)
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ConstraintError is a write rejected by a UNIQUE or FOREIGN KEY constraint.
+// Err is ErrUniqueViolation or ErrForeignKeyViolation and Column names the
+// offending column when it is known.
+type ConstraintError struct {
+	Err    error
+	Column string
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Column == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + " on " + e.Column
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// classifyConstraint converts SQLite constraint failures into a
+// *ConstraintError and returns any other error unchanged. SQLite does not
+// report which column a foreign key failure came from, so the caller passes
+// foreignKey, the column the statement could have violated.
+func classifyConstraint(err error, foreignKey string) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return &ConstraintError{Err: ErrUniqueViolation, Column: constraintColumn(sqliteErr.Error())}
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return &ConstraintError{Err: ErrForeignKeyViolation, Column: foreignKey}
+	default:
+		return err
+	}
+}
+
+// constraintColumn extracts the first column from a message such as
+// "constraint failed: UNIQUE constraint failed: cars.vin (2067)".
+func constraintColumn(message string) string {
+	const marker = "UNIQUE constraint failed: "
+	i := strings.LastIndex(message, marker)
+	if i < 0 {
+		return ""
+	}
+
+	column, _, _ := strings.Cut(message[i+len(marker):], " ")
+	column = strings.TrimSuffix(column, ",")
+	if _, name, ok := strings.Cut(column, "."); ok {
+		return name
+	}
+	return column
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "errors"
+
+var (
+	ErrForeignKeyViolation = errors.New("foreign key constraint violation")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrStaleVersion        = errors.New("stale version")
+	ErrUniqueViolation     = errors.New("unique constraint violation")
+)
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocalFileStore(t *testing.T) {
+	store, err := NewLocalFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalFileStore() error = %v", err)
+	}
+
+	if n, err := store.Put("7/photo", strings.NewReader("jpeg bytes")); err != nil || n != 10 {
+		t.Fatalf("Put() = %d, %v; want 10 bytes", n, err)
+	}
+	file, err := store.Open("7/photo")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "jpeg bytes" {
+		t.Fatalf("Open() read %q", data)
+	}
+
+	if err := store.Delete("7/photo"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete("7/photo"); err != nil {
+		t.Fatalf("Delete() of a missing file error = %v", err)
+	}
+	if _, err := store.Open("7/photo"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Open() after Delete() error = %v, want os.ErrNotExist", err)
+	}
+
+	_, _ = store.Put("8/a", strings.NewReader("a"))
+	_, _ = store.Put("8/b", strings.NewReader("b"))
+	_, _ = store.Put("80/c", strings.NewReader("c"))
+	if err := store.DeleteAll("8"); err != nil {
+		t.Fatalf("DeleteAll() error = %v", err)
+	}
+	if _, err := store.Open("8/a"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Open() after DeleteAll() error = %v, want os.ErrNotExist", err)
+	}
+	if file, err := store.Open("80/c"); err != nil {
+		t.Fatalf("DeleteAll(8) removed 80/c: %v", err)
+	} else {
+		file.Close()
+	}
+
+	for _, key := range []string{"", "../escape", "/etc/passwd", "a/../../escape"} {
+		if _, err := store.Put(key, strings.NewReader("x")); err == nil {
+			t.Fatalf("Put(%q) succeeded, want an invalid key error", key)
+		}
+	}
+}
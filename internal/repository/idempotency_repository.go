@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"carsapi/internal/models"
+)
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, key, requestHash string) (bool, error)
+	// Reclaim takes over a reservation of key for requestHash that is still
+	// in progress but was made before reservedBefore.
+	Reclaim(ctx context.Context, key, requestHash string, reservedBefore time.Time) (bool, error)
+	GetByKey(ctx context.Context, key string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *models.IdempotencyRecord) error
+	Delete(ctx context.Context, key string) error
+	DeleteCreatedBefore(ctx context.Context, before time.Time) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"carsapi/internal/models"
+)
+
+type InventoryRepository interface {
+	Create(ctx context.Context, inventory *models.Inventory) error
+	GetByID(ctx context.Context, id int64) (*models.Inventory, error)
+	GetAll(ctx context.Context) ([]*models.Inventory, error)
+	Update(ctx context.Context, inventory *models.Inventory) error
+	Delete(ctx context.Context, id int64) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"carsapi/internal/models"
+)
+
+type SaleRepository interface {
+	Create(ctx context.Context, sale *models.Sale) error
+	GetByID(ctx context.Context, id int64) (*models.Sale, error)
+	GetByCarID(ctx context.Context, carID int64) (*models.Sale, error)
+	GetAll(ctx context.Context, filter models.SaleFilter) ([]*models.Sale, error)
+	// WithinTx runs fn with sale and car repositories sharing one
+	// transaction, so that a sale and the car it sells are written together.
+	WithinTx(ctx context.Context, fn func(SaleRepository, CarRepository) error) error
+}
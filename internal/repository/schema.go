@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// inlineVINUnique is how cars declared vin before soft delete, when a VIN
+// had to be unique even among deleted cars. cars_vin_active_idx replaces it.
+const inlineVINUnique = "vin TEXT NOT NULL UNIQUE"
+
+// normalizedVINSQL is the VIN of a cars row as the service normalizes VINs
+// it is given: upper case, without whitespace.
+const normalizedVINSQL = `UPPER(REPLACE(REPLACE(REPLACE(REPLACE(vin, ' ', ''), char(9), ''), char(10), ''), char(13), ''))`
+
+// columnMigration adds a column to a table that an older schema created.
+// CREATE TABLE IF NOT EXISTS leaves such a table as it was, so every column
+// added to an existing table needs one. backfill, if set, runs once the
+// column is added, for values its default cannot give.
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+	backfill   string
+}
+
+// columnMigrations run in order before the schema itself.
+var columnMigrations = []columnMigration{
+	{table: "cars", column: "version", definition: "INTEGER NOT NULL DEFAULT 1"},
+	{table: "cars", column: "deleted_at", definition: "DATETIME"},
+	{table: "cars", column: "status", definition: "TEXT NOT NULL DEFAULT 'in_stock' CHECK (status IN ('in_stock', 'reserved', 'sold', 'in_transit', 'retired'))"},
+	{table: "cars", column: "price", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{table: "cars", column: "currency", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "cars", column: "mileage", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{table: "cars", column: "reserved_until", definition: "DATETIME"},
+	{table: "cars", column: "attributes", definition: "TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(attributes))"},
+	{table: "cars", column: "service_summary", definition: "TEXT"},
+	// ADD COLUMN cannot default to CURRENT_TIMESTAMP, so cars are inserted
+	// with an explicit price_changed_at and existing ones take updated_at,
+	// the last time their price could have changed.
+	{table: "cars", column: "price_changed_at", definition: "DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00'", backfill: "UPDATE cars SET price_changed_at = updated_at"},
+	{table: "idempotency_keys", column: "reserved_at", definition: "DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00'", backfill: "UPDATE idempotency_keys SET reserved_at = created_at"},
+}
+
+// ApplySchema brings the tables of an existing database up to date and then
+// runs schemaSQL, which creates whatever is still missing.
+func ApplySchema(ctx context.Context, db *sql.DB, schemaSQL string) error {
+	if err := migrate(ctx, db); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	ftsColumns, err := tableColumns(ctx, db, "cars_fts")
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, schemaSQL); err != nil {
+		return err
+	}
+
+	// The search index starts out empty. Cars written before it existed are
+	// indexed once from the content table; the triggers keep it current
+	// from then on.
+	if len(ftsColumns) == 0 {
+		if _, err := db.ExecContext(ctx, `INSERT INTO cars_fts (cars_fts) VALUES ('rebuild')`); err != nil {
+			return fmt.Errorf("index cars for search: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	for _, m := range columnMigrations {
+		columns, err := tableColumns(ctx, db, m.table)
+		if err != nil {
+			return err
+		}
+		// A missing table is created whole by the schema.
+		if len(columns) == 0 || columns[m.column] {
+			continue
+		}
+
+		if err := addColumn(ctx, db, m); err != nil {
+			return fmt.Errorf("add %s.%s: %w", m.table, m.column, err)
+		}
+	}
+
+	if err := dropInlineVINUnique(ctx, db); err != nil {
+		return fmt.Errorf("drop unique vin constraint: %w", err)
+	}
+
+	if err := normalizeVINs(ctx, db); err != nil {
+		return fmt.Errorf("normalize vins: %w", err)
+	}
+
+	return nil
+}
+
+// addColumn adds the column of m and backfills it in one transaction, so
+// that a failed backfill is retried on the next start.
+func addColumn(ctx context.Context, db *sql.DB, m columnMigration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "ALTER TABLE "+m.table+" ADD COLUMN "+m.column+" "+m.definition); err != nil {
+		return err
+	}
+	if m.backfill != "" {
+		if _, err := tx.ExecContext(ctx, m.backfill); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// dropInlineVINUnique rebuilds a cars table that still declares vin UNIQUE,
+// since SQLite cannot drop a column constraint in place. Foreign keys are
+// off meanwhile so that dropping the old table does not cascade to the rows
+// referencing it; the schema recreates its indexes and triggers afterwards.
+func dropInlineVINUnique(ctx context.Context, db *sql.DB) error {
+	var tableSQL string
+	err := db.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'cars'`).Scan(&tableSQL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(tableSQL, inlineVINUnique) {
+		return nil
+	}
+
+	rebuildSQL := strings.Replace(tableSQL, inlineVINUnique, "vin TEXT NOT NULL", 1)
+	rebuildSQL = strings.Replace(rebuildSQL, "CREATE TABLE cars", "CREATE TABLE cars_rebuild", 1)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Keep AUTOINCREMENT from reusing the ids of purged cars.
+	var seq sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `SELECT seq FROM sqlite_sequence WHERE name = 'cars'`).Scan(&seq); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	for _, stmt := range []string{
+		rebuildSQL,
+		`INSERT INTO cars_rebuild SELECT * FROM cars`,
+		`DROP TABLE cars`,
+		`ALTER TABLE cars_rebuild RENAME TO cars`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if seq.Valid {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM sqlite_sequence WHERE name = 'cars'`); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO sqlite_sequence (name, seq) VALUES ('cars', ?)`, seq.Int64); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// normalizeVINs rewrites the VINs stored before the service normalized
+// them. Active cars whose VINs differ only in case or whitespace would
+// collide once normalized; they are reported rather than merged, and the
+// migration is retried on the next start once they have been resolved.
+func normalizeVINs(ctx context.Context, db *sql.DB) error {
+	columns, err := tableColumns(ctx, db, "cars")
+	if err != nil || len(columns) == 0 {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+normalizedVINSQL+` AS normalized, GROUP_CONCAT(id, ', ') FROM cars
+WHERE deleted_at IS NULL GROUP BY normalized HAVING COUNT(*) > 1 ORDER BY normalized`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var collisions []string
+	for rows.Next() {
+		var vin, ids string
+		if err := rows.Scan(&vin, &ids); err != nil {
+			return err
+		}
+		collisions = append(collisions, fmt.Sprintf("%s (cars %s)", vin, ids))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(collisions) > 0 {
+		return fmt.Errorf("active cars share a vin once it is normalized, delete or correct all but one of each: %s", strings.Join(collisions, "; "))
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE cars SET vin = `+normalizedVINSQL+` WHERE vin <> `+normalizedVINSQL); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// tableColumns returns the column names of table, or none if it does not
+// exist.
+func tableColumns(ctx context.Context, db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+
+	return columns, rows.Err()
+}
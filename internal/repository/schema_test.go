@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"carsapi/internal/models"
+)
+
+// openTestDB opens a fresh SQLite database file the way the server does.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "cars.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// openSchemaDB opens a test database with the current schema applied.
+func openSchemaDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db := openTestDB(t)
+	applySchemaFile(t, db)
+	return db
+}
+
+func applySchemaFile(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	schemaSQL, err := os.ReadFile("../../db/schema.sql")
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	if err := ApplySchema(context.Background(), db, string(schemaSQL)); err != nil {
+		t.Fatalf("ApplySchema() error = %v", err)
+	}
+}
+
+func execFile(t *testing.T, db *sql.DB, path string) {
+	t.Helper()
+
+	schemaSQL, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	if _, err := db.Exec(string(schemaSQL)); err != nil {
+		t.Fatalf("exec %s: %v", path, err)
+	}
+}
+
+func TestMigrateAddsCarColumns(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	execFile(t, db, "testdata/baseline_schema.sql")
+	if _, err := db.Exec(`INSERT INTO cars (inventory_id, make, model, year, color, vin) VALUES (1, 'Volvo', 'V70', 2015, 'Red', '1HGCM82633A004352'), (1, 'Volvo', 'V90', 2019, 'Grey', '1HGCM82633A004353'); DELETE FROM cars WHERE id = 2`); err != nil {
+		t.Fatalf("insert cars: %v", err)
+	}
+	// Rows referencing cars must survive the table being rebuilt.
+	if _, err := db.Exec(`CREATE TABLE car_notes (car_id INTEGER NOT NULL REFERENCES cars(id) ON DELETE CASCADE, note TEXT); INSERT INTO car_notes VALUES (1, 'scratched')`); err != nil {
+		t.Fatalf("create car_notes: %v", err)
+	}
+
+	// Migrating twice must be a no-op the second time.
+	for i := 0; i < 2; i++ {
+		if err := migrate(ctx, db); err != nil {
+			t.Fatalf("migrate() run %d error = %v", i+1, err)
+		}
+	}
+
+	columns, err := tableColumns(ctx, db, "cars")
+	if err != nil {
+		t.Fatalf("tableColumns() error = %v", err)
+	}
+	for _, m := range columnMigrations {
+		if m.table == "cars" && !columns[m.column] {
+			t.Fatalf("cars has no %s column after migrate()", m.column)
+		}
+	}
+
+	var version int64
+	if err := db.QueryRow(`SELECT version FROM cars`).Scan(&version); err != nil || version != 1 {
+		t.Fatalf("version of an existing car = %d, %v; want 1", version, err)
+	}
+
+	var notes int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM car_notes`).Scan(&notes); err != nil || notes != 1 {
+		t.Fatalf("car_notes rows after migrate() = %d, %v; want 1", notes, err)
+	}
+	// The VIN of a deleted car can be reused once the inline UNIQUE is gone.
+	if _, err := db.Exec(`UPDATE cars SET deleted_at = CURRENT_TIMESTAMP; INSERT INTO cars (inventory_id, make, model, year, color, vin) VALUES (1, 'Volvo', 'V70', 2015, 'Blue', '1HGCM82633A004352')`); err != nil {
+		t.Fatalf("reuse the vin of a deleted car: %v", err)
+	}
+	var id int64
+	if err := db.QueryRow(`SELECT MAX(id) FROM cars`).Scan(&id); err != nil || id != 3 {
+		t.Fatalf("id of the car inserted after migrate() = %d, %v; want 3", id, err)
+	}
+}
+
+func TestMigrateNormalizesVINs(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	execFile(t, db, "testdata/baseline_schema.sql")
+	if _, err := db.Exec(`INSERT INTO cars (inventory_id, make, model, year, color, vin) VALUES
+(1, 'Volvo', 'V70', 2015, 'Red', ' 1hgcm82633a004352 '),
+(1, 'Ford', 'Model T', 1925, 'Black', 'legacy-vin-1'),
+(1, 'Ford', 'Model A', 1929, 'Black', 'LEGACY-VIN-1')`); err != nil {
+		t.Fatalf("insert cars: %v", err)
+	}
+
+	err := migrate(ctx, db)
+	if err == nil || !strings.Contains(err.Error(), "LEGACY-VIN-1 (cars 2, 3)") {
+		t.Fatalf("migrate() with colliding vins error = %v, want the collision reported", err)
+	}
+
+	if _, err := db.Exec(`UPDATE cars SET deleted_at = CURRENT_TIMESTAMP WHERE id = 3`); err != nil {
+		t.Fatalf("delete car: %v", err)
+	}
+	if err := migrate(ctx, db); err != nil {
+		t.Fatalf("migrate() error = %v", err)
+	}
+
+	rows, err := db.Query(`SELECT vin FROM cars ORDER BY id`)
+	if err != nil {
+		t.Fatalf("query vins: %v", err)
+	}
+	defer rows.Close()
+	var vins []string
+	for rows.Next() {
+		var vin string
+		if err := rows.Scan(&vin); err != nil {
+			t.Fatalf("scan vin: %v", err)
+		}
+		vins = append(vins, vin)
+	}
+	if want := []string{"1HGCM82633A004352", "LEGACY-VIN-1", "LEGACY-VIN-1"}; strings.Join(vins, ",") != strings.Join(want, ",") {
+		t.Fatalf("vins = %v, want %v", vins, want)
+	}
+}
+
+func TestMigrateAddsIdempotencyReservedAt(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(`CREATE TABLE idempotency_keys (
+    key TEXT PRIMARY KEY,
+    request_hash TEXT NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_headers TEXT NOT NULL DEFAULT '{}',
+    response_body BLOB,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO idempotency_keys (key, request_hash, created_at) VALUES ('old', 'hash-1', '2020-01-02 03:04:05')`); err != nil {
+		t.Fatalf("create idempotency_keys: %v", err)
+	}
+	applySchemaFile(t, db)
+
+	repo := NewSQLiteIdempotencyRepository(NewSQLDBAdapter(db))
+	old, err := repo.GetByKey(ctx, "old")
+	if err != nil || !old.ReservedAt.Equal(old.CreatedAt) {
+		t.Fatalf("GetByKey() = %+v, %v; want reserved when created", old, err)
+	}
+	if reserved, err := repo.Reserve(ctx, "new", "hash-1"); err != nil || !reserved {
+		t.Fatalf("Reserve() = %v, %v; want true", reserved, err)
+	}
+
+	// Only the reservation made before the lease is taken over.
+	leaseStart := time.Now().Add(-time.Minute)
+	for key, want := range map[string]bool{"old": true, "new": false} {
+		if reclaimed, err := repo.Reclaim(ctx, key, "hash-1", leaseStart); err != nil || reclaimed != want {
+			t.Fatalf("Reclaim(%q) = %v, %v; want %v", key, reclaimed, err, want)
+		}
+	}
+	if reclaimed, err := repo.Reclaim(ctx, "old", "hash-1", leaseStart); err != nil || reclaimed {
+		t.Fatalf("Reclaim() of a reclaimed key = %v, %v; want false", reclaimed, err)
+	}
+}
+
+func TestApplySchemaUpgradesBaselineDatabase(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	execFile(t, db, "testdata/baseline_schema.sql")
+	if _, err := db.Exec(`INSERT INTO cars (inventory_id, make, model, year, color, vin) VALUES (1, 'Volvo', 'V70', 2015, 'Red', '1HGCM82633A004352')`); err != nil {
+		t.Fatalf("insert car: %v", err)
+	}
+
+	// A restart applies the schema again.
+	applySchemaFile(t, db)
+	applySchemaFile(t, db)
+
+	repo := NewSQLiteCarRepository(NewSQLDBAdapter(db))
+	car, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if car.Version != 1 || car.Status != models.StatusInStock || !car.PriceChangedAt.Equal(car.UpdatedAt) {
+		t.Fatalf("GetByID() = %+v, want version 1, in stock, price changed when last updated", car)
+	}
+	if history, err := repo.GetHistory(ctx, 1); err != nil || len(history) != 1 || history[0].Action != "create" {
+		t.Fatalf("GetHistory() = %+v, %v; want the backfilled create", history, err)
+	}
+	if prices, err := repo.GetPriceHistory(ctx, 1); err != nil || len(prices) != 1 {
+		t.Fatalf("GetPriceHistory() = %+v, %v; want the backfilled price", prices, err)
+	}
+	if ids := searchIDs(t, repo, "volvo"); len(ids) != 1 || ids[0] != 1 {
+		t.Fatalf("Search() = %v, want the car written before the search index", ids)
+	}
+
+	// The VIN is unique among active cars only.
+	if err := repo.Delete(ctx, 1, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	again := &models.Car{InventoryID: 1, Make: "Volvo", Model: "V70", Year: 2015, Color: "Blue", VIN: car.VIN, Status: models.StatusInStock}
+	if err := repo.Create(ctx, again); err != nil {
+		t.Fatalf("Create() with the vin of a deleted car error = %v", err)
+	}
+	duplicate := *again
+	if err := repo.Create(ctx, &duplicate); !errors.Is(err, ErrUniqueViolation) {
+		t.Fatalf("Create() with the vin of an active car error = %v, want ErrUniqueViolation", err)
+	}
+	if created, err := repo.GetByID(ctx, again.ID); err != nil || created.PriceChangedAt.Before(car.PriceChangedAt) {
+		t.Fatalf("GetByID() of a new car = %+v, %v; want price_changed_at set on insert", created, err)
+	}
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"carsapi/internal/models"
+)
+
+type ServiceRecordRepository interface {
+	Create(ctx context.Context, record *models.ServiceRecord) error
+	GetByID(ctx context.Context, id int64) (*models.ServiceRecord, error)
+	GetByCarID(ctx context.Context, carID int64) ([]*models.ServiceRecord, error)
+	Delete(ctx context.Context, id int64) error
+	UpdateSummary(ctx context.Context, carID int64) error
+	// WithinTx runs fn with service record and car repositories sharing one
+	// transaction, so that a record and the car summarizing it are written
+	// together.
+	WithinTx(ctx context.Context, fn func(ServiceRecordRepository, CarRepository) error) error
+}
@@ -3,8 +3,22 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
 )
 
+// OpenSQLite opens the SQLite database at path. foreign_keys and
+// busy_timeout are per-connection settings, so they go in the DSN to apply
+// to every connection in the pool rather than just the first. busy_timeout
+// lets writers wait out a bulk transaction, and _txlock=immediate makes
+// transactions take the write lock when they begin: a deferred transaction
+// that reads before it writes gets SQLITE_BUSY, which busy_timeout does not
+// retry, if another writer took the lock in between.
+func OpenSQLite(path string) (*sql.DB, error) {
+	return sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate")
+}
+
 type SQLDBAdapter struct {
 	db *sql.DB
 }
@@ -24,3 +38,91 @@ func (a *SQLDBAdapter) QueryRowContext(ctx context.Context, query string, args .
 func (a *SQLDBAdapter) QueryContext(ctx context.Context, query string, args ...any) (Rows, error) {
 	return a.db.QueryContext(ctx, query, args...)
 }
+
+// BeginTx starts a write transaction, which on a database opened with
+// OpenSQLite waits for the write lock up front.
+func (a *SQLDBAdapter) BeginTx(ctx context.Context) (Tx, error) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlTxAdapter{tx: tx, savepoints: new(int)}, nil
+}
+
+// sqlTxAdapter is either the outer *sql.Tx or, when savepoint is set, a
+// savepoint nested inside it.
+type sqlTxAdapter struct {
+	tx         *sql.Tx
+	savepoint  string
+	savepoints *int
+}
+
+func (t *sqlTxAdapter) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+func (t *sqlTxAdapter) QueryRowContext(ctx context.Context, query string, args ...any) Row {
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (t *sqlTxAdapter) QueryContext(ctx context.Context, query string, args ...any) (Rows, error) {
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+func (t *sqlTxAdapter) BeginTx(ctx context.Context) (Tx, error) {
+	*t.savepoints++
+	name := fmt.Sprintf("sp%d", *t.savepoints)
+	if _, err := t.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+
+	return &sqlTxAdapter{tx: t.tx, savepoint: name, savepoints: t.savepoints}, nil
+}
+
+func (t *sqlTxAdapter) Commit() error {
+	if t.savepoint == "" {
+		return t.tx.Commit()
+	}
+
+	_, err := t.tx.Exec("RELEASE " + t.savepoint)
+	return err
+}
+
+func (t *sqlTxAdapter) Rollback() error {
+	if t.savepoint == "" {
+		return t.tx.Rollback()
+	}
+
+	// ROLLBACK TO keeps the savepoint open, so it is released afterwards.
+	if _, err := t.tx.Exec("ROLLBACK TO " + t.savepoint); err != nil {
+		return err
+	}
+	_, err := t.tx.Exec("RELEASE " + t.savepoint)
+	return err
+}
+
+// withinTx runs fn in a transaction on db, committing if fn succeeds and
+// rolling back otherwise.
+func withinTx(ctx context.Context, db DB, fn func(DB) error) error {
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"carsapi/internal/models"
+)
+
+// Transactions that read a car before writing it must not fail when they
+// overlap: each has to wait for the write lock rather than hit SQLITE_BUSY.
+func TestConcurrentWriteTransactions(t *testing.T) {
+	db := openSchemaDB(t)
+	repo := NewSQLiteCarRepository(NewSQLDBAdapter(db))
+	ctx := context.Background()
+
+	car := &models.Car{InventoryID: 1, Make: "Volvo", Model: "V70", Year: 2015, Color: "Red", VIN: "1HGCM82633A004352", Status: models.StatusInStock}
+	if err := repo.Create(ctx, car); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	const writers = 20
+	errs := make(chan error, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.WithinTx(ctx, func(tx CarRepository) error {
+				current, err := tx.GetByID(ctx, car.ID)
+				if err != nil {
+					return err
+				}
+				current.Mileage += 100
+				return tx.Update(ctx, current)
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("WithinTx() error = %v", err)
+		}
+	}
+	updated, err := repo.GetByID(ctx, car.ID)
+	if err != nil || updated.Mileage != writers*100 || updated.Version != writers+1 {
+		t.Fatalf("GetByID() = %+v, %v; want every write applied", updated, err)
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"carsapi/internal/models"
+)
+
+const (
+	createAttachmentQuery      = `INSERT INTO attachments (car_id, filename, content_type, size, sha256, storage_key, thumbnail_key) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id, created_at`
+	getAttachmentByIDQuery     = `SELECT id, car_id, filename, content_type, size, sha256, storage_key, thumbnail_key, created_at FROM attachments WHERE id = ?`
+	getAttachmentsByCarIDQuery = `SELECT id, car_id, filename, content_type, size, sha256, storage_key, thumbnail_key, created_at FROM attachments WHERE car_id = ? ORDER BY id ASC`
+	deleteAttachmentQuery      = `DELETE FROM attachments WHERE id = ?`
+)
+
+type SQLiteAttachmentRepository struct {
+	db DB
+}
+
+func NewSQLiteAttachmentRepository(db DB) *SQLiteAttachmentRepository {
+	return &SQLiteAttachmentRepository{db: db}
+}
+
+func (r *SQLiteAttachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	row := r.db.QueryRowContext(ctx, createAttachmentQuery, attachment.CarID, attachment.Filename, attachment.ContentType, attachment.Size, attachment.SHA256, attachment.StorageKey, attachment.ThumbnailKey)
+	if err := row.Scan(&attachment.ID, timestamp{&attachment.CreatedAt}); err != nil {
+		return classifyConstraint(err, "car_id")
+	}
+
+	return nil
+}
+
+func (r *SQLiteAttachmentRepository) GetByID(ctx context.Context, id int64) (*models.Attachment, error) {
+	return scanAttachment(r.db.QueryRowContext(ctx, getAttachmentByIDQuery, id))
+}
+
+func (r *SQLiteAttachmentRepository) GetByCarID(ctx context.Context, carID int64) ([]*models.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, getAttachmentsByCarIDQuery, carID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := make([]*models.Attachment, 0)
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (r *SQLiteAttachmentRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, deleteAttachmentQuery, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+func scanAttachment(row Row) (*models.Attachment, error) {
+	attachment := &models.Attachment{}
+	if err := row.Scan(&attachment.ID, &attachment.CarID, &attachment.Filename, &attachment.ContentType, &attachment.Size, &attachment.SHA256, &attachment.StorageKey, &attachment.ThumbnailKey, timestamp{&attachment.CreatedAt}); err != nil {
+		return nil, err
+	}
+	attachment.HasThumbnail = attachment.ThumbnailKey != ""
+
+	return attachment, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"carsapi/internal/models"
+)
+
+const (
+	insertCarHistoryQuery = `INSERT INTO car_history (car_id, action, actor, old_values, new_values) VALUES (?, ?, ?, ?, ?)`
+	getCarHistoryQuery    = `SELECT id, car_id, action, actor, COALESCE(old_values, ''), COALESCE(new_values, ''), changed_at FROM car_history WHERE car_id = ? ORDER BY id ASC`
+)
+
+func (r *SQLiteCarRepository) AddHistory(ctx context.Context, entry *models.CarHistoryEntry) error {
+	oldValues, err := marshalSnapshot(entry.Old)
+	if err != nil {
+		return err
+	}
+	newValues, err := marshalSnapshot(entry.New)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, insertCarHistoryQuery, entry.CarID, entry.Action, entry.Actor, oldValues, newValues)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	entry.ID = id
+	return nil
+}
+
+// GetHistory returns the changes recorded for a car, oldest first.
+func (r *SQLiteCarRepository) GetHistory(ctx context.Context, carID int64) ([]*models.CarHistoryEntry, error) {
+	rows, err := r.db.QueryContext(ctx, getCarHistoryQuery, carID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*models.CarHistoryEntry, 0)
+	for rows.Next() {
+		entry := &models.CarHistoryEntry{}
+		var oldValues, newValues string
+		if err := rows.Scan(&entry.ID, &entry.CarID, &entry.Action, &entry.Actor, &oldValues, &newValues, &entry.ChangedAt); err != nil {
+			return nil, err
+		}
+		if entry.Old, err = unmarshalSnapshot(oldValues); err != nil {
+			return nil, err
+		}
+		if entry.New, err = unmarshalSnapshot(newValues); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// marshalSnapshot encodes car for a history column; a nil car is stored as
+// NULL.
+func marshalSnapshot(car *models.Car) (any, error) {
+	if car == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(car)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+func unmarshalSnapshot(data string) (*models.Car, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	car := &models.Car{}
+	if err := json.Unmarshal([]byte(data), car); err != nil {
+		return nil, err
+	}
+
+	return car, nil
+}
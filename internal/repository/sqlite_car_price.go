@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"carsapi/internal/models"
+)
+
+const (
+	insertCarPriceQuery = `INSERT INTO car_prices (car_id, price, currency, changed_by, changed_at) VALUES (?, ?, ?, ?, ?)`
+	getCarPricesQuery   = `SELECT id, car_id, price, currency, changed_by, changed_at FROM car_prices WHERE car_id = ? ORDER BY changed_at ASC, id ASC`
+)
+
+func (r *SQLiteCarRepository) AddPriceChange(ctx context.Context, change *models.PriceChange) error {
+	result, err := r.db.ExecContext(ctx, insertCarPriceQuery, change.CarID, change.Price, change.Currency, change.ChangedBy, sqliteTime(change.ChangedAt))
+	if err != nil {
+		return classifyConstraint(err, "car_id")
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	change.ID = id
+	return nil
+}
+
+// GetPriceHistory returns the prices a car has been listed at, oldest first.
+func (r *SQLiteCarRepository) GetPriceHistory(ctx context.Context, carID int64) ([]*models.PriceChange, error) {
+	rows, err := r.db.QueryContext(ctx, getCarPricesQuery, carID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]*models.PriceChange, 0)
+	for rows.Next() {
+		change := &models.PriceChange{}
+		if err := rows.Scan(&change.ID, &change.CarID, &change.Price, &change.Currency, &change.ChangedBy, timestamp{&change.ChangedAt}); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
@@ -3,16 +3,27 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
 	"carsapi/internal/models"
 )
 
 const (
-	createCarQuery  = `INSERT INTO cars (inventory_id, make, model, year, color, vin) VALUES (?, ?, ?, ?, ?, ?)`
-	getCarByIDQuery = `SELECT id, inventory_id, make, model, year, color, vin FROM cars WHERE id = ?`
-	getAllCarsQuery = `SELECT id, inventory_id, make, model, year, color, vin FROM cars ORDER BY id ASC`
-	updateCarQuery  = `UPDATE cars SET inventory_id = ?, make = ?, model = ?, year = ?, color = ?, vin = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	deleteCarQuery  = `DELETE FROM cars WHERE id = ?`
+	createCarQuery            = `INSERT INTO cars (inventory_id, make, model, year, color, vin, status, price, currency, price_changed_at, mileage, attributes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?, ?)`
+	getCarByIDQuery           = `SELECT id, inventory_id, make, model, year, color, vin, status, price, currency, price_changed_at, mileage, reserved_until, attributes, ` + carTagsColumn + `, service_summary, version, created_at, updated_at FROM cars WHERE id = ? AND deleted_at IS NULL`
+	getCarByVINQuery          = `SELECT id, inventory_id, make, model, year, color, vin, status, price, currency, price_changed_at, mileage, reserved_until, attributes, ` + carTagsColumn + `, service_summary, version, created_at, updated_at FROM cars WHERE vin = ? AND deleted_at IS NULL`
+	getCarVersionQuery        = `SELECT version FROM cars WHERE id = ? AND deleted_at IS NULL`
+	getCarsByInventoryIDQuery = `SELECT id, inventory_id, make, model, year, color, vin, status, price, currency, price_changed_at, mileage, reserved_until, attributes, ` + carTagsColumn + `, service_summary, version, created_at, updated_at FROM cars WHERE inventory_id = ? AND deleted_at IS NULL ORDER BY id ASC`
+	// updateCarQuery compares the new price and currency, parameters 7 and 8,
+	// with the stored ones to tell whether the price changed.
+	updateCarQuery       = `UPDATE cars SET inventory_id = ?, make = ?, model = ?, year = ?, color = ?, vin = ?, price = ?, currency = ?, price_changed_at = CASE WHEN price = ?7 AND currency = ?8 THEN price_changed_at ELSE CURRENT_TIMESTAMP END, mileage = ?, attributes = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`
+	transferCarQuery     = `UPDATE cars SET inventory_id = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`
+	deleteCarQuery       = `UPDATE cars SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`
+	getDeletedCarsQuery  = `SELECT id, inventory_id, make, model, year, color, vin, status, price, currency, price_changed_at, mileage, reserved_until, attributes, ` + carTagsColumn + `, service_summary, version, created_at, updated_at, deleted_at FROM cars WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC`
+	restoreCarQuery      = `UPDATE cars SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NOT NULL`
+	purgeCarQuery        = `DELETE FROM cars WHERE id = ? AND deleted_at IS NOT NULL`
+	updateCarStatusQuery = `UPDATE cars SET status = ?, reserved_until = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`
 )
 
 type SQLiteCarRepository struct {
@@ -23,35 +34,170 @@ func NewSQLiteCarRepository(db DB) *SQLiteCarRepository {
 	return &SQLiteCarRepository{db: db}
 }
 
+// Create inserts car and its tags. Callers run it in WithinTx so that the
+// two are written together.
 func (r *SQLiteCarRepository) Create(ctx context.Context, car *models.Car) error {
-	result, err := r.db.ExecContext(ctx, createCarQuery, car.InventoryID, car.Make, car.Model, car.Year, car.Color, car.VIN)
+	attributes, err := marshalAttributes(car.Attributes)
 	if err != nil {
 		return err
 	}
 
+	result, err := r.db.ExecContext(ctx, createCarQuery, car.InventoryID, car.Make, car.Model, car.Year, car.Color, car.VIN, car.Status, car.Price, car.Currency, car.Mileage, attributes)
+	if err != nil {
+		return classifyConstraint(err, "inventory_id")
+	}
+
 	id, err := result.LastInsertId()
 	if err != nil {
 		return err
 	}
 
 	car.ID = id
-	return nil
+	return r.setTags(ctx, id, car.Tags)
 }
 
 func (r *SQLiteCarRepository) GetByID(ctx context.Context, id int64) (*models.Car, error) {
-	row := r.db.QueryRowContext(ctx, getCarByIDQuery, id)
+	return scanCar(r.db.QueryRowContext(ctx, getCarByIDQuery, id))
+}
 
-	car := &models.Car{}
-	err := row.Scan(&car.ID, &car.InventoryID, &car.Make, &car.Model, &car.Year, &car.Color, &car.VIN)
+// GetByIDAsOf returns the car as it was at asOf, or sql.ErrNoRows if it did
+// not exist or was deleted at that moment.
+func (r *SQLiteCarRepository) GetByIDAsOf(ctx context.Context, id int64, asOf time.Time) (*models.Car, error) {
+	return scanCar(r.db.QueryRowContext(ctx, carsAsOfQuery+getCarByIDQuery, sqliteTime(asOf), id))
+}
+
+func (r *SQLiteCarRepository) GetByVIN(ctx context.Context, vin string) (*models.Car, error) {
+	return scanCar(r.db.QueryRowContext(ctx, getCarByVINQuery, vin))
+}
+
+func (r *SQLiteCarRepository) GetAll(ctx context.Context, filter models.CarFilter) ([]*models.Car, string, error) {
+	query, args, err := buildListCarsQuery(filter)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return car, nil
+	cars, err := r.queryCars(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(cars) <= filter.Limit {
+		return cars, "", nil
+	}
+
+	cars = cars[:filter.Limit]
+	next, err := encodeCarCursor(filter, cars[len(cars)-1])
+	if err != nil {
+		return nil, "", err
+	}
+
+	return cars, next, nil
+}
+
+func (r *SQLiteCarRepository) Count(ctx context.Context, filter models.CarFilter) (int64, error) {
+	query, args := buildCountCarsQuery(filter)
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (r *SQLiteCarRepository) Search(ctx context.Context, text string, limit int) ([]*models.Car, error) {
+	match := buildCarSearchMatch(text)
+	if match == "" {
+		return []*models.Car{}, nil
+	}
+
+	return r.queryCars(ctx, searchCarsQuery, match, limit)
+}
+
+func (r *SQLiteCarRepository) GetByInventoryID(ctx context.Context, inventoryID int64) ([]*models.Car, error) {
+	return r.queryCars(ctx, getCarsByInventoryIDQuery, inventoryID)
+}
+
+// Update replaces car and its tags; like Create, it belongs in WithinTx.
+func (r *SQLiteCarRepository) Update(ctx context.Context, car *models.Car) error {
+	attributes, err := marshalAttributes(car.Attributes)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, updateCarQuery, car.InventoryID, car.Make, car.Model, car.Year, car.Color, car.VIN, car.Price, car.Currency, car.Mileage, attributes, car.ID, car.Version, car.Version)
+	if err != nil {
+		return classifyConstraint(err, "inventory_id")
+	}
+
+	if err := r.checkVersionedWrite(ctx, result, car.ID); err != nil {
+		return err
+	}
+	return r.setTags(ctx, car.ID, car.Tags)
+}
+
+// UpsertByVIN inserts car or replaces the car that already has its VIN,
+// reporting whether a row was inserted. A non-zero car.Version makes the
+// replacement conditional on it, yielding ErrStaleVersion on mismatch.
+func (r *SQLiteCarRepository) UpsertByVIN(ctx context.Context, car *models.Car) (bool, error) {
+	attributes, err := marshalAttributes(car.Attributes)
+	if err != nil {
+		return false, err
+	}
+
+	row := r.db.QueryRowContext(ctx, upsertCarByVINQuery, car.InventoryID, car.Make, car.Model, car.Year, car.Color, car.VIN, car.Status, car.Price, car.Currency, car.Mileage, attributes, car.Version, car.Version)
+
+	var id, version int64
+	err = row.Scan(&id, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		// DO UPDATE ... WHERE skipped the existing row.
+		return false, ErrStaleVersion
+	}
+	if err != nil {
+		return false, classifyConstraint(err, "inventory_id")
+	}
+
+	car.ID = id
+	car.Version = version
+	if err := r.setTags(ctx, id, car.Tags); err != nil {
+		return false, err
+	}
+	// Every update bumps the version, so only an inserted row is at 1.
+	return version == 1, nil
+}
+
+// UpdateStatus writes car.Status and car.ReservedUntil. A non-zero
+// car.Version makes the write conditional on it, yielding ErrStaleVersion on
+// mismatch.
+func (r *SQLiteCarRepository) UpdateStatus(ctx context.Context, car *models.Car) error {
+	result, err := r.db.ExecContext(ctx, updateCarStatusQuery, car.Status, nullableTime(car.ReservedUntil), car.ID, car.Version, car.Version)
+	if err != nil {
+		return err
+	}
+
+	return r.checkVersionedWrite(ctx, result, car.ID)
+}
+
+func (r *SQLiteCarRepository) Transfer(ctx context.Context, id, inventoryID int64) error {
+	result, err := r.db.ExecContext(ctx, transferCarQuery, inventoryID, id)
+	if err != nil {
+		return classifyConstraint(err, "inventory_id")
+	}
+
+	return checkRowsAffected(result)
 }
 
-func (r *SQLiteCarRepository) GetAll(ctx context.Context) ([]*models.Car, error) {
-	rows, err := r.db.QueryContext(ctx, getAllCarsQuery)
+func (r *SQLiteCarRepository) Delete(ctx context.Context, id, version int64) error {
+	result, err := r.db.ExecContext(ctx, deleteCarQuery, id, version, version)
+	if err != nil {
+		return err
+	}
+
+	return r.checkVersionedWrite(ctx, result, id)
+}
+
+func (r *SQLiteCarRepository) GetDeleted(ctx context.Context) ([]*models.Car, error) {
+	rows, err := r.db.QueryContext(ctx, getDeletedCarsQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -60,9 +206,11 @@ func (r *SQLiteCarRepository) GetAll(ctx context.Context) ([]*models.Car, error)
 	cars := make([]*models.Car, 0)
 	for rows.Next() {
 		car := &models.Car{}
-		if err := rows.Scan(&car.ID, &car.InventoryID, &car.Make, &car.Model, &car.Year, &car.Color, &car.VIN); err != nil {
+		var deletedAt time.Time
+		if err := rows.Scan(&car.ID, &car.InventoryID, &car.Make, &car.Model, &car.Year, &car.Color, &car.VIN, &car.Status, &car.Price, &car.Currency, timestamp{&car.PriceChangedAt}, &car.Mileage, nullTimestamp{&car.ReservedUntil}, jsonColumn{&car.Attributes}, jsonColumn{&car.Tags}, jsonColumn{&car.ServiceSummary}, &car.Version, timestamp{&car.CreatedAt}, timestamp{&car.UpdatedAt}, timestamp{&deletedAt}); err != nil {
 			return nil, err
 		}
+		car.DeletedAt = &deletedAt
 		cars = append(cars, car)
 	}
 
@@ -73,30 +221,54 @@ func (r *SQLiteCarRepository) GetAll(ctx context.Context) ([]*models.Car, error)
 	return cars, nil
 }
 
-func (r *SQLiteCarRepository) Update(ctx context.Context, car *models.Car) error {
-	result, err := r.db.ExecContext(ctx, updateCarQuery, car.InventoryID, car.Make, car.Model, car.Year, car.Color, car.VIN, car.ID)
+// Restore takes a car out of the trash. It fails with a unique violation on
+// vin when another car has taken the VIN in the meantime.
+func (r *SQLiteCarRepository) Restore(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, restoreCarQuery, id)
 	if err != nil {
-		return err
+		return classifyConstraint(err, "")
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return checkRowsAffected(result)
+}
+
+// Purge permanently removes a car that is already in the trash.
+func (r *SQLiteCarRepository) Purge(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, purgeCarQuery, id)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
-	}
+	return checkRowsAffected(result)
+}
 
-	return nil
+func (r *SQLiteCarRepository) WithinTx(ctx context.Context, fn func(CarRepository) error) error {
+	return withinTx(ctx, r.db, func(tx DB) error {
+		return fn(NewSQLiteCarRepository(tx))
+	})
 }
 
-func (r *SQLiteCarRepository) Delete(ctx context.Context, id int64) error {
-	result, err := r.db.ExecContext(ctx, deleteCarQuery, id)
+// checkVersionedWrite tells a missing car (sql.ErrNoRows) apart from a
+// version mismatch (ErrStaleVersion) when a conditional write touched no rows.
+func (r *SQLiteCarRepository) checkVersionedWrite(ctx context.Context, result sql.Result, id int64) error {
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var version int64
+	if err := r.db.QueryRowContext(ctx, getCarVersionQuery, id).Scan(&version); err != nil {
+		return err
+	}
+
+	return ErrStaleVersion
+}
+
+func checkRowsAffected(result sql.Result) error {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
@@ -108,3 +280,35 @@ func (r *SQLiteCarRepository) Delete(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+func (r *SQLiteCarRepository) queryCars(ctx context.Context, query string, args ...any) ([]*models.Car, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cars := make([]*models.Car, 0)
+	for rows.Next() {
+		car, err := scanCar(rows)
+		if err != nil {
+			return nil, err
+		}
+		cars = append(cars, car)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cars, nil
+}
+
+func scanCar(row Row) (*models.Car, error) {
+	car := &models.Car{}
+	if err := row.Scan(&car.ID, &car.InventoryID, &car.Make, &car.Model, &car.Year, &car.Color, &car.VIN, &car.Status, &car.Price, &car.Currency, timestamp{&car.PriceChangedAt}, &car.Mileage, nullTimestamp{&car.ReservedUntil}, jsonColumn{&car.Attributes}, jsonColumn{&car.Tags}, jsonColumn{&car.ServiceSummary}, &car.Version, timestamp{&car.CreatedAt}, timestamp{&car.UpdatedAt}); err != nil {
+		return nil, err
+	}
+
+	return car, nil
+}
@@ -0,0 +1,379 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"carsapi/internal/models"
+)
+
+// The tests in this file run the repository against db/schema.sql on a real
+// SQLite database, covering the SQL that the query-matching fakes in
+// car_repository_test.go take on trust.
+
+func newSchemaRepository(t *testing.T) (*SQLiteCarRepository, *sql.DB) {
+	t.Helper()
+
+	db := openSchemaDB(t)
+	return NewSQLiteCarRepository(NewSQLDBAdapter(db)), db
+}
+
+func createTestCar(t *testing.T, repo *SQLiteCarRepository, carMake, model, color, vin string) *models.Car {
+	t.Helper()
+
+	car := &models.Car{InventoryID: 1, Make: carMake, Model: model, Year: 2018, Color: color, VIN: vin, Status: models.StatusInStock, Tags: []string{"demo"}}
+	if err := repo.Create(context.Background(), car); err != nil {
+		t.Fatalf("Create(%s) error = %v", vin, err)
+	}
+	return car
+}
+
+func searchIDs(t *testing.T, repo *SQLiteCarRepository, text string) []int64 {
+	t.Helper()
+
+	cars, err := repo.Search(context.Background(), text, 10)
+	if err != nil {
+		t.Fatalf("Search(%q) error = %v", text, err)
+	}
+	ids := make([]int64, len(cars))
+	for i, car := range cars {
+		ids[i] = car.ID
+	}
+	return ids
+}
+
+func TestSQLiteSearchFollowsCarWrites(t *testing.T) {
+	repo, db := newSchemaRepository(t)
+	ctx := context.Background()
+	accord := createTestCar(t, repo, "Honda", "Accord", "Silver", "1HGCM82633A004352")
+	civic := createTestCar(t, repo, "Honda", "Civic", "Red", "2HGFG12698H500001")
+
+	if got := searchIDs(t, repo, "honda"); len(got) != 2 {
+		t.Fatalf("Search(honda) = %v, want both cars", got)
+	}
+	if got := searchIDs(t, repo, "acc"); len(got) != 1 || got[0] != accord.ID {
+		t.Fatalf("Search(acc) = %v, want [%d]", got, accord.ID)
+	}
+
+	accord.Color = "Blue"
+	if err := repo.Update(ctx, accord); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := searchIDs(t, repo, "silver"); len(got) != 0 {
+		t.Fatalf("Search(silver) after repaint = %v, want none", got)
+	}
+	if got := searchIDs(t, repo, "blue"); len(got) != 1 || got[0] != accord.ID {
+		t.Fatalf("Search(blue) after repaint = %v, want [%d]", got, accord.ID)
+	}
+
+	// Soft-deleted cars stay indexed but are filtered out; purged ones leave
+	// the index.
+	if err := repo.Delete(ctx, civic.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := searchIDs(t, repo, "civic"); len(got) != 0 {
+		t.Fatalf("Search(civic) after delete = %v, want none", got)
+	}
+	if err := repo.Purge(ctx, civic.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	var indexed int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM cars_fts WHERE cars_fts MATCH 'civic'`).Scan(&indexed); err != nil || indexed != 0 {
+		t.Fatalf("cars_fts rows for a purged car = %d, %v; want 0", indexed, err)
+	}
+}
+
+func TestSQLiteVINUniqueAmongActiveCars(t *testing.T) {
+	repo, _ := newSchemaRepository(t)
+	ctx := context.Background()
+	car := createTestCar(t, repo, "Honda", "Accord", "Silver", "1HGCM82633A004352")
+
+	duplicate := &models.Car{InventoryID: 1, Make: "Honda", Model: "Accord", Year: 2018, Color: "Black", VIN: car.VIN, Status: models.StatusInStock}
+	if err := repo.Create(ctx, duplicate); !errors.Is(err, ErrUniqueViolation) {
+		t.Fatalf("Create() with an active vin error = %v, want ErrUniqueViolation", err)
+	}
+
+	if err := repo.Delete(ctx, car.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Create(ctx, duplicate); err != nil {
+		t.Fatalf("Create() with the vin of a deleted car error = %v", err)
+	}
+
+	// The deleted car cannot come back while its VIN is taken.
+	if err := repo.Restore(ctx, car.ID); !errors.Is(err, ErrUniqueViolation) {
+		t.Fatalf("Restore() of a car whose vin was reused error = %v, want ErrUniqueViolation", err)
+	}
+}
+
+func TestSQLiteUpsertByVIN(t *testing.T) {
+	repo, _ := newSchemaRepository(t)
+	ctx := context.Background()
+	vin := "1HGCM82633A004352"
+
+	car := &models.Car{InventoryID: 1, Make: "Honda", Model: "Accord", Year: 2018, Color: "Silver", VIN: vin, Status: models.StatusInStock, Price: 1500000, Currency: "USD"}
+	if created, err := repo.UpsertByVIN(ctx, car); err != nil || !created || car.Version != 1 {
+		t.Fatalf("first UpsertByVIN() = %v, %v with version %d; want created at version 1", created, err, car.Version)
+	}
+	firstID := car.ID
+
+	car.Color = "Black"
+	if created, err := repo.UpsertByVIN(ctx, car); err != nil || created || car.ID != firstID || car.Version != 2 {
+		t.Fatalf("second UpsertByVIN() = %v, %v with id %d version %d; want replaced %d at version 2", created, err, car.ID, car.Version, firstID)
+	}
+	stale := *car
+	stale.Version = 1
+	if _, err := repo.UpsertByVIN(ctx, &stale); !errors.Is(err, ErrStaleVersion) {
+		t.Fatalf("UpsertByVIN() with a stale version error = %v, want ErrStaleVersion", err)
+	}
+	if got, err := repo.GetByVIN(ctx, vin); err != nil || got.Color != "Black" || got.Version != 2 {
+		t.Fatalf("GetByVIN() = %+v, %v; want the black car at version 2", got, err)
+	}
+
+	// The conflict target only covers active cars, so a deleted car's VIN
+	// inserts a new row instead of reviving it.
+	if err := repo.Delete(ctx, firstID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	again := &models.Car{InventoryID: 1, Make: "Honda", Model: "Accord", Year: 2018, Color: "White", VIN: vin, Status: models.StatusInStock}
+	if created, err := repo.UpsertByVIN(ctx, again); err != nil || !created || again.ID == firstID {
+		t.Fatalf("UpsertByVIN() over a deleted car = %v, %v with id %d; want a new car", created, err, again.ID)
+	}
+
+	missing := &models.Car{InventoryID: 99, Make: "Honda", Model: "Fit", Year: 2018, Color: "Red", VIN: "JHMGD38498S000001", Status: models.StatusInStock}
+	if _, err := repo.UpsertByVIN(ctx, missing); !errors.Is(err, ErrForeignKeyViolation) {
+		t.Fatalf("UpsertByVIN() with an unknown inventory error = %v, want ErrForeignKeyViolation", err)
+	}
+}
+
+func TestSQLiteCarsAsOf(t *testing.T) {
+	repo, db := newSchemaRepository(t)
+	ctx := context.Background()
+	car := createTestCar(t, repo, "Honda", "Accord", "Silver", "1HGCM82633A004352")
+
+	// recordAt writes the car's current state to car_history as if it had
+	// been recorded at changedAt.
+	recordAt := func(action, changedAt string, deleted bool) {
+		t.Helper()
+		entry := &models.CarHistoryEntry{CarID: car.ID, Action: action, Actor: "test"}
+		if !deleted {
+			current, err := repo.GetByID(ctx, car.ID)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			entry.New = current
+		}
+		if err := repo.AddHistory(ctx, entry); err != nil {
+			t.Fatalf("AddHistory() error = %v", err)
+		}
+		if _, err := db.Exec(`UPDATE car_history SET changed_at = ? WHERE id = ?`, changedAt, entry.ID); err != nil {
+			t.Fatalf("backdate history: %v", err)
+		}
+	}
+
+	if _, err := db.Exec(`DELETE FROM car_history`); err != nil {
+		t.Fatalf("clear history: %v", err)
+	}
+	recordAt("create", "2024-01-01 00:00:00", false)
+	car.Color = "Blue"
+	car.Tags = []string{"sale"}
+	if err := repo.Update(ctx, car); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	recordAt("update", "2024-02-01 00:00:00", false)
+	if err := repo.Delete(ctx, car.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	recordAt("delete", "2024-03-01 00:00:00", true)
+
+	at := func(day string) time.Time {
+		t.Helper()
+		asOf, err := time.Parse(time.DateOnly, day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return asOf
+	}
+
+	tests := []struct {
+		asOf      string
+		wantColor string
+		wantTag   string
+	}{
+		{asOf: "2023-12-31"},
+		{asOf: "2024-01-15", wantColor: "Silver", wantTag: "demo"},
+		{asOf: "2024-02-15", wantColor: "Blue", wantTag: "sale"},
+		{asOf: "2024-03-15"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.asOf, func(t *testing.T) {
+			got, err := repo.GetByIDAsOf(ctx, car.ID, at(tt.asOf))
+			if tt.wantColor == "" {
+				if !errors.Is(err, sql.ErrNoRows) {
+					t.Fatalf("GetByIDAsOf() = %+v, %v; want sql.ErrNoRows", got, err)
+				}
+			} else if err != nil || got.Color != tt.wantColor || len(got.Tags) != 1 || got.Tags[0] != tt.wantTag {
+				t.Fatalf("GetByIDAsOf() = %+v, %v; want color %s tagged %s", got, err, tt.wantColor, tt.wantTag)
+			}
+
+			filter := models.CarFilter{SortBy: "id", Limit: 10, AsOf: at(tt.asOf)}
+			if tt.wantColor != "" {
+				filter.Color = tt.wantColor
+				filter.Tags = []string{tt.wantTag}
+			}
+			cars, _, err := repo.GetAll(ctx, filter)
+			if err != nil {
+				t.Fatalf("GetAll() error = %v", err)
+			}
+			total, err := repo.Count(ctx, filter)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			want := 0
+			if tt.wantColor != "" {
+				want = 1
+			}
+			if len(cars) != want || total != int64(want) {
+				t.Fatalf("GetAll() = %d cars, Count() = %d; want %d", len(cars), total, want)
+			}
+		})
+	}
+}
+
+func TestSQLiteHistoryBackfill(t *testing.T) {
+	db := openTestDB(t)
+	applySchemaFile(t, db)
+	repo := NewSQLiteCarRepository(NewSQLDBAdapter(db))
+	ctx := context.Background()
+
+	// Cars written without history, as before car_history existed.
+	live := createTestCar(t, repo, "Honda", "Accord", "Silver", "1HGCM82633A004352")
+	gone := createTestCar(t, repo, "Honda", "Civic", "Red", "2HGFG12698H500001")
+	if err := repo.Delete(ctx, gone.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	applySchemaFile(t, db)
+	// A second restart must not backfill again.
+	applySchemaFile(t, db)
+
+	history, err := repo.GetHistory(ctx, live.ID)
+	if err != nil || len(history) != 1 || history[0].Action != "create" || history[0].Actor != "system" {
+		t.Fatalf("GetHistory(live) = %+v, %v; want one system create", history, err)
+	}
+	if got := history[0].New; got == nil || got.VIN != live.VIN || len(got.Tags) != 1 || got.Tags[0] != "demo" {
+		t.Fatalf("backfilled snapshot = %+v, want the car with its tags", got)
+	}
+
+	history, err = repo.GetHistory(ctx, gone.ID)
+	if err != nil || len(history) != 2 || history[0].Action != "create" || history[1].Action != "delete" || history[1].New != nil {
+		t.Fatalf("GetHistory(deleted) = %+v, %v; want create then delete", history, err)
+	}
+
+	// The backfill is what as_of queries see.
+	if got, err := repo.GetByIDAsOf(ctx, live.ID, time.Now().Add(time.Minute)); err != nil || got.VIN != live.VIN {
+		t.Fatalf("GetByIDAsOf(live) = %+v, %v; want the car", got, err)
+	}
+	if _, err := repo.GetByIDAsOf(ctx, gone.ID, time.Now().Add(time.Minute)); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetByIDAsOf(deleted) error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestSQLiteSaleRepositoryWithinTx(t *testing.T) {
+	cars, db := newSchemaRepository(t)
+	sales := NewSQLiteSaleRepository(NewSQLDBAdapter(db))
+	ctx := context.Background()
+	car := createTestCar(t, cars, "Honda", "Accord", "Silver", "1HGCM82633A004352")
+
+	sell := func(fail error) error {
+		return sales.WithinTx(ctx, func(sales SaleRepository, cars CarRepository) error {
+			if err := sales.Create(ctx, &models.Sale{CarID: car.ID, BuyerName: "Ana", SoldAt: time.Now(), Salesperson: "sam"}); err != nil {
+				return err
+			}
+			sold := *car
+			sold.Status = models.StatusSold
+			if err := cars.UpdateStatus(ctx, &sold); err != nil {
+				return err
+			}
+			return fail
+		})
+	}
+
+	errBoom := errors.New("boom")
+	if err := sell(errBoom); !errors.Is(err, errBoom) {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errBoom)
+	}
+	if _, err := sales.GetByCarID(ctx, car.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetByCarID() after rollback error = %v, want sql.ErrNoRows", err)
+	}
+	if got, err := cars.GetByID(ctx, car.ID); err != nil || got.Status != models.StatusInStock {
+		t.Fatalf("GetByID() after rollback = %+v, %v; want in stock", got, err)
+	}
+
+	if err := sell(nil); err != nil {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+	if got, err := cars.GetByID(ctx, car.ID); err != nil || got.Status != models.StatusSold {
+		t.Fatalf("GetByID() after commit = %+v, %v; want sold", got, err)
+	}
+	if err := sell(nil); !errors.Is(err, ErrUniqueViolation) {
+		t.Fatalf("second sale error = %v, want ErrUniqueViolation", err)
+	}
+}
+
+func TestSQLiteServiceRecordRepositoryWithinTx(t *testing.T) {
+	cars, db := newSchemaRepository(t)
+	records := NewSQLiteServiceRecordRepository(NewSQLDBAdapter(db))
+	ctx := context.Background()
+	car := createTestCar(t, cars, "Honda", "Accord", "Silver", "1HGCM82633A004352")
+
+	errBoom := errors.New("boom")
+	err := records.WithinTx(ctx, func(records ServiceRecordRepository, cars CarRepository) error {
+		record := &models.ServiceRecord{CarID: car.ID, Kind: models.ServiceRepair, PerformedAt: time.Now(), Mileage: 1000, Cost: 5000, Currency: "USD", RecordedBy: "sam"}
+		if err := records.Create(ctx, record); err != nil {
+			return err
+		}
+		if err := records.UpdateSummary(ctx, car.ID); err != nil {
+			return err
+		}
+		if serviced, err := cars.GetByID(ctx, car.ID); err != nil || serviced.ServiceSummary == nil {
+			t.Fatalf("GetByID() inside the transaction = %+v, %v; want a summary", serviced, err)
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errBoom)
+	}
+
+	if got, err := records.GetByCarID(ctx, car.ID); err != nil || len(got) != 0 {
+		t.Fatalf("GetByCarID() after rollback = %+v, %v; want none", got, err)
+	}
+	if got, err := cars.GetByID(ctx, car.ID); err != nil || got.ServiceSummary != nil {
+		t.Fatalf("GetByID() after rollback = %+v, %v; want no summary", got, err)
+	}
+}
+
+func TestSQLiteInventoryRepositoryDeleteInUse(t *testing.T) {
+	cars, db := newSchemaRepository(t)
+	inventories := NewSQLiteInventoryRepository(NewSQLDBAdapter(db))
+	ctx := context.Background()
+	car := createTestCar(t, cars, "Honda", "Accord", "Silver", "1HGCM82633A004352")
+
+	// Cars in the trash still hold on to their inventory.
+	if err := cars.Delete(ctx, car.ID, 0); err != nil {
+		t.Fatalf("Delete() car error = %v", err)
+	}
+	if err := inventories.Delete(ctx, car.InventoryID); !errors.Is(err, ErrForeignKeyViolation) {
+		t.Fatalf("Delete() of an inventory with cars error = %v, want ErrForeignKeyViolation", err)
+	}
+
+	if err := cars.Purge(ctx, car.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if err := inventories.Delete(ctx, car.InventoryID); err != nil {
+		t.Fatalf("Delete() of an empty inventory error = %v", err)
+	}
+}
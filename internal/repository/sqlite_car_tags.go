@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// carTagsColumn selects the tags of the car in the enclosing query as a
+	// JSON array. It goes through car_tags and tags rather than a column of
+	// cars, so under carsAsOfQuery it reads the tags recorded in the
+	// snapshot.
+	carTagsColumn      = `(SELECT json_group_array(t.name ORDER BY t.name) FROM car_tags ct JOIN tags t ON t.id = ct.tag_id WHERE ct.car_id = cars.id)`
+	carHasTagCondition = `EXISTS (SELECT 1 FROM car_tags ct JOIN tags t ON t.id = ct.tag_id WHERE ct.car_id = cars.id AND t.name = ?)`
+
+	deleteCarTagsQuery = `DELETE FROM car_tags WHERE car_id = ?`
+	insertTagQuery     = `INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING`
+	insertCarTagQuery  = `INSERT INTO car_tags (car_id, tag_id) SELECT ?, id FROM tags WHERE name = ? ON CONFLICT DO NOTHING`
+)
+
+// setTags replaces the tags of a car. It runs several statements, so callers
+// wanting it atomic with the car write run both in WithinTx.
+func (r *SQLiteCarRepository) setTags(ctx context.Context, carID int64, tags []string) error {
+	if _, err := r.db.ExecContext(ctx, deleteCarTagsQuery, carID); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := r.db.ExecContext(ctx, insertTagQuery, tag); err != nil {
+			return err
+		}
+		if _, err := r.db.ExecContext(ctx, insertCarTagQuery, carID, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attributePath is the JSON path of a top-level attribute. Names are quoted
+// so that ones containing dots are not read as nested paths.
+func attributePath(name string) string {
+	return `$."` + name + `"`
+}
+
+func marshalAttributes(attributes map[string]string) (string, error) {
+	if len(attributes) == 0 {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// jsonColumn scans JSON text, such as the attributes column or an aggregated
+// array, into v.
+type jsonColumn struct {
+	v any
+}
+
+func (c jsonColumn) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(value), c.v)
+	case []byte:
+		return json.Unmarshal(value, c.v)
+	default:
+		return fmt.Errorf("cannot scan %T as JSON", src)
+	}
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+
+	"carsapi/internal/models"
+)
+
+const (
+	seedMakeQuery      = `INSERT INTO makes (name) VALUES (?) ON CONFLICT (name) DO NOTHING`
+	seedMakeAliasQuery = `INSERT INTO make_aliases (alias, make_id) SELECT ?, id FROM makes WHERE name = ? ON CONFLICT (alias) DO NOTHING`
+	seedModelQuery     = `INSERT INTO models (make_id, name) SELECT id, ? FROM makes WHERE name = ? ON CONFLICT (make_id, name) DO NOTHING`
+	seedTrimQuery      = `INSERT INTO trims (model_id, name) SELECT models.id, ? FROM models JOIN makes ON makes.id = models.make_id WHERE makes.name = ? AND models.name = ? ON CONFLICT (model_id, name) DO NOTHING`
+	selectMakesQuery   = `SELECT id, name, (SELECT json_group_array(alias ORDER BY alias) FROM make_aliases WHERE make_id = makes.id) FROM makes`
+	getMakesQuery      = selectMakesQuery + ` ORDER BY name ASC`
+	// getMakeQuery matches a make by name or alias, preferring the name.
+	getMakeQuery   = selectMakesQuery + ` WHERE name = ?1 OR id IN (SELECT make_id FROM make_aliases WHERE alias = ?1) ORDER BY name = ?1 DESC LIMIT 1`
+	getModelsQuery = `SELECT id, name, (SELECT json_group_array(trims.name ORDER BY trims.name) FROM trims WHERE model_id = models.id) FROM models WHERE make_id = ? ORDER BY name ASC`
+)
+
+type SQLiteCatalogRepository struct {
+	db DB
+}
+
+func NewSQLiteCatalogRepository(db DB) *SQLiteCatalogRepository {
+	return &SQLiteCatalogRepository{db: db}
+}
+
+// Seed adds the makes, aliases, models and trims of makes that the catalog
+// does not have yet. Existing entries are kept, so seeding is repeatable.
+func (r *SQLiteCatalogRepository) Seed(ctx context.Context, makes []models.CatalogMake) error {
+	return withinTx(ctx, r.db, func(db DB) error {
+		for _, catalogMake := range makes {
+			if _, err := db.ExecContext(ctx, seedMakeQuery, catalogMake.Name); err != nil {
+				return err
+			}
+			for _, alias := range catalogMake.Aliases {
+				if _, err := db.ExecContext(ctx, seedMakeAliasQuery, alias, catalogMake.Name); err != nil {
+					return err
+				}
+			}
+			for _, model := range catalogMake.Models {
+				if _, err := db.ExecContext(ctx, seedModelQuery, model.Name, catalogMake.Name); err != nil {
+					return err
+				}
+				for _, trim := range model.Trims {
+					if _, err := db.ExecContext(ctx, seedTrimQuery, trim, catalogMake.Name, model.Name); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteCatalogRepository) GetMakes(ctx context.Context) ([]*models.CatalogMake, error) {
+	rows, err := r.db.QueryContext(ctx, getMakesQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	makes := make([]*models.CatalogMake, 0)
+	for rows.Next() {
+		catalogMake, err := scanCatalogMake(rows)
+		if err != nil {
+			return nil, err
+		}
+		makes = append(makes, catalogMake)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return makes, nil
+}
+
+// GetMake returns the make called name, or with name as an alias, ignoring
+// case.
+func (r *SQLiteCatalogRepository) GetMake(ctx context.Context, name string) (*models.CatalogMake, error) {
+	return scanCatalogMake(r.db.QueryRowContext(ctx, getMakeQuery, name))
+}
+
+func (r *SQLiteCatalogRepository) GetModels(ctx context.Context, makeID int64) ([]*models.CatalogModel, error) {
+	rows, err := r.db.QueryContext(ctx, getModelsQuery, makeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	catalogModels := make([]*models.CatalogModel, 0)
+	for rows.Next() {
+		model := &models.CatalogModel{}
+		if err := rows.Scan(&model.ID, &model.Name, jsonColumn{&model.Trims}); err != nil {
+			return nil, err
+		}
+		catalogModels = append(catalogModels, model)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return catalogModels, nil
+}
+
+func scanCatalogMake(row Row) (*models.CatalogMake, error) {
+	catalogMake := &models.CatalogMake{}
+	if err := row.Scan(&catalogMake.ID, &catalogMake.Name, jsonColumn{&catalogMake.Aliases}); err != nil {
+		return nil, err
+	}
+
+	return catalogMake, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"carsapi/internal/models"
+)
+
+const (
+	reserveIdempotencyKeyQuery       = `INSERT INTO idempotency_keys (key, request_hash, reserved_at) VALUES (?, ?, CURRENT_TIMESTAMP) ON CONFLICT (key) DO NOTHING`
+	reclaimIdempotencyKeyQuery       = `UPDATE idempotency_keys SET reserved_at = CURRENT_TIMESTAMP WHERE key = ? AND request_hash = ? AND status_code = 0 AND reserved_at < ?`
+	getIdempotencyKeyQuery           = `SELECT key, request_hash, status_code, response_headers, response_body, created_at, reserved_at FROM idempotency_keys WHERE key = ?`
+	completeIdempotencyKeyQuery      = `UPDATE idempotency_keys SET status_code = ?, response_headers = ?, response_body = ? WHERE key = ?`
+	deleteIdempotencyKeyQuery        = `DELETE FROM idempotency_keys WHERE key = ?`
+	deleteExpiredIdempotencyKeyQuery = `DELETE FROM idempotency_keys WHERE created_at < ?`
+)
+
+type SQLiteIdempotencyRepository struct {
+	db DB
+}
+
+func NewSQLiteIdempotencyRepository(db DB) *SQLiteIdempotencyRepository {
+	return &SQLiteIdempotencyRepository{db: db}
+}
+
+func (r *SQLiteIdempotencyRepository) Reserve(ctx context.Context, key, requestHash string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, reserveIdempotencyKeyQuery, key, requestHash)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *SQLiteIdempotencyRepository) Reclaim(ctx context.Context, key, requestHash string, reservedBefore time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, reclaimIdempotencyKeyQuery, key, requestHash, sqliteTime(reservedBefore))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *SQLiteIdempotencyRepository) GetByKey(ctx context.Context, key string) (*models.IdempotencyRecord, error) {
+	row := r.db.QueryRowContext(ctx, getIdempotencyKeyQuery, key)
+
+	record := &models.IdempotencyRecord{}
+	var headers string
+	if err := row.Scan(&record.Key, &record.RequestHash, &record.StatusCode, &headers, &record.Body, &record.CreatedAt, &record.ReservedAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(headers), &record.Headers); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (r *SQLiteIdempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	headers, err := json.Marshal(record.Headers)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, completeIdempotencyKeyQuery, record.StatusCode, string(headers), record.Body, record.Key)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *SQLiteIdempotencyRepository) Delete(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, deleteIdempotencyKeyQuery, key)
+	return err
+}
+
+func (r *SQLiteIdempotencyRepository) DeleteCreatedBefore(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx, deleteExpiredIdempotencyKeyQuery, sqliteTime(before))
+	return err
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"carsapi/internal/models"
+)
+
+const (
+	createInventoryQuery  = `INSERT INTO inventory (name) VALUES (?)`
+	getInventoryByIDQuery = `SELECT id, name FROM inventory WHERE id = ?`
+	getAllInventoryQuery  = `SELECT id, name FROM inventory ORDER BY id ASC`
+	updateInventoryQuery  = `UPDATE inventory SET name = ? WHERE id = ?`
+	deleteInventoryQuery  = `DELETE FROM inventory WHERE id = ?`
+)
+
+type SQLiteInventoryRepository struct {
+	db DB
+}
+
+func NewSQLiteInventoryRepository(db DB) *SQLiteInventoryRepository {
+	return &SQLiteInventoryRepository{db: db}
+}
+
+func (r *SQLiteInventoryRepository) Create(ctx context.Context, inventory *models.Inventory) error {
+	result, err := r.db.ExecContext(ctx, createInventoryQuery, inventory.Name)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	inventory.ID = id
+	return nil
+}
+
+func (r *SQLiteInventoryRepository) GetByID(ctx context.Context, id int64) (*models.Inventory, error) {
+	row := r.db.QueryRowContext(ctx, getInventoryByIDQuery, id)
+
+	inventory := &models.Inventory{}
+	if err := row.Scan(&inventory.ID, &inventory.Name); err != nil {
+		return nil, err
+	}
+
+	return inventory, nil
+}
+
+func (r *SQLiteInventoryRepository) GetAll(ctx context.Context) ([]*models.Inventory, error) {
+	rows, err := r.db.QueryContext(ctx, getAllInventoryQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inventories := make([]*models.Inventory, 0)
+	for rows.Next() {
+		inventory := &models.Inventory{}
+		if err := rows.Scan(&inventory.ID, &inventory.Name); err != nil {
+			return nil, err
+		}
+		inventories = append(inventories, inventory)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return inventories, nil
+}
+
+func (r *SQLiteInventoryRepository) Update(ctx context.Context, inventory *models.Inventory) error {
+	result, err := r.db.ExecContext(ctx, updateInventoryQuery, inventory.Name, inventory.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *SQLiteInventoryRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, deleteInventoryQuery, id)
+	if err != nil {
+		return classifyConstraint(err, "")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
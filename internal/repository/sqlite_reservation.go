@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"carsapi/internal/models"
+)
+
+const (
+	createReservationQuery      = `INSERT INTO reservations (car_id, customer, reserved_by, expires_at) VALUES (?, ?, ?, ?) RETURNING id, created_at`
+	getReservationsQuery        = `SELECT id, car_id, customer, reserved_by, expires_at, created_at, released_at, release_reason FROM reservations WHERE car_id = ? ORDER BY id DESC`
+	getExpiredReservationsQuery = `SELECT id, car_id, customer, reserved_by, expires_at, created_at, released_at, release_reason FROM reservations WHERE released_at IS NULL AND expires_at <= ? ORDER BY expires_at ASC, id ASC`
+	releaseReservationQuery     = `UPDATE reservations SET released_at = CURRENT_TIMESTAMP, release_reason = ? WHERE car_id = ? AND released_at IS NULL`
+)
+
+// CreateReservation inserts an active reservation. It fails with a unique
+// violation on car_id when the car already has one.
+func (r *SQLiteCarRepository) CreateReservation(ctx context.Context, reservation *models.Reservation) error {
+	row := r.db.QueryRowContext(ctx, createReservationQuery, reservation.CarID, reservation.Customer, reservation.ReservedBy, sqliteTime(reservation.ExpiresAt))
+	if err := row.Scan(&reservation.ID, timestamp{&reservation.CreatedAt}); err != nil {
+		return classifyConstraint(err, "car_id")
+	}
+
+	return nil
+}
+
+// GetReservations returns every reservation made for a car, newest first.
+func (r *SQLiteCarRepository) GetReservations(ctx context.Context, carID int64) ([]*models.Reservation, error) {
+	return r.queryReservations(ctx, getReservationsQuery, carID)
+}
+
+// GetExpiredReservations returns the active reservations that expired at or
+// before now, soonest first.
+func (r *SQLiteCarRepository) GetExpiredReservations(ctx context.Context, now time.Time) ([]*models.Reservation, error) {
+	return r.queryReservations(ctx, getExpiredReservationsQuery, sqliteTime(now))
+}
+
+// ReleaseReservation ends the active reservation of a car, if it has one.
+func (r *SQLiteCarRepository) ReleaseReservation(ctx context.Context, carID int64, reason string) error {
+	_, err := r.db.ExecContext(ctx, releaseReservationQuery, reason, carID)
+	return err
+}
+
+func (r *SQLiteCarRepository) queryReservations(ctx context.Context, query string, args ...any) ([]*models.Reservation, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := make([]*models.Reservation, 0)
+	for rows.Next() {
+		reservation := &models.Reservation{}
+		if err := rows.Scan(&reservation.ID, &reservation.CarID, &reservation.Customer, &reservation.ReservedBy, timestamp{&reservation.ExpiresAt}, timestamp{&reservation.CreatedAt}, nullTimestamp{&reservation.ReleasedAt}, &reservation.ReleaseReason); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
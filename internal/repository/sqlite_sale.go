@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"carsapi/internal/models"
+)
+
+const (
+	createSaleQuery  = `INSERT INTO sales (car_id, buyer_name, sale_price, currency, sold_at, salesperson, reservation_id) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id, created_at`
+	selectSalesQuery = `SELECT id, car_id, buyer_name, sale_price, currency, sold_at, salesperson, reservation_id, created_at FROM sales`
+	getSaleQuery     = selectSalesQuery + ` WHERE id = ?`
+	getCarSaleQuery  = selectSalesQuery + ` WHERE car_id = ?`
+)
+
+type SQLiteSaleRepository struct {
+	db DB
+}
+
+func NewSQLiteSaleRepository(db DB) *SQLiteSaleRepository {
+	return &SQLiteSaleRepository{db: db}
+}
+
+// Create inserts a sale. It fails with a unique violation on car_id when the
+// car has already been sold.
+func (r *SQLiteSaleRepository) Create(ctx context.Context, sale *models.Sale) error {
+	var reservationID any
+	if sale.ReservationID != nil {
+		reservationID = *sale.ReservationID
+	}
+
+	row := r.db.QueryRowContext(ctx, createSaleQuery, sale.CarID, sale.BuyerName, sale.SalePrice, sale.Currency, sqliteTime(sale.SoldAt), sale.Salesperson, reservationID)
+	if err := row.Scan(&sale.ID, timestamp{&sale.CreatedAt}); err != nil {
+		return classifyConstraint(err, "car_id")
+	}
+
+	return nil
+}
+
+func (r *SQLiteSaleRepository) GetByID(ctx context.Context, id int64) (*models.Sale, error) {
+	return scanSale(r.db.QueryRowContext(ctx, getSaleQuery, id))
+}
+
+func (r *SQLiteSaleRepository) GetByCarID(ctx context.Context, carID int64) (*models.Sale, error) {
+	return scanSale(r.db.QueryRowContext(ctx, getCarSaleQuery, carID))
+}
+
+// GetAll returns the sales matching filter, most recent first.
+func (r *SQLiteSaleRepository) GetAll(ctx context.Context, filter models.SaleFilter) ([]*models.Sale, error) {
+	query, args := buildSalesQuery(filter)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := make([]*models.Sale, 0)
+	for rows.Next() {
+		sale, err := scanSale(rows)
+		if err != nil {
+			return nil, err
+		}
+		sales = append(sales, sale)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sales, nil
+}
+
+func (r *SQLiteSaleRepository) WithinTx(ctx context.Context, fn func(SaleRepository, CarRepository) error) error {
+	return withinTx(ctx, r.db, func(tx DB) error {
+		return fn(NewSQLiteSaleRepository(tx), NewSQLiteCarRepository(tx))
+	})
+}
+
+func buildSalesQuery(filter models.SaleFilter) (string, []any) {
+	conditions := make([]string, 0, 3)
+	args := make([]any, 0, 3)
+
+	if filter.Salesperson != "" {
+		conditions = append(conditions, "salesperson = ?")
+		args = append(args, filter.Salesperson)
+	}
+	if !filter.SoldFrom.IsZero() {
+		conditions = append(conditions, "sold_at >= ?")
+		args = append(args, sqliteTime(filter.SoldFrom))
+	}
+	if !filter.SoldTo.IsZero() {
+		conditions = append(conditions, "sold_at < ?")
+		args = append(args, sqliteTime(filter.SoldTo))
+	}
+
+	var b strings.Builder
+	b.WriteString(selectSalesQuery)
+	if len(conditions) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(conditions, " AND "))
+	}
+	b.WriteString(" ORDER BY sold_at DESC, id DESC")
+
+	return b.String(), args
+}
+
+func scanSale(row Row) (*models.Sale, error) {
+	sale := &models.Sale{}
+	var reservationID sql.NullInt64
+	if err := row.Scan(&sale.ID, &sale.CarID, &sale.BuyerName, &sale.SalePrice, &sale.Currency, timestamp{&sale.SoldAt}, &sale.Salesperson, &reservationID, timestamp{&sale.CreatedAt}); err != nil {
+		return nil, err
+	}
+	if reservationID.Valid {
+		sale.ReservationID = &reservationID.Int64
+	}
+
+	return sale, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+
+	"carsapi/internal/models"
+)
+
+const (
+	createServiceRecordQuery  = `INSERT INTO service_records (car_id, kind, description, performed_at, mileage, cost, currency, vendor, recorded_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, created_at`
+	selectServiceRecordsQuery = `SELECT id, car_id, kind, description, performed_at, mileage, cost, currency, vendor, recorded_by, created_at FROM service_records`
+	getServiceRecordQuery     = selectServiceRecordsQuery + ` WHERE id = ?`
+	getServiceRecordsQuery    = selectServiceRecordsQuery + ` WHERE car_id = ? ORDER BY performed_at ASC, id ASC`
+	deleteServiceRecordQuery  = `DELETE FROM service_records WHERE id = ?`
+	// updateServiceSummaryQuery recomputes cars.service_summary from the
+	// car's records; HAVING leaves it NULL once the last one is deleted.
+	updateServiceSummaryQuery = `UPDATE cars SET service_summary = (
+SELECT json_object('records', COUNT(*), 'reconditioning_cost', SUM(cost), 'currency', MAX(currency), 'last_service_at', strftime('%Y-%m-%dT%H:%M:%SZ', MAX(performed_at)))
+FROM service_records WHERE car_id = cars.id HAVING COUNT(*) > 0
+), version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`
+)
+
+type SQLiteServiceRecordRepository struct {
+	db DB
+}
+
+func NewSQLiteServiceRecordRepository(db DB) *SQLiteServiceRecordRepository {
+	return &SQLiteServiceRecordRepository{db: db}
+}
+
+func (r *SQLiteServiceRecordRepository) Create(ctx context.Context, record *models.ServiceRecord) error {
+	row := r.db.QueryRowContext(ctx, createServiceRecordQuery, record.CarID, record.Kind, record.Description, sqliteTime(record.PerformedAt), record.Mileage, record.Cost, record.Currency, record.Vendor, record.RecordedBy)
+	if err := row.Scan(&record.ID, timestamp{&record.CreatedAt}); err != nil {
+		return classifyConstraint(err, "car_id")
+	}
+
+	return nil
+}
+
+func (r *SQLiteServiceRecordRepository) GetByID(ctx context.Context, id int64) (*models.ServiceRecord, error) {
+	return scanServiceRecord(r.db.QueryRowContext(ctx, getServiceRecordQuery, id))
+}
+
+// GetByCarID returns the service records of a car in the order the work was
+// performed.
+func (r *SQLiteServiceRecordRepository) GetByCarID(ctx context.Context, carID int64) ([]*models.ServiceRecord, error) {
+	rows, err := r.db.QueryContext(ctx, getServiceRecordsQuery, carID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*models.ServiceRecord, 0)
+	for rows.Next() {
+		record, err := scanServiceRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (r *SQLiteServiceRecordRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, deleteServiceRecordQuery, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+// UpdateSummary refreshes the service summary of a car after its records
+// changed, bumping its version.
+func (r *SQLiteServiceRecordRepository) UpdateSummary(ctx context.Context, carID int64) error {
+	result, err := r.db.ExecContext(ctx, updateServiceSummaryQuery, carID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+func (r *SQLiteServiceRecordRepository) WithinTx(ctx context.Context, fn func(ServiceRecordRepository, CarRepository) error) error {
+	return withinTx(ctx, r.db, func(tx DB) error {
+		return fn(NewSQLiteServiceRecordRepository(tx), NewSQLiteCarRepository(tx))
+	})
+}
+
+func scanServiceRecord(row Row) (*models.ServiceRecord, error) {
+	record := &models.ServiceRecord{}
+	if err := row.Scan(&record.ID, &record.CarID, &record.Kind, &record.Description, timestamp{&record.PerformedAt}, &record.Mileage, &record.Cost, &record.Currency, &record.Vendor, &record.RecordedBy, timestamp{&record.CreatedAt}); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+)
+
+// sqliteTimeLayout matches the text SQLite's CURRENT_TIMESTAMP produces, so
+// bound times compare correctly against DEFAULT CURRENT_TIMESTAMP columns.
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// timestamp scans into t a time the driver returns either as a time.Time,
+// for DATETIME columns, or as text, for values computed by a query. NULL
+// leaves t zero.
+type timestamp struct {
+	t *time.Time
+}
+
+func (ts timestamp) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*ts.t = time.Time{}
+	case time.Time:
+		*ts.t = value
+	case string:
+		parsed, err := time.Parse(sqliteTimeLayout, value)
+		if err != nil {
+			return err
+		}
+		*ts.t = parsed
+	default:
+		return fmt.Errorf("cannot scan %T into a timestamp", src)
+	}
+
+	return nil
+}
+
+// nullTimestamp scans a nullable time into *t, leaving it nil for NULL.
+type nullTimestamp struct {
+	t **time.Time
+}
+
+func (ts nullTimestamp) Scan(src any) error {
+	var t time.Time
+	if err := (timestamp{&t}).Scan(src); err != nil {
+		return err
+	}
+
+	*ts.t = nil
+	if src != nil {
+		*ts.t = &t
+	}
+	return nil
+}
+
+// nullableTime binds t as NULL when it is nil.
+func nullableTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return sqliteTime(*t)
+}
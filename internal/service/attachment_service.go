@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+const (
+	DefaultMaxAttachmentSize = 10 << 20
+
+	maxFilenameLength = 255
+)
+
+// attachmentTypes lists the content types accepted for upload. They are
+// sniffed from the file rather than taken from the client.
+var attachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"application/pdf": true,
+}
+
+type AttachmentService interface {
+	Upload(ctx context.Context, carID int64, filename string, r io.Reader) (*models.Attachment, error)
+	List(ctx context.Context, carID int64) ([]*models.Attachment, error)
+	Open(ctx context.Context, carID, id int64, thumbnail bool) (*models.Attachment, io.ReadSeekCloser, error)
+	Delete(ctx context.Context, carID, id int64) error
+}
+
+type attachmentService struct {
+	repo    repository.AttachmentRepository
+	cars    repository.CarRepository
+	files   repository.FileStore
+	maxSize int64
+	logf    func(format string, args ...any)
+}
+
+// NewAttachmentService stores attachment contents in files and their
+// metadata in repo. Uploads larger than maxSize bytes are rejected.
+func NewAttachmentService(repo repository.AttachmentRepository, cars repository.CarRepository, files repository.FileStore, maxSize int64) AttachmentService {
+	return &attachmentService{repo: repo, cars: cars, files: files, maxSize: maxSize, logf: log.Printf}
+}
+
+func (s *attachmentService) Upload(ctx context.Context, carID int64, filename string, r io.Reader) (*models.Attachment, error) {
+	if err := s.checkCar(ctx, carID); err != nil {
+		return nil, err
+	}
+
+	// Reading one byte past the limit tells a file at the limit apart from a
+	// larger one without buffering either.
+	content := bufio.NewReader(io.LimitReader(r, s.maxSize+1))
+	head, err := content.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(head) == 0 {
+		return nil, ValidationErrors{"file": {"must not be empty"}}
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !attachmentTypes[contentType] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+	}
+
+	key, err := attachmentKey(carID)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	size, err := s.files.Put(key, io.TeeReader(content, hash))
+	if err != nil {
+		return nil, err
+	}
+	if size > s.maxSize {
+		s.removeFiles(key, "")
+		return nil, fmt.Errorf("%w: the limit is %d bytes", ErrAttachmentTooLarge, s.maxSize)
+	}
+
+	attachment := &models.Attachment{
+		CarID:       carID,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+	}
+	if strings.HasPrefix(contentType, "image/") {
+		if attachment.ThumbnailKey, err = s.storeThumbnail(key, contentType); err != nil {
+			s.removeFiles(key, "")
+			return nil, err
+		}
+		attachment.HasThumbnail = attachment.ThumbnailKey != ""
+	}
+
+	if err := s.repo.Create(ctx, attachment); err != nil {
+		s.removeFiles(attachment.StorageKey, attachment.ThumbnailKey)
+		if errors.Is(err, repository.ErrForeignKeyViolation) {
+			return nil, ErrCarNotFound
+		}
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (s *attachmentService) List(ctx context.Context, carID int64) ([]*models.Attachment, error) {
+	if err := s.checkCar(ctx, carID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByCarID(ctx, carID)
+}
+
+// Open returns an attachment of a car with its contents, or those of its
+// thumbnail. The caller closes the returned file.
+func (s *attachmentService) Open(ctx context.Context, carID, id int64, thumbnail bool) (*models.Attachment, io.ReadSeekCloser, error) {
+	attachment, err := s.get(ctx, carID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := attachment.StorageKey
+	if thumbnail {
+		if !attachment.HasThumbnail {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		key = attachment.ThumbnailKey
+	}
+
+	file, err := s.files.Open(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attachment, file, nil
+}
+
+func (s *attachmentService) Delete(ctx context.Context, carID, id int64) error {
+	attachment, err := s.get(ctx, carID, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Delete(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAttachmentNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	s.removeFiles(attachment.StorageKey, attachment.ThumbnailKey)
+	return nil
+}
+
+func (s *attachmentService) get(ctx context.Context, carID, id int64) (*models.Attachment, error) {
+	if err := s.checkCar(ctx, carID); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: attachment id must be positive", ErrValidation)
+	}
+
+	attachment, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && attachment.CarID != carID) {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (s *attachmentService) checkCar(ctx context.Context, carID int64) error {
+	if carID <= 0 {
+		return fmt.Errorf("%w: id must be positive", ErrValidation)
+	}
+
+	_, err := s.cars.GetByID(ctx, carID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCarNotFound
+	}
+	return err
+}
+
+// storeThumbnail writes a thumbnail of the image stored at key and returns
+// its key, or "" when the image is too large to thumbnail.
+func (s *attachmentService) storeThumbnail(key, contentType string) (string, error) {
+	file, err := s.files.Open(key)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	thumbnail, err := makeThumbnail(file, contentType)
+	if errors.Is(err, errImageTooLarge) {
+		return "", nil
+	}
+	if err != nil {
+		return "", ValidationErrors{"file": {"is not a valid " + contentType + " image"}}
+	}
+
+	thumbnailKey := key + ".thumb"
+	if _, err := s.files.Put(thumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+		return "", err
+	}
+
+	return thumbnailKey, nil
+}
+
+// removeFiles deletes stored files that are no longer referenced. Failures
+// only leave orphaned files behind, so they are logged rather than returned.
+func (s *attachmentService) removeFiles(keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.files.Delete(key); err != nil {
+			s.logf("remove attachment file %s: %v", key, err)
+		}
+	}
+}
+
+// attachmentDir is the file store prefix holding the attachments of a car.
+func attachmentDir(carID int64) string {
+	return strconv.FormatInt(carID, 10)
+}
+
+func attachmentKey(carID int64) (string, error) {
+	var random [16]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return "", err
+	}
+
+	return attachmentDir(carID) + "/" + hex.EncodeToString(random[:]), nil
+}
+
+// cleanFilename keeps the base name of a client-supplied filename, as sent
+// by browsers that include a path, limited to maxFilenameLength bytes.
+func cleanFilename(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	for len(name) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
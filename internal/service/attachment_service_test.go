@@ -0,0 +1,232 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+type fakeAttachmentRepository struct {
+	attachments map[int64]*models.Attachment
+	nextID      int64
+}
+
+func newFakeAttachmentRepository() *fakeAttachmentRepository {
+	return &fakeAttachmentRepository{attachments: map[int64]*models.Attachment{}, nextID: 1}
+}
+
+func (f *fakeAttachmentRepository) Create(_ context.Context, attachment *models.Attachment) error {
+	attachment.ID = f.nextID
+	attachment.CreatedAt = time.Now()
+	f.nextID++
+	copyAttachment := *attachment
+	f.attachments[attachment.ID] = &copyAttachment
+	return nil
+}
+
+func (f *fakeAttachmentRepository) GetByID(_ context.Context, id int64) (*models.Attachment, error) {
+	attachment, ok := f.attachments[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copyAttachment := *attachment
+	return &copyAttachment, nil
+}
+
+func (f *fakeAttachmentRepository) GetByCarID(_ context.Context, carID int64) ([]*models.Attachment, error) {
+	out := make([]*models.Attachment, 0)
+	for id := int64(1); id < f.nextID; id++ {
+		if attachment, ok := f.attachments[id]; ok && attachment.CarID == carID {
+			out = append(out, attachment)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAttachmentRepository) Delete(_ context.Context, id int64) error {
+	if _, ok := f.attachments[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(f.attachments, id)
+	return nil
+}
+
+func testImage(t *testing.T, contentType string, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func newTestAttachmentService(t *testing.T, maxSize int64) (AttachmentService, CarService, string) {
+	t.Helper()
+	dir := t.TempDir()
+	files, err := repository.NewLocalFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewLocalFileStore() error = %v", err)
+	}
+
+	cars := newFakeCarRepository()
+	carService := NewCarService(cars, newFakeInventoryRepository(), WithAttachmentFiles(files))
+	if _, err := carService.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Saab", Model: "900", Year: 1990, Color: "Black", VIN: testVIN(150)}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	return NewAttachmentService(newFakeAttachmentRepository(), cars, files, maxSize), carService, dir
+}
+
+func TestAttachmentServiceUpload(t *testing.T) {
+	svc, _, dir := newTestAttachmentService(t, 1<<20)
+	ctx := context.Background()
+
+	for _, contentType := range []string{"image/png", "image/jpeg"} {
+		data := testImage(t, contentType, 640, 320)
+		attachment, err := svc.Upload(ctx, 1, `C:\photos\side`, bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Upload(%s) error = %v", contentType, err)
+		}
+		if attachment.ContentType != contentType || attachment.Size != int64(len(data)) || len(attachment.SHA256) != 64 || attachment.Filename != "side" || !attachment.HasThumbnail {
+			t.Fatalf("Upload(%s) = %+v", contentType, attachment)
+		}
+
+		_, thumbnail, err := svc.Open(ctx, 1, attachment.ID, true)
+		if err != nil {
+			t.Fatalf("Open(thumbnail) error = %v", err)
+		}
+		config, format, err := image.DecodeConfig(thumbnail)
+		thumbnail.Close()
+		if err != nil || "image/"+format != contentType || config.Width != thumbnailSize || config.Height != thumbnailSize/2 {
+			t.Fatalf("thumbnail = %s %dx%d, %v; want %s %dx%d", format, config.Width, config.Height, err, contentType, thumbnailSize, thumbnailSize/2)
+		}
+	}
+
+	pdf, err := svc.Upload(ctx, 1, "title.pdf", strings.NewReader("%PDF-1.4\n"))
+	if err != nil || pdf.ContentType != "application/pdf" || pdf.HasThumbnail {
+		t.Fatalf("Upload(pdf) = %+v, %v; want a pdf without thumbnail", pdf, err)
+	}
+	if list, err := svc.List(ctx, 1); err != nil || len(list) != 3 {
+		t.Fatalf("List() = %d attachments, %v; want 3", len(list), err)
+	}
+
+	tests := []struct {
+		name    string
+		carID   int64
+		content []byte
+		wantErr error
+	}{
+		{name: "missing car", carID: 9, content: []byte("%PDF-1.4\n"), wantErr: ErrCarNotFound},
+		{name: "empty", carID: 1, wantErr: ErrValidation},
+		{name: "text", carID: 1, content: []byte("hello"), wantErr: ErrUnsupportedMediaType},
+		{name: "corrupt png", carID: 1, content: append([]byte("\x89PNG\r\n\x1a\n"), "garbage"...), wantErr: ErrValidation},
+		{name: "too large", carID: 1, content: append([]byte("%PDF-1.4\n"), make([]byte, 1<<20)...), wantErr: ErrAttachmentTooLarge},
+	}
+	for _, tt := range tests {
+		if _, err := svc.Upload(ctx, tt.carID, "upload", bytes.NewReader(tt.content)); !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: Upload() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// Rejected uploads leave nothing behind: two images with thumbnails and
+	// the pdf.
+	files, _ := filepath.Glob(filepath.Join(dir, "1", "*"))
+	if len(files) != 5 {
+		t.Fatalf("stored files = %v, want 5", files)
+	}
+}
+
+func TestAttachmentServiceOpenAndDelete(t *testing.T) {
+	svc, cars, dir := newTestAttachmentService(t, DefaultMaxAttachmentSize)
+	ctx := context.Background()
+
+	attachment, err := svc.Upload(ctx, 1, "front.png", bytes.NewReader(testImage(t, "image/png", 32, 32)))
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	pdf, _ := svc.Upload(ctx, 1, "title.pdf", strings.NewReader("%PDF-1.4\n"))
+
+	_, file, err := svc.Open(ctx, 1, attachment.ID, false)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if int64(len(data)) != attachment.Size {
+		t.Fatalf("Open() read %d bytes, want %d", len(data), attachment.Size)
+	}
+
+	if _, err := cars.Create(ctx, &models.Car{InventoryID: 1, Make: "Saab", Model: "9-3", Year: 2005, Color: "Blue", VIN: testVIN(151)}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, _, err := svc.Open(ctx, 2, attachment.ID, false); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("Open() through another car error = %v, want ErrAttachmentNotFound", err)
+	}
+	if _, _, err := svc.Open(ctx, 1, pdf.ID, true); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("Open() of a missing thumbnail error = %v, want ErrAttachmentNotFound", err)
+	}
+
+	if err := svc.Delete(ctx, 1, attachment.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := svc.Delete(ctx, 1, attachment.ID); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("second Delete() error = %v, want ErrAttachmentNotFound", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "1", "*")); len(files) != 1 {
+		t.Fatalf("stored files after Delete() = %v, want only the pdf", files)
+	}
+
+	// Purging the car removes its remaining files.
+	if err := cars.Delete(ctx, 1, 0); err != nil {
+		t.Fatalf("Delete() car error = %v", err)
+	}
+	if err := cars.Purge(ctx, 1); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "1")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("attachment dir after Purge() error = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestCleanFilename(t *testing.T) {
+	tests := map[string]string{
+		"photo.jpg":               "photo.jpg",
+		"  ../../etc/passwd ":     "passwd",
+		`C:\Users\dana\title.pdf`: "title.pdf",
+		"":                        "attachment",
+		"/":                       "attachment",
+		strings.Repeat("é", 200):  strings.Repeat("é", 127),
+	}
+	for in, want := range tests {
+		if got := cleanFilename(in); got != want {
+			t.Fatalf("cleanFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
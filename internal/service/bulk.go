@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+const maxBulkOperations = 100
+
+type BulkMode string
+
+const (
+	// BulkAtomic applies every operation or none of them.
+	BulkAtomic BulkMode = "atomic"
+	// BulkBestEffort applies each operation independently and keeps the ones
+	// that succeed.
+	BulkBestEffort BulkMode = "best_effort"
+)
+
+// BulkResult is the outcome of one bulk operation. Car is the created or
+// updated car; Err is nil when the operation was applied.
+type BulkResult struct {
+	Op  string
+	Car *models.Car
+	Err error
+}
+
+// errBulkFailed aborts an atomic batch after the failing operation has
+// recorded its own error.
+var errBulkFailed = errors.New("bulk operation failed")
+
+func (s *carService) Bulk(ctx context.Context, mode BulkMode, ops []models.BulkOperation) ([]BulkResult, error) {
+	if mode == "" {
+		mode = BulkAtomic
+	}
+
+	problems := ValidationErrors{}
+	if mode != BulkAtomic && mode != BulkBestEffort {
+		problems.Add("mode", "must be atomic or best_effort")
+	}
+	if len(ops) == 0 || len(ops) > maxBulkOperations {
+		problems.Add("operations", fmt.Sprintf("must contain between 1 and %d operations", maxBulkOperations))
+	}
+	if err := problems.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]BulkResult, len(ops))
+	for i, op := range ops {
+		results[i] = BulkResult{Op: op.Op}
+	}
+
+	err := s.repo.WithinTx(ctx, func(repo repository.CarRepository) error {
+		tx := s.withRepo(repo)
+		for i, op := range ops {
+			if mode == BulkAtomic {
+				results[i].Car, results[i].Err = tx.applyBulk(ctx, op)
+				if results[i].Err != nil {
+					return errBulkFailed
+				}
+				continue
+			}
+
+			// Each best-effort operation gets its own savepoint so a failure
+			// midway through it leaves nothing behind.
+			_ = repo.WithinTx(ctx, func(item repository.CarRepository) error {
+				results[i].Car, results[i].Err = s.withRepo(item).applyBulk(ctx, op)
+				return results[i].Err
+			})
+		}
+		return nil
+	})
+	if errors.Is(err, errBulkFailed) {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Car = nil
+				results[i].Err = ErrBulkAborted
+			}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// withRepo returns a copy of s that reads and writes through repo.
+func (s *carService) withRepo(repo repository.CarRepository) *carService {
+	copyService := *s
+	copyService.repo = repo
+	return &copyService
+}
+
+func (s *carService) applyBulk(ctx context.Context, op models.BulkOperation) (*models.Car, error) {
+	switch op.Op {
+	case "create":
+		if op.Car == nil {
+			return nil, ValidationErrors{"car": {"is required"}}
+		}
+		car := *op.Car
+		return s.Create(ctx, &car)
+	case "update":
+		if op.Car == nil {
+			return nil, ValidationErrors{"car": {"is required"}}
+		}
+		car := *op.Car
+		car.ID = op.ID
+		car.Version = op.Version
+		return s.Update(ctx, &car)
+	case "delete":
+		return nil, s.Delete(ctx, op.ID, op.Version)
+	default:
+		return nil, ValidationErrors{"op": {"must be create, update or delete"}}
+	}
+}
@@ -1,11 +1,17 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
 	"carsapi/internal/models"
 	"carsapi/internal/repository"
@@ -14,29 +20,142 @@ import (
 type CarService interface {
 	Create(ctx context.Context, car *models.Car) (*models.Car, error)
 	GetByID(ctx context.Context, id int64) (*models.Car, error)
-	GetAll(ctx context.Context) ([]*models.Car, error)
+	GetByIDAsOf(ctx context.Context, id int64, asOf time.Time) (*models.Car, error)
+	GetByVIN(ctx context.Context, vin string) (*models.Car, error)
+	GetAll(ctx context.Context, filter models.CarFilter) (*models.CarPage, error)
+	Search(ctx context.Context, text string, limit int) ([]*models.Car, error)
 	Update(ctx context.Context, car *models.Car) (*models.Car, error)
-	Delete(ctx context.Context, id int64) error
+	UpsertByVIN(ctx context.Context, vin string, car *models.Car) (*models.Car, bool, error)
+	Patch(ctx context.Context, id int64, kind PatchKind, patch []byte, version int64) (*models.Car, error)
+	Transfer(ctx context.Context, id, inventoryID, version int64) (*models.Car, error)
+	Delete(ctx context.Context, id, version int64) error
+	ListDeleted(ctx context.Context) ([]*models.Car, error)
+	Restore(ctx context.Context, id int64) (*models.Car, error)
+	Purge(ctx context.Context, id int64) error
+	Transition(ctx context.Context, id int64, name string, version int64) (*models.Car, error)
+	Bulk(ctx context.Context, mode BulkMode, ops []models.BulkOperation) ([]BulkResult, error)
+	History(ctx context.Context, id int64) ([]*models.CarHistoryEntry, error)
+	Prices(ctx context.Context, id int64) ([]*models.PriceChange, error)
+	Reserve(ctx context.Context, id int64, customer string, hours int, version int64) (*models.Reservation, error)
+	ListReservations(ctx context.Context, id int64) ([]*models.Reservation, error)
+	ExpireReservations(ctx context.Context, now time.Time) (int, error)
 }
 
-type carService struct {
+const (
+	defaultCarPageSize = 50
+	maxCarPageSize     = 200
+)
+
+var carSortFields = map[string]bool{
+	"id":               true,
+	"inventory_id":     true,
+	"make":             true,
+	"model":            true,
+	"year":             true,
+	"color":            true,
+	"vin":              true,
+	"price_changed_at": true,
+}
+
+// VINCheckMode controls what Create does when the year or make of a car
+// disagree with its decoded VIN.
+type VINCheckMode int
+
+const (
+	VINCheckOff VINCheckMode = iota
+	VINCheckWarn
+	VINCheckReject
+)
+
+func ParseVINCheckMode(mode string) (VINCheckMode, error) {
+	switch mode {
+	case "off":
+		return VINCheckOff, nil
+	case "warn":
+		return VINCheckWarn, nil
+	case "reject":
+		return VINCheckReject, nil
+	default:
+		return VINCheckOff, fmt.Errorf("unknown vin check mode %q", mode)
+	}
+}
+
+type CarServiceOption func(*carService)
+
+func WithVINCheck(mode VINCheckMode) CarServiceOption {
+	return func(s *carService) {
+		s.vinCheck = mode
+	}
+}
+
+// WithStrictCatalog makes validation require the make, model and any "trim"
+// attribute of a car to be in catalog, rewriting them to its spelling.
+func WithStrictCatalog(catalog repository.CatalogRepository) CarServiceOption {
+	return func(s *carService) {
+		s.catalog = catalog
+	}
+}
+
+// WithAttachmentFiles lets Purge remove the attachment files of a purged car
+// from files.
+func WithAttachmentFiles(files repository.FileStore) CarServiceOption {
+	return func(s *carService) {
+		s.files = files
+	}
+}
+
+// carWriter changes cars through repo and records each change in the car's
+// history. carService embeds one; services whose own writes change a car,
+// such as recording a sale, build one on the repository of their
+// transaction.
+type carWriter struct {
 	repo repository.CarRepository
 }
 
-func NewCarService(repo repository.CarRepository) CarService {
-	return &carService{repo: repo}
+type carService struct {
+	carWriter
+	inventories repository.InventoryRepository
+	files       repository.FileStore
+	catalog     repository.CatalogRepository
+	vinCheck    VINCheckMode
+	logf        func(format string, args ...any)
+}
+
+func NewCarService(repo repository.CarRepository, inventories repository.InventoryRepository, opts ...CarServiceOption) CarService {
+	s := &carService{carWriter: carWriter{repo: repo}, inventories: inventories, logf: log.Printf}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *carService) Create(ctx context.Context, car *models.Car) (*models.Car, error) {
-	if err := validateCar(car); err != nil {
+	if err := s.validateCar(ctx, car, nil); err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.Create(ctx, car); err != nil {
+	if err := checkStatus(nil, car); err != nil {
+		return nil, err
+	}
+	if err := s.checkVIN(car, nil); err != nil {
 		return nil, err
 	}
 
-	created, err := s.repo.GetByID(ctx, car.ID)
+	var created *models.Car
+	err := s.inTx(ctx, func(tx *carService) error {
+		if err := tx.repo.Create(ctx, car); err != nil {
+			return constraintError(err)
+		}
+
+		var err error
+		if created, err = tx.repo.GetByID(ctx, car.ID); err != nil {
+			return err
+		}
+		if err := tx.recordPrice(ctx, nil, created); err != nil {
+			return err
+		}
+		return tx.record(ctx, models.CarCreated, car.ID, nil, created)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -45,11 +164,50 @@ func (s *carService) Create(ctx context.Context, car *models.Car) (*models.Car,
 }
 
 func (s *carService) GetByID(ctx context.Context, id int64) (*models.Car, error) {
+	return s.getCar(ctx, id)
+}
+
+func (w carWriter) getCar(ctx context.Context, id int64) (*models.Car, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: id must be positive", ErrValidation)
+	}
+
+	car, err := w.repo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCarNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return car, nil
+}
+
+// GetByIDAsOf returns the car as it was at asOf, as reconstructed from its
+// history.
+func (s *carService) GetByIDAsOf(ctx context.Context, id int64, asOf time.Time) (*models.Car, error) {
 	if id <= 0 {
 		return nil, fmt.Errorf("%w: id must be positive", ErrValidation)
 	}
 
-	car, err := s.repo.GetByID(ctx, id)
+	car, err := s.repo.GetByIDAsOf(ctx, id, asOf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCarNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return car, nil
+}
+
+func (s *carService) GetByVIN(ctx context.Context, vin string) (*models.Car, error) {
+	vin = normalizeVIN(vin)
+	if vin == "" {
+		return nil, ValidationErrors{"vin": {"is required"}}
+	}
+
+	car, err := s.repo.GetByVIN(ctx, vin)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrCarNotFound
 	}
@@ -60,8 +218,49 @@ func (s *carService) GetByID(ctx context.Context, id int64) (*models.Car, error)
 	return car, nil
 }
 
-func (s *carService) GetAll(ctx context.Context) ([]*models.Car, error) {
-	return s.repo.GetAll(ctx)
+func (s *carService) GetAll(ctx context.Context, filter models.CarFilter) (*models.CarPage, error) {
+	return listCars(ctx, s.repo, filter)
+}
+
+// listCars returns the page of cars matching filter along with their total.
+func listCars(ctx context.Context, repo repository.CarRepository, filter models.CarFilter) (*models.CarPage, error) {
+	if err := normalizeCarFilter(&filter); err != nil {
+		return nil, err
+	}
+
+	cars, next, err := repo.GetAll(ctx, filter)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, ValidationErrors{"cursor": {"is invalid"}}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CarPage{Cars: cars, NextCursor: next, Total: total}, nil
+}
+
+func (s *carService) Search(ctx context.Context, text string, limit int) ([]*models.Car, error) {
+	if limit == 0 {
+		limit = defaultCarPageSize
+	}
+
+	problems := ValidationErrors{}
+	if strings.TrimSpace(text) == "" {
+		problems.Add("q", "is required")
+	}
+	if limit < 0 || limit > maxCarPageSize {
+		problems.Add("limit", fmt.Sprintf("must be between 1 and %d", maxCarPageSize))
+	}
+	if err := problems.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Search(ctx, text, limit)
 }
 
 func (s *carService) Update(ctx context.Context, car *models.Car) (*models.Car, error) {
@@ -69,61 +268,477 @@ func (s *carService) Update(ctx context.Context, car *models.Car) (*models.Car,
 		return nil, fmt.Errorf("%w: id must be positive", ErrValidation)
 	}
 
-	if err := validateCar(car); err != nil {
+	// The car is read again, and its version checked, in the transaction;
+	// this read only tells validateCar whether the VIN changes.
+	stored, err := s.GetByID(ctx, car.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateCar(ctx, car, stored); err != nil {
 		return nil, err
 	}
 
-	err := s.repo.Update(ctx, car)
+	var updated *models.Car
+	err = s.inTx(ctx, func(tx *carService) error {
+		current, err := tx.GetByID(ctx, car.ID)
+		if err != nil {
+			return err
+		}
+		if car.Version != 0 && car.Version != current.Version {
+			return ErrPreconditionFailed
+		}
+		if err := checkStatus(current, car); err != nil {
+			return err
+		}
+		if sameCarData(current, car) {
+			updated = current
+			return nil
+		}
+
+		err = tx.repo.Update(ctx, car)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCarNotFound
+		}
+		if errors.Is(err, repository.ErrStaleVersion) {
+			return ErrPreconditionFailed
+		}
+		if err != nil {
+			return constraintError(err)
+		}
+
+		if updated, err = tx.repo.GetByID(ctx, car.ID); err != nil {
+			return err
+		}
+		if err := tx.recordPrice(ctx, current, updated); err != nil {
+			return err
+		}
+		return tx.record(ctx, models.CarUpdated, car.ID, current, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// UpsertByVIN creates the car identified by vin or replaces it, reporting
+// whether it was created. car.Version, when set, must match the existing car.
+func (s *carService) UpsertByVIN(ctx context.Context, vin string, car *models.Car) (*models.Car, bool, error) {
+	if car == nil {
+		return nil, false, fmt.Errorf("%w: car payload is required", ErrValidation)
+	}
+
+	vin = normalizeVIN(vin)
+	if car.VIN != "" && normalizeVIN(car.VIN) != vin {
+		return nil, false, ValidationErrors{"vin": {"must match the vin in the path"}}
+	}
+	car.VIN = vin
+
+	stored, err := s.repo.GetByVIN(ctx, vin)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+	if err := s.validateCar(ctx, car, stored); err != nil {
+		return nil, false, err
+	}
+	if err := s.checkVIN(car, stored); err != nil {
+		return nil, false, err
+	}
+
+	var upserted *models.Car
+	var created bool
+	err = s.inTx(ctx, func(tx *carService) error {
+		current, err := tx.repo.GetByVIN(ctx, vin)
+		if errors.Is(err, sql.ErrNoRows) && car.Version != 0 {
+			return ErrPreconditionFailed
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err := checkStatus(current, car); err != nil {
+			return err
+		}
+		if current != nil && sameCarData(current, car) {
+			if car.Version != 0 && car.Version != current.Version {
+				return ErrPreconditionFailed
+			}
+			upserted = current
+			return nil
+		}
+
+		created, err = tx.repo.UpsertByVIN(ctx, car)
+		if errors.Is(err, repository.ErrStaleVersion) {
+			return ErrPreconditionFailed
+		}
+		if err != nil {
+			return constraintError(err)
+		}
+
+		if upserted, err = tx.repo.GetByID(ctx, car.ID); err != nil {
+			return err
+		}
+		action := models.CarUpdated
+		if created {
+			action = models.CarCreated
+		}
+		if err := tx.recordPrice(ctx, current, upserted); err != nil {
+			return err
+		}
+		return tx.record(ctx, action, car.ID, current, upserted)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return upserted, created, nil
+}
+
+func (s *carService) Patch(ctx context.Context, id int64, kind PatchKind, patch []byte, version int64) (*models.Car, error) {
+	current, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != current.Version {
+		return nil, ErrPreconditionFailed
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := applyPatch(doc, kind, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var car models.Car
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&car); err != nil {
+		return nil, fmt.Errorf("%w: patched car is invalid: %v", ErrValidation, err)
+	}
+	problems := ValidationErrors{}
+	if car.ID != id {
+		problems.Add("id", "is read-only")
+	}
+	if car.Version != current.Version {
+		problems.Add("version", "is read-only")
+	}
+	if !car.CreatedAt.Equal(current.CreatedAt) {
+		problems.Add("created_at", "is read-only")
+	}
+	if !car.UpdatedAt.Equal(current.UpdatedAt) {
+		problems.Add("updated_at", "is read-only")
+	}
+	if !car.PriceChangedAt.Equal(current.PriceChangedAt) {
+		problems.Add("price_changed_at", "is read-only")
+	}
+	if !sameTime(car.ReservedUntil, current.ReservedUntil) {
+		problems.Add("reserved_until", "is read-only")
+	}
+	if !sameServiceSummary(car.ServiceSummary, current.ServiceSummary) {
+		problems.Add("service_summary", "is read-only")
+	}
+	if car.DeletedAt != nil {
+		problems.Add("deleted_at", "is read-only")
+	}
+	if err := problems.Err(); err != nil {
+		return nil, err
+	}
+
+	// The write is conditioned on the version the patch was applied to, so a
+	// concurrent update between the read above and this write is not lost.
+	return s.Update(ctx, &car)
+}
+
+func (s *carService) Transfer(ctx context.Context, id, inventoryID, version int64) (*models.Car, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: id must be positive", ErrValidation)
+	}
+	if inventoryID <= 0 {
+		return nil, fmt.Errorf("%w: inventory_id must be positive", ErrValidation)
+	}
+
+	_, err := s.inventories.GetByID(ctx, inventoryID)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, ErrCarNotFound
+		return nil, ErrInventoryNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	updated, err := s.repo.GetByID(ctx, car.ID)
+	var transferred *models.Car
+	err = s.inTx(ctx, func(tx *carService) error {
+		current, err := tx.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && version != current.Version {
+			return ErrPreconditionFailed
+		}
+		if current.InventoryID == inventoryID {
+			transferred = current
+			return nil
+		}
+
+		err = tx.repo.Transfer(ctx, id, inventoryID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCarNotFound
+		}
+		if err != nil {
+			return constraintError(err)
+		}
+
+		if transferred, err = tx.repo.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return tx.record(ctx, models.CarTransferred, id, current, transferred)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return updated, nil
+	return transferred, nil
 }
 
-func (s *carService) Delete(ctx context.Context, id int64) error {
+func (s *carService) Delete(ctx context.Context, id, version int64) error {
 	if id <= 0 {
 		return fmt.Errorf("%w: id must be positive", ErrValidation)
 	}
 
-	err := s.repo.Delete(ctx, id)
-	if errors.Is(err, sql.ErrNoRows) {
-		return ErrCarNotFound
+	return s.inTx(ctx, func(tx *carService) error {
+		current, err := tx.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.repo.Delete(ctx, id, version)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCarNotFound
+		}
+		if errors.Is(err, repository.ErrStaleVersion) {
+			return ErrPreconditionFailed
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.record(ctx, models.CarDeleted, id, current, nil)
+	})
+}
+
+func (s *carService) ListDeleted(ctx context.Context) ([]*models.Car, error) {
+	return s.repo.GetDeleted(ctx)
+}
+
+func (s *carService) Restore(ctx context.Context, id int64) (*models.Car, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: id must be positive", ErrValidation)
 	}
 
-	return err
+	var restored *models.Car
+	err := s.inTx(ctx, func(tx *carService) error {
+		err := tx.repo.Restore(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCarNotFound
+		}
+		if err != nil {
+			return constraintError(err)
+		}
+
+		if restored, err = tx.repo.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return tx.record(ctx, models.CarRestored, id, nil, restored)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// Purge permanently removes a car from the trash; cars that have not been
+// deleted first are reported as not found.
+func (s *carService) Purge(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return fmt.Errorf("%w: id must be positive", ErrValidation)
+	}
+
+	err := s.inTx(ctx, func(tx *carService) error {
+		err := tx.repo.Purge(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCarNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.record(ctx, models.CarPurged, id, nil, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The attachment rows went with the car; their files are only removed
+	// once that is committed, and a failure just leaves them orphaned.
+	if s.files != nil {
+		if err := s.files.DeleteAll(attachmentDir(id)); err != nil {
+			s.logf("purge car %d: remove attachment files: %v", id, err)
+		}
+	}
+	return nil
 }
 
-func validateCar(car *models.Car) error {
+// sameCarData reports whether writing b over a would change nothing but the
+// version and timestamps, in which case the write is skipped.
+func sameCarData(a, b *models.Car) bool {
+	return a.InventoryID == b.InventoryID &&
+		a.Make == b.Make &&
+		a.Model == b.Model &&
+		a.Year == b.Year &&
+		a.Color == b.Color &&
+		a.VIN == b.VIN &&
+		a.Price == b.Price &&
+		a.Currency == b.Currency &&
+		a.Mileage == b.Mileage &&
+		slices.Equal(a.Tags, b.Tags) &&
+		maps.Equal(a.Attributes, b.Attributes)
+}
+
+// checkVIN applies the configured VINCheckMode to car, unless it keeps the
+// VIN of stored.
+func (s *carService) checkVIN(car, stored *models.Car) error {
+	if s.vinCheck == VINCheckOff || !vinChanged(car, stored) {
+		return nil
+	}
+
+	mismatches := vinMismatches(car)
+	if s.vinCheck == VINCheckReject {
+		return mismatches.Err()
+	}
+	if len(mismatches) > 0 {
+		s.logf("vin %s check: %s", car.VIN, mismatches.summary())
+	}
+
+	return nil
+}
+
+// validateCar checks car before it is written over stored, which is nil for
+// a new car. The VIN is only checked when it changes, so that cars stored
+// before VINs were validated can still be edited.
+func (s *carService) validateCar(ctx context.Context, car, stored *models.Car) error {
 	if car == nil {
 		return fmt.Errorf("%w: car payload is required", ErrValidation)
 	}
+
+	problems := ValidationErrors{}
 	if car.InventoryID <= 0 {
-		return fmt.Errorf("%w: inventory_id must be positive", ErrValidation)
+		problems.Add("inventory_id", "must be positive")
 	}
 	if strings.TrimSpace(car.Make) == "" {
-		return fmt.Errorf("%w: make is required", ErrValidation)
+		problems.Add("make", "is required")
 	}
 	if strings.TrimSpace(car.Model) == "" {
-		return fmt.Errorf("%w: model is required", ErrValidation)
+		problems.Add("model", "is required")
 	}
 	if car.Year < 1886 || car.Year > 2100 {
-		return fmt.Errorf("%w: year must be between 1886 and 2100", ErrValidation)
+		problems.Add("year", "must be between 1886 and 2100")
 	}
 	if strings.TrimSpace(car.Color) == "" {
-		return fmt.Errorf("%w: color is required", ErrValidation)
+		problems.Add("color", "is required")
+	}
+	car.VIN = normalizeVIN(car.VIN)
+	if vinChanged(car, stored) {
+		for _, problem := range vinProblems(car.VIN, car.Year) {
+			problems.Add("vin", problem)
+		}
+	}
+	if car.Price < 0 {
+		problems.Add("price", "must not be negative")
 	}
-	if strings.TrimSpace(car.VIN) == "" {
-		return fmt.Errorf("%w: vin is required", ErrValidation)
+	car.Currency = strings.ToUpper(strings.TrimSpace(car.Currency))
+	if car.Currency != "" && !isCurrencyCode(car.Currency) {
+		problems.Add("currency", "must be a three-letter ISO 4217 code")
+	}
+	if car.Price > 0 && car.Currency == "" {
+		problems.Add("currency", "is required when price is set")
+	}
+	if car.Mileage < 0 {
+		problems.Add("mileage", "must not be negative")
+	}
+	car.Tags = normalizeTags(car.Tags)
+	for _, problem := range tagProblems(car.Tags) {
+		problems.Add("tags", problem)
+	}
+	for _, problem := range attributeProblems(car.Attributes) {
+		problems.Add("attributes", problem)
+	}
+	if s.catalog != nil && problems["make"] == nil && problems["model"] == nil {
+		if err := s.matchCatalog(ctx, car, problems); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return problems.Err()
+}
+
+// vinChanged reports whether car gives a VIN other than the one of stored,
+// which is nil for a new car.
+func vinChanged(car, stored *models.Car) bool {
+	return stored == nil || stored.VIN == "" || car.VIN != stored.VIN
+}
+
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeCarFilter(filter *models.CarFilter) error {
+	if filter.SortBy == "" {
+		filter.SortBy = "id"
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultCarPageSize
+	}
+
+	problems := ValidationErrors{}
+	if !carSortFields[filter.SortBy] {
+		problems.Add("sort", "must be one of id, inventory_id, make, model, year, color, vin, price_changed_at")
+	}
+	if filter.Limit < 0 || filter.Limit > maxCarPageSize {
+		problems.Add("limit", fmt.Sprintf("must be between 1 and %d", maxCarPageSize))
+	}
+	if filter.InventoryID < 0 {
+		problems.Add("inventory_id", "must be positive")
+	}
+	if filter.Status != "" && !slices.Contains(carStatuses, filter.Status) {
+		problems.Add("status", "must be one of "+strings.Join(carStatuses, ", "))
+	}
+	if filter.YearMin > 0 && filter.YearMax > 0 && filter.YearMin > filter.YearMax {
+		problems.Add("year_min", "must not be greater than year_max")
+	}
+	filter.Tags = normalizeTags(filter.Tags)
+	for _, tag := range filter.Tags {
+		if tag == "" {
+			problems.Add("tag", "must not be empty")
+		} else if tagTooLong(tag) {
+			problems.Add("tag", fmt.Sprintf("must be at most %d characters", maxTagLength))
+		}
+	}
+	for name := range filter.Attributes {
+		if attributeNameProblem(name) != "" {
+			problems.Add("attr."+name, "is not a valid attribute name")
+		}
+	}
+
+	return problems.Err()
 }
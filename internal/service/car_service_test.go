@@ -4,22 +4,40 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"carsapi/internal/models"
+	"carsapi/internal/repository"
 )
 
 type fakeCarRepository struct {
-	cars   map[int64]*models.Car
-	nextID int64
+	cars         map[int64]*models.Car
+	deleted      map[int64]*models.Car
+	history      []*models.CarHistoryEntry
+	reservations []*models.Reservation
+	sales        []*models.Sale
+	services     []*models.ServiceRecord
+	prices       []*models.PriceChange
+	nextID       int64
 }
 
 func newFakeCarRepository() *fakeCarRepository {
-	return &fakeCarRepository{cars: map[int64]*models.Car{}, nextID: 1}
+	return &fakeCarRepository{cars: map[int64]*models.Car{}, deleted: map[int64]*models.Car{}, nextID: 1}
 }
 
 func (f *fakeCarRepository) Create(_ context.Context, car *models.Car) error {
+	for _, existing := range f.cars {
+		if existing.VIN == car.VIN {
+			return &repository.ConstraintError{Err: repository.ErrUniqueViolation, Column: "vin"}
+		}
+	}
 	car.ID = f.nextID
+	car.Version = 1
+	car.PriceChangedAt = time.Now()
 	f.nextID++
 	copyCar := *car
 	f.cars[car.ID] = &copyCar
@@ -35,10 +53,63 @@ func (f *fakeCarRepository) GetByID(_ context.Context, id int64) (*models.Car, e
 	return &copyCar, nil
 }
 
-func (f *fakeCarRepository) GetAll(_ context.Context) ([]*models.Car, error) {
+func (f *fakeCarRepository) GetByVIN(_ context.Context, vin string) (*models.Car, error) {
+	for _, car := range f.cars {
+		if car.VIN == vin {
+			copyCar := *car
+			return &copyCar, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeCarRepository) GetAll(_ context.Context, filter models.CarFilter) ([]*models.Car, string, error) {
 	out := make([]*models.Car, 0, len(f.cars))
 	for id := int64(1); id <= f.nextID; id++ {
-		if car, ok := f.cars[id]; ok {
+		if car, ok := f.cars[id]; ok && matchesFilter(car, filter) {
+			copyCar := *car
+			out = append(out, &copyCar)
+		}
+	}
+	if filter.SortBy == "price_changed_at" {
+		slices.SortStableFunc(out, func(a, b *models.Car) int {
+			return a.PriceChangedAt.Compare(b.PriceChangedAt)
+		})
+	}
+	if len(out) > filter.Limit {
+		return out[:filter.Limit], "next", nil
+	}
+	return out, "", nil
+}
+
+func matchesFilter(car *models.Car, filter models.CarFilter) bool {
+	return (filter.Make == "" || car.Make == filter.Make) &&
+		(filter.InventoryID == 0 || car.InventoryID == filter.InventoryID) &&
+		(filter.Status == "" || car.Status == filter.Status) &&
+		(filter.PriceChangedBefore.IsZero() || !car.PriceChangedAt.After(filter.PriceChangedBefore))
+}
+
+func (f *fakeCarRepository) Count(ctx context.Context, filter models.CarFilter) (int64, error) {
+	filter.Limit = len(f.cars)
+	cars, _, err := f.GetAll(ctx, filter)
+	return int64(len(cars)), err
+}
+
+func (f *fakeCarRepository) Search(_ context.Context, text string, limit int) ([]*models.Car, error) {
+	out := make([]*models.Car, 0)
+	for id := int64(1); id <= f.nextID && len(out) < limit; id++ {
+		if car, ok := f.cars[id]; ok && strings.Contains(strings.ToLower(car.Make+" "+car.Model), strings.ToLower(text)) {
+			copyCar := *car
+			out = append(out, &copyCar)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeCarRepository) GetByInventoryID(_ context.Context, inventoryID int64) ([]*models.Car, error) {
+	out := make([]*models.Car, 0, len(f.cars))
+	for id := int64(1); id <= f.nextID; id++ {
+		if car, ok := f.cars[id]; ok && car.InventoryID == inventoryID {
 			copyCar := *car
 			out = append(out, &copyCar)
 		}
@@ -47,26 +118,227 @@ func (f *fakeCarRepository) GetAll(_ context.Context) ([]*models.Car, error) {
 }
 
 func (f *fakeCarRepository) Update(_ context.Context, car *models.Car) error {
-	if _, ok := f.cars[car.ID]; !ok {
+	current, ok := f.cars[car.ID]
+	if !ok {
 		return sql.ErrNoRows
 	}
+	if car.Version != 0 && car.Version != current.Version {
+		return repository.ErrStaleVersion
+	}
 	copyCar := *car
+	copyCar.Version = current.Version + 1
+	copyCar.PriceChangedAt = current.PriceChangedAt
+	if car.Price != current.Price || car.Currency != current.Currency {
+		copyCar.PriceChangedAt = time.Now()
+	}
 	f.cars[car.ID] = &copyCar
 	return nil
 }
 
-func (f *fakeCarRepository) Delete(_ context.Context, id int64) error {
-	if _, ok := f.cars[id]; !ok {
+func (f *fakeCarRepository) UpdateStatus(_ context.Context, update *models.Car) error {
+	car, ok := f.cars[update.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if update.Version != 0 && update.Version != car.Version {
+		return repository.ErrStaleVersion
+	}
+	car.Status = update.Status
+	car.ReservedUntil = update.ReservedUntil
+	car.Version++
+	return nil
+}
+
+func (f *fakeCarRepository) UpsertByVIN(ctx context.Context, car *models.Car) (bool, error) {
+	existing, err := f.GetByVIN(ctx, car.VIN)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, f.Create(ctx, car)
+	}
+	car.ID = existing.ID
+	if err := f.Update(ctx, car); err != nil {
+		return false, err
+	}
+	car.Version = f.cars[car.ID].Version
+	return false, nil
+}
+
+func (f *fakeCarRepository) WithinTx(_ context.Context, fn func(repository.CarRepository) error) error {
+	cars, deleted, history, sales, prices, nextID := copyCars(f.cars), copyCars(f.deleted), len(f.history), len(f.sales), len(f.prices), f.nextID
+	reservations := make([]*models.Reservation, 0, len(f.reservations))
+	for _, reservation := range f.reservations {
+		copyReservation := *reservation
+		reservations = append(reservations, &copyReservation)
+	}
+
+	if err := fn(f); err != nil {
+		f.cars, f.deleted, f.history, f.reservations, f.sales, f.prices, f.nextID = cars, deleted, f.history[:history], reservations, f.sales[:sales], f.prices[:prices], nextID
+		return err
+	}
+	return nil
+}
+
+func (f *fakeCarRepository) AddHistory(_ context.Context, entry *models.CarHistoryEntry) error {
+	entry.ID = int64(len(f.history) + 1)
+	entry.ChangedAt = time.Now()
+	f.history = append(f.history, entry)
+	return nil
+}
+
+func (f *fakeCarRepository) AddPriceChange(_ context.Context, change *models.PriceChange) error {
+	change.ID = int64(len(f.prices) + 1)
+	f.prices = append(f.prices, change)
+	return nil
+}
+
+func (f *fakeCarRepository) GetPriceHistory(_ context.Context, carID int64) ([]*models.PriceChange, error) {
+	out := make([]*models.PriceChange, 0)
+	for _, change := range f.prices {
+		if change.CarID == carID {
+			out = append(out, change)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeCarRepository) GetByIDAsOf(_ context.Context, id int64, asOf time.Time) (*models.Car, error) {
+	var car *models.Car
+	for _, entry := range f.history {
+		if entry.CarID == id && !entry.ChangedAt.After(asOf) {
+			car = entry.New
+		}
+	}
+	if car == nil {
+		return nil, sql.ErrNoRows
+	}
+	copyCar := *car
+	return &copyCar, nil
+}
+
+func (f *fakeCarRepository) GetHistory(_ context.Context, carID int64) ([]*models.CarHistoryEntry, error) {
+	out := make([]*models.CarHistoryEntry, 0)
+	for _, entry := range f.history {
+		if entry.CarID == carID {
+			copyEntry := *entry
+			out = append(out, &copyEntry)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeCarRepository) CreateReservation(_ context.Context, reservation *models.Reservation) error {
+	for _, existing := range f.reservations {
+		if existing.CarID == reservation.CarID && existing.ReleasedAt == nil {
+			return &repository.ConstraintError{Err: repository.ErrUniqueViolation, Column: "car_id"}
+		}
+	}
+	reservation.ID = int64(len(f.reservations) + 1)
+	reservation.CreatedAt = time.Now()
+	f.reservations = append(f.reservations, reservation)
+	return nil
+}
+
+func (f *fakeCarRepository) GetReservations(_ context.Context, carID int64) ([]*models.Reservation, error) {
+	out := make([]*models.Reservation, 0)
+	for i := len(f.reservations) - 1; i >= 0; i-- {
+		if f.reservations[i].CarID == carID {
+			out = append(out, f.reservations[i])
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeCarRepository) GetExpiredReservations(_ context.Context, now time.Time) ([]*models.Reservation, error) {
+	out := make([]*models.Reservation, 0)
+	for _, reservation := range f.reservations {
+		if reservation.ReleasedAt == nil && !reservation.ExpiresAt.After(now) {
+			out = append(out, reservation)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeCarRepository) ReleaseReservation(_ context.Context, carID int64, reason string) error {
+	for _, reservation := range f.reservations {
+		if reservation.CarID == carID && reservation.ReleasedAt == nil {
+			now := time.Now()
+			reservation.ReleasedAt = &now
+			reservation.ReleaseReason = reason
+		}
+	}
+	return nil
+}
+
+func copyCars(cars map[int64]*models.Car) map[int64]*models.Car {
+	out := make(map[int64]*models.Car, len(cars))
+	for id, car := range cars {
+		copyCar := *car
+		out[id] = &copyCar
+	}
+	return out
+}
+
+func (f *fakeCarRepository) Transfer(_ context.Context, id, inventoryID int64) error {
+	car, ok := f.cars[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	car.InventoryID = inventoryID
+	car.Version++
+	return nil
+}
+
+func (f *fakeCarRepository) Delete(_ context.Context, id, version int64) error {
+	car, ok := f.cars[id]
+	if !ok {
 		return sql.ErrNoRows
 	}
+	if version != 0 && version != car.Version {
+		return repository.ErrStaleVersion
+	}
+	deletedAt := time.Now()
+	car.DeletedAt = &deletedAt
+	car.Version++
+	f.deleted[id] = car
 	delete(f.cars, id)
 	return nil
 }
 
+func (f *fakeCarRepository) GetDeleted(_ context.Context) ([]*models.Car, error) {
+	out := make([]*models.Car, 0, len(f.deleted))
+	for _, car := range f.deleted {
+		copyCar := *car
+		out = append(out, &copyCar)
+	}
+	return out, nil
+}
+
+func (f *fakeCarRepository) Restore(ctx context.Context, id int64) error {
+	car, ok := f.deleted[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if _, err := f.GetByVIN(ctx, car.VIN); err == nil {
+		return &repository.ConstraintError{Err: repository.ErrUniqueViolation, Column: "vin"}
+	}
+	car.DeletedAt = nil
+	car.Version++
+	f.cars[id] = car
+	delete(f.deleted, id)
+	return nil
+}
+
+func (f *fakeCarRepository) Purge(_ context.Context, id int64) error {
+	if _, ok := f.deleted[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(f.deleted, id)
+	return nil
+}
+
 func TestCarServiceCreate(t *testing.T) {
-	svc := NewCarService(newFakeCarRepository())
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
 
-	car := &models.Car{InventoryID: 1, Make: "Toyota", Model: "Camry", Year: 2023, Color: "White", VIN: "VIN-SVC-1"}
+	car := &models.Car{InventoryID: 1, Make: "Toyota", Model: "Camry", Year: 2023, Color: "White", VIN: testVIN(1)}
 	created, err := svc.Create(context.Background(), car)
 	if err != nil {
 		t.Fatalf("Create() error = %v", err)
@@ -78,16 +350,44 @@ func TestCarServiceCreate(t *testing.T) {
 }
 
 func TestCarServiceCreateValidation(t *testing.T) {
-	svc := NewCarService(newFakeCarRepository())
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
 
 	_, err := svc.Create(context.Background(), &models.Car{InventoryID: 1})
 	if !errors.Is(err, ErrValidation) {
 		t.Fatalf("Create() error = %v, want ErrValidation", err)
 	}
+
+	var problems ValidationErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("Create() error = %T, want ValidationErrors", err)
+	}
+	for _, field := range []string{"make", "model", "year", "color", "vin"} {
+		if len(problems[field]) == 0 {
+			t.Fatalf("Create() problems = %v, want an entry for %s", problems, field)
+		}
+	}
+	if _, ok := problems["inventory_id"]; ok {
+		t.Fatalf("Create() problems = %v, want no entry for inventory_id", problems)
+	}
+}
+
+func TestCarServiceCreateDuplicateVIN(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	car := &models.Car{InventoryID: 1, Make: "Ford", Model: "Focus", Year: 2020, Color: "Red", VIN: testVIN(99)}
+	if _, err := svc.Create(context.Background(), car); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	duplicate := &models.Car{InventoryID: 1, Make: "Ford", Model: "Puma", Year: 2021, Color: "Grey", VIN: testVIN(99)}
+	_, err := svc.Create(context.Background(), duplicate)
+	var fieldErr *FieldError
+	if !errors.Is(err, ErrConflict) || !errors.As(err, &fieldErr) || fieldErr.Field != "vin" {
+		t.Fatalf("Create() error = %v, want ErrConflict on vin", err)
+	}
 }
 
 func TestCarServiceGetByIDNotFound(t *testing.T) {
-	svc := NewCarService(newFakeCarRepository())
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
 
 	_, err := svc.GetByID(context.Background(), 999)
 	if !errors.Is(err, ErrCarNotFound) {
@@ -97,10 +397,10 @@ func TestCarServiceGetByIDNotFound(t *testing.T) {
 
 func TestCarServiceUpdate(t *testing.T) {
 	repo := newFakeCarRepository()
-	svc := NewCarService(repo)
+	svc := NewCarService(repo, newFakeInventoryRepository())
 	ctx := context.Background()
 
-	created, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Tesla", Model: "Model 3", Year: 2022, Color: "Black", VIN: "VIN-SVC-2"})
+	created, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Tesla", Model: "Model 3", Year: 2022, Color: "Black", VIN: testVIN(2)})
 	created.Color = "Red"
 
 	updated, err := svc.Update(ctx, created)
@@ -112,14 +412,46 @@ func TestCarServiceUpdate(t *testing.T) {
 	}
 }
 
+func TestCarServiceKeepsLegacyVIN(t *testing.T) {
+	repo := newFakeCarRepository()
+	svc := NewCarService(repo, newFakeInventoryRepository(), WithVINCheck(VINCheckReject))
+	ctx := context.Background()
+
+	// Cars stored before VINs were validated.
+	legacy := &models.Car{InventoryID: 1, Make: "Tesla", Model: "Model S", Year: 2015, Color: "Black", VIN: "LEGACY-VIN-1"}
+	if err := repo.Create(ctx, legacy); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	legacy.Color = "Red"
+	updated, err := svc.Update(ctx, legacy)
+	if err != nil || updated.Color != "Red" {
+		t.Fatalf("Update() = %+v, %v; want the color changed", updated, err)
+	}
+	if patched, err := svc.Patch(ctx, legacy.ID, MergePatch, []byte(`{"color":"Blue"}`), 0); err != nil || patched.Color != "Blue" {
+		t.Fatalf("Patch() = %+v, %v; want the color changed", patched, err)
+	}
+	if _, _, err := svc.UpsertByVIN(ctx, "legacy-vin-1", &models.Car{InventoryID: 1, Make: "Tesla", Model: "Model S", Year: 2015, Color: "White"}); err != nil {
+		t.Fatalf("UpsertByVIN() error = %v", err)
+	}
+
+	// A new VIN must be valid.
+	if _, err := svc.Patch(ctx, legacy.ID, MergePatch, []byte(`{"vin":"LEGACY-VIN-2"}`), 0); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Patch() to another invalid vin error = %v, want ErrValidation", err)
+	}
+	if _, err := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Tesla", Model: "Model S", Year: 2015, Color: "Black", VIN: "LEGACY-VIN-3"}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Create() with an invalid vin error = %v, want ErrValidation", err)
+	}
+}
+
 func TestCarServiceDelete(t *testing.T) {
 	repo := newFakeCarRepository()
-	svc := NewCarService(repo)
+	svc := NewCarService(repo, newFakeInventoryRepository())
 	ctx := context.Background()
 
-	created, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Audi", Model: "A4", Year: 2020, Color: "Gray", VIN: "VIN-SVC-3"})
+	created, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Audi", Model: "A4", Year: 2020, Color: "Gray", VIN: testVIN(3)})
 
-	if err := svc.Delete(ctx, created.ID); err != nil {
+	if err := svc.Delete(ctx, created.ID, 0); err != nil {
 		t.Fatalf("Delete() error = %v", err)
 	}
 
@@ -128,3 +460,636 @@ func TestCarServiceDelete(t *testing.T) {
 		t.Fatalf("GetByID() error = %v, want ErrCarNotFound", err)
 	}
 }
+
+func TestCarServiceTransfer(t *testing.T) {
+	inventories := newFakeInventoryRepository()
+	svc := NewCarService(newFakeCarRepository(), inventories)
+	ctx := context.Background()
+
+	created, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Honda", Model: "Fit", Year: 2019, Color: "Blue", VIN: testVIN(4)})
+	target := &models.Inventory{Name: "Overflow Lot"}
+	_ = inventories.Create(ctx, target)
+
+	transferred, err := svc.Transfer(ctx, created.ID, target.ID, 0)
+	if err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+	if transferred.InventoryID != target.ID {
+		t.Fatalf("Transfer() inventory_id = %d, want %d", transferred.InventoryID, target.ID)
+	}
+
+	if _, err := svc.Transfer(ctx, created.ID, 1, created.Version); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Transfer() at a stale version error = %v, want ErrPreconditionFailed", err)
+	}
+	back, err := svc.Transfer(ctx, created.ID, 1, transferred.Version)
+	if err != nil || back.InventoryID != 1 {
+		t.Fatalf("Transfer() at the current version = %+v, %v; want inventory 1", back, err)
+	}
+}
+
+func TestCarServiceTransferUnknownInventory(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := context.Background()
+
+	created, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Honda", Model: "Fit", Year: 2019, Color: "Blue", VIN: testVIN(5)})
+
+	_, err := svc.Transfer(ctx, created.ID, 42, 0)
+	if !errors.Is(err, ErrInventoryNotFound) {
+		t.Fatalf("Transfer() error = %v, want ErrInventoryNotFound", err)
+	}
+}
+
+func TestCarServiceGetAll(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := context.Background()
+
+	_, _ = svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Honda", Model: "Civic", Year: 2018, Color: "Blue", VIN: testVIN(6)})
+	_, _ = svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Honda", Model: "Accord", Year: 2020, Color: "Black", VIN: testVIN(7)})
+	_, _ = svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Mazda", Model: "CX-5", Year: 2021, Color: "Red", VIN: testVIN(8)})
+
+	page, err := svc.GetAll(ctx, models.CarFilter{Make: "Honda", Limit: 1})
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(page.Cars) != 1 || page.Total != 2 || page.NextCursor == "" {
+		t.Fatalf("GetAll() = %d cars, total %d, cursor %q; want 1 car, total 2, a cursor", len(page.Cars), page.Total, page.NextCursor)
+	}
+}
+
+func TestCarServiceGetAllValidation(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := context.Background()
+
+	filters := []models.CarFilter{
+		{SortBy: "price"},
+		{Limit: 1000},
+		{YearMin: 2020, YearMax: 2010},
+	}
+	for _, filter := range filters {
+		if _, err := svc.GetAll(ctx, filter); !errors.Is(err, ErrValidation) {
+			t.Fatalf("GetAll(%+v) error = %v, want ErrValidation", filter, err)
+		}
+	}
+}
+
+func TestCarServiceTagsAndAttributes(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := context.Background()
+
+	car, err := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Volvo", Model: "V70", Year: 2015, Color: "Red", VIN: testVIN(160), Tags: []string{" Winter Tires", "certified", "CERTIFIED"}, Attributes: map[string]string{"trim": "LX"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !slices.Equal(car.Tags, []string{"certified", "winter tires"}) {
+		t.Fatalf("Create() tags = %q, want normalized and deduplicated", car.Tags)
+	}
+
+	update := *car
+	update.Attributes = map[string]string{"trim": "LX", "seats": "7"}
+	updated, err := svc.Update(ctx, &update)
+	if err != nil || updated.Version != car.Version+1 {
+		t.Fatalf("Update() of attributes = %+v, %v; want a new version", updated, err)
+	}
+
+	invalid := []models.Car{
+		{Tags: []string{"  "}},
+		{Tags: []string{strings.Repeat("x", maxTagLength+1)}},
+		{Attributes: map[string]string{"": "x"}},
+		{Attributes: map[string]string{"wheel base": "2.8m"}},
+		{Attributes: map[string]string{"trim": strings.Repeat("x", maxAttributeValueLength+1)}},
+	}
+	for i, fields := range invalid {
+		car := fields
+		car.InventoryID, car.Make, car.Model, car.Year, car.Color, car.VIN = 1, "Volvo", "V70", 2015, "Red", testVIN(161+i)
+		if _, err := svc.Create(ctx, &car); !errors.Is(err, ErrValidation) {
+			t.Fatalf("Create(%+v) error = %v, want ErrValidation", fields, err)
+		}
+	}
+
+	for _, filter := range []models.CarFilter{{Tags: []string{""}}, {Attributes: map[string]string{`trim"`: "LX"}}} {
+		if _, err := svc.GetAll(ctx, filter); !errors.Is(err, ErrValidation) {
+			t.Fatalf("GetAll(%+v) error = %v, want ErrValidation", filter, err)
+		}
+	}
+}
+
+func TestCarServicePriceHistory(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := ContextWithActor(context.Background(), "sam")
+
+	car, err := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Volvo", Model: "V90", Year: 2019, Color: "Grey", VIN: testVIN(170), Price: 3000000, Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Changes that leave the price alone add no row.
+	update := *car
+	update.Mileage = 42000
+	if _, err := svc.Update(ctx, &update); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	update.Price, update.Version = 2800000, 0
+	if _, err := svc.Update(ctx, &update); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	upsert := update
+	upsert.Price, upsert.Currency = 2700000, "sek"
+	if _, _, err := svc.UpsertByVIN(ctx, car.VIN, &upsert); err != nil {
+		t.Fatalf("UpsertByVIN() error = %v", err)
+	}
+
+	prices, err := svc.Prices(ctx, car.ID)
+	if err != nil {
+		t.Fatalf("Prices() error = %v", err)
+	}
+	if len(prices) != 3 || prices[0].Price != 3000000 || prices[1].Price != 2800000 || prices[2].Currency != "SEK" || prices[2].ChangedBy != "sam" {
+		t.Fatalf("Prices() = %+v, want 3000000 EUR, 2800000 EUR and 2700000 SEK by sam", prices)
+	}
+
+	if _, err := svc.Patch(ctx, car.ID, MergePatch, []byte(`{"price_changed_at":"2020-01-01T00:00:00Z"}`), 0); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Patch() of price_changed_at error = %v, want ErrValidation", err)
+	}
+	if _, err := svc.Prices(ctx, 999); !errors.Is(err, ErrCarNotFound) {
+		t.Fatalf("Prices() of a missing car error = %v, want ErrCarNotFound", err)
+	}
+}
+
+func TestCarServiceSearch(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := context.Background()
+
+	_, _ = svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Honda", Model: "Civic", Year: 2018, Color: "Blue", VIN: testVIN(9)})
+
+	cars, err := svc.Search(ctx, "civic", 0)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(cars) != 1 {
+		t.Fatalf("Search() len = %d, want 1", len(cars))
+	}
+
+	if _, err := svc.Search(ctx, "   ", 0); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Search() error = %v, want ErrValidation", err)
+	}
+}
+
+func TestCarServicePatch(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := context.Background()
+
+	created, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Kia", Model: "Rio", Year: 2017, Color: "White", VIN: testVIN(10)})
+
+	patched, err := svc.Patch(ctx, created.ID, MergePatch, []byte(`{"color":"Silver"}`), 0)
+	if err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if patched.Color != "Silver" || patched.Model != "Rio" {
+		t.Fatalf("Patch() = %+v, want color Silver and model kept", patched)
+	}
+
+	invalid := []string{`{"year":1200}`, `{"make":null}`, `{"id":99}`, `{"version":7}`, `{"created_at":"2020-01-01T00:00:00Z"}`, `{"colour":"Red"}`}
+	for _, patch := range invalid {
+		if _, err := svc.Patch(ctx, created.ID, MergePatch, []byte(patch), 0); !errors.Is(err, ErrValidation) {
+			t.Fatalf("Patch(%s) error = %v, want ErrValidation", patch, err)
+		}
+	}
+}
+
+func TestCarServiceOptimisticConcurrency(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := context.Background()
+
+	created, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Ford", Model: "Escape", Year: 2020, Color: "Gray", VIN: testVIN(11)})
+
+	first := *created
+	first.Color = "Blue"
+	updated, err := svc.Update(ctx, &first)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.Version != created.Version+1 {
+		t.Fatalf("Update() version = %d, want %d", updated.Version, created.Version+1)
+	}
+
+	stale := *created
+	stale.Color = "Green"
+	if _, err := svc.Update(ctx, &stale); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Update() error = %v, want ErrPreconditionFailed", err)
+	}
+	if _, err := svc.Patch(ctx, created.ID, MergePatch, []byte(`{"color":"Green"}`), created.Version); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Patch() error = %v, want ErrPreconditionFailed", err)
+	}
+	if err := svc.Delete(ctx, created.ID, created.Version); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Delete() error = %v, want ErrPreconditionFailed", err)
+	}
+	if err := svc.Delete(ctx, created.ID, updated.Version); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+}
+
+func TestCarServiceUpsertByVIN(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := context.Background()
+	vin := testVIN(40)
+
+	created, isNew, err := svc.UpsertByVIN(ctx, strings.ToLower(vin), &models.Car{InventoryID: 1, Make: "Honda", Model: "Civic", Year: 2020, Color: "Blue"})
+	if err != nil || !isNew || created.VIN != vin {
+		t.Fatalf("UpsertByVIN() = %+v, %v, %v; want a new car with normalized vin", created, isNew, err)
+	}
+
+	replaced, isNew, err := svc.UpsertByVIN(ctx, vin, &models.Car{InventoryID: 1, Make: "Honda", Model: "Civic", Year: 2020, Color: "Red", VIN: vin, Version: created.Version})
+	if err != nil || isNew || replaced.ID != created.ID || replaced.Color != "Red" {
+		t.Fatalf("UpsertByVIN() = %+v, %v, %v; want car %d replaced", replaced, isNew, err, created.ID)
+	}
+
+	if _, _, err := svc.UpsertByVIN(ctx, vin, &models.Car{InventoryID: 1, Make: "Honda", Model: "Civic", Year: 2020, Color: "Red", VIN: testVIN(41)}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("UpsertByVIN() mismatched vin error = %v, want ErrValidation", err)
+	}
+
+	if _, _, err := svc.UpsertByVIN(ctx, testVIN(42), &models.Car{InventoryID: 1, Make: "Honda", Model: "Civic", Year: 2020, Color: "Red", Version: 3}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("UpsertByVIN() If-Match on missing car error = %v, want ErrPreconditionFailed", err)
+	}
+
+	fetched, err := svc.GetByVIN(ctx, " "+strings.ToLower(vin))
+	if err != nil || fetched.ID != created.ID {
+		t.Fatalf("GetByVIN() = %+v, %v", fetched, err)
+	}
+	if _, err := svc.GetByVIN(ctx, testVIN(43)); !errors.Is(err, ErrCarNotFound) {
+		t.Fatalf("GetByVIN() error = %v, want ErrCarNotFound", err)
+	}
+}
+
+func TestCarServiceBulkAtomic(t *testing.T) {
+	repo := newFakeCarRepository()
+	svc := NewCarService(repo, newFakeInventoryRepository())
+	ctx := context.Background()
+
+	ops := []models.BulkOperation{
+		{Op: "create", Car: &models.Car{InventoryID: 1, Make: "Kia", Model: "Rio", Year: 2017, Color: "White", VIN: testVIN(50)}},
+		{Op: "delete", ID: 99},
+	}
+	results, err := svc.Bulk(ctx, BulkAtomic, ops)
+	if err != nil {
+		t.Fatalf("Bulk() error = %v", err)
+	}
+	if !errors.Is(results[0].Err, ErrBulkAborted) || !errors.Is(results[1].Err, ErrCarNotFound) {
+		t.Fatalf("Bulk() results = %+v, want the create aborted by the failed delete", results)
+	}
+	if len(repo.cars) != 0 {
+		t.Fatalf("cars = %d, want the batch rolled back", len(repo.cars))
+	}
+}
+
+func TestCarServiceBulkBestEffort(t *testing.T) {
+	repo := newFakeCarRepository()
+	svc := NewCarService(repo, newFakeInventoryRepository())
+	ctx := context.Background()
+
+	ops := []models.BulkOperation{
+		{Op: "create", Car: &models.Car{InventoryID: 1, Make: "Kia", Model: "Rio", Year: 2017, Color: "White", VIN: testVIN(51)}},
+		{Op: "create", Car: &models.Car{InventoryID: 1, Make: "Kia", Model: "Soul", Year: 2018, Color: "Green", VIN: testVIN(51)}},
+		{Op: "update", ID: 1, Car: &models.Car{InventoryID: 1, Make: "Kia", Model: "Rio", Year: 2017, Color: "Black", VIN: testVIN(51)}},
+		{Op: "rename"},
+	}
+	results, err := svc.Bulk(ctx, BulkBestEffort, ops)
+	if err != nil {
+		t.Fatalf("Bulk() error = %v", err)
+	}
+	if results[0].Err != nil || !errors.Is(results[1].Err, ErrConflict) || results[2].Err != nil || !errors.Is(results[3].Err, ErrValidation) {
+		t.Fatalf("Bulk() results = %+v", results)
+	}
+	if len(repo.cars) != 1 || repo.cars[1].Color != "Black" {
+		t.Fatalf("cars = %v, want the created car updated to Black", repo.cars)
+	}
+}
+
+func TestCarServiceBulkValidation(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+
+	if _, err := svc.Bulk(context.Background(), "sometimes", nil); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Bulk() error = %v, want ErrValidation", err)
+	}
+}
+
+func TestCarServiceTrash(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := context.Background()
+
+	car, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Mini", Model: "Cooper", Year: 2015, Color: "Green", VIN: testVIN(60)})
+	if err := svc.Purge(ctx, car.ID); !errors.Is(err, ErrCarNotFound) {
+		t.Fatalf("Purge() of a live car error = %v, want ErrCarNotFound", err)
+	}
+	if err := svc.Delete(ctx, car.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := svc.GetByID(ctx, car.ID); !errors.Is(err, ErrCarNotFound) {
+		t.Fatalf("GetByID() after delete error = %v, want ErrCarNotFound", err)
+	}
+
+	trash, err := svc.ListDeleted(ctx)
+	if err != nil || len(trash) != 1 || trash[0].DeletedAt == nil {
+		t.Fatalf("ListDeleted() = %v, %v; want the deleted car", trash, err)
+	}
+
+	// The VIN is free again while the car is in the trash, which blocks restoring it.
+	reused, err := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Mini", Model: "Clubman", Year: 2016, Color: "Red", VIN: testVIN(60)})
+	if err != nil {
+		t.Fatalf("Create() with a trashed VIN error = %v", err)
+	}
+	if _, err := svc.Restore(ctx, car.ID); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Restore() error = %v, want ErrConflict", err)
+	}
+
+	if err := svc.Delete(ctx, reused.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	restored, err := svc.Restore(ctx, car.ID)
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("Restore() = %+v, %v", restored, err)
+	}
+
+	if err := svc.Purge(ctx, reused.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if trash, _ := svc.ListDeleted(ctx); len(trash) != 0 {
+		t.Fatalf("ListDeleted() after purge = %v, want empty", trash)
+	}
+}
+
+func TestCarServiceHistory(t *testing.T) {
+	repo := newFakeCarRepository()
+	svc := NewCarService(repo, newFakeInventoryRepository())
+	ctx := ContextWithActor(context.Background(), "alice")
+
+	car, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Audi", Model: "A3", Year: 2019, Color: "Black", VIN: testVIN(70)})
+	_, _ = svc.Update(context.Background(), &models.Car{ID: car.ID, InventoryID: 1, Make: "Audi", Model: "A3", Year: 2019, Color: "White", VIN: testVIN(70)})
+	if _, err := svc.Update(ctx, &models.Car{ID: car.ID, Version: 1, InventoryID: 1, Make: "Audi", Model: "A4", Year: 2019, Color: "White", VIN: testVIN(70)}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("stale Update() error = %v, want ErrPreconditionFailed", err)
+	}
+	_ = svc.Delete(ctx, car.ID, 0)
+
+	history, err := svc.History(ctx, car.ID)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("History() returned %d entries, want 3 (failed writes are not recorded)", len(history))
+	}
+
+	created, updated, deleted := history[0], history[1], history[2]
+	if created.Action != models.CarCreated || created.Actor != "alice" || len(created.Changes) == 0 || created.Changes[0].Old != nil {
+		t.Fatalf("create entry = %+v", created)
+	}
+	if updated.Actor != anonymousActor {
+		t.Fatalf("update actor = %q, want %q", updated.Actor, anonymousActor)
+	}
+	want := []models.FieldChange{{Field: "color", Old: "Black", New: "White"}, {Field: "version", Old: float64(1), New: float64(2)}}
+	if !reflect.DeepEqual(updated.Changes, want) {
+		t.Fatalf("update changes = %+v, want %+v", updated.Changes, want)
+	}
+	if deleted.Action != models.CarDeleted || deleted.Old == nil || deleted.New != nil {
+		t.Fatalf("delete entry = %+v", deleted)
+	}
+
+	if _, err := svc.History(ctx, 999); !errors.Is(err, ErrCarNotFound) {
+		t.Fatalf("History() of an unknown car error = %v, want ErrCarNotFound", err)
+	}
+}
+
+func TestCarServiceGetByIDAsOf(t *testing.T) {
+	repo := newFakeCarRepository()
+	svc := NewCarService(repo, newFakeInventoryRepository())
+	ctx := context.Background()
+
+	car, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Skoda", Model: "Octavia", Year: 2018, Color: "Grey", VIN: testVIN(80)})
+	_, _ = svc.Update(ctx, &models.Car{ID: car.ID, InventoryID: 1, Make: "Skoda", Model: "Octavia", Year: 2018, Color: "Blue", VIN: testVIN(80)})
+	_ = svc.Delete(ctx, car.ID, 0)
+
+	created := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	for i, entry := range repo.history {
+		entry.ChangedAt = created.AddDate(0, i, 0)
+	}
+
+	tests := []struct {
+		name      string
+		asOf      time.Time
+		wantColor string
+		wantErr   error
+	}{
+		{name: "before create", asOf: created.Add(-time.Second), wantErr: ErrCarNotFound},
+		{name: "at create", asOf: created, wantColor: "Grey"},
+		{name: "after update", asOf: created.AddDate(0, 1, 1), wantColor: "Blue"},
+		{name: "after delete", asOf: created.AddDate(0, 2, 1), wantErr: ErrCarNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.GetByIDAsOf(ctx, car.ID, tt.asOf)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetByIDAsOf() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got.Color != tt.wantColor {
+				t.Fatalf("GetByIDAsOf() = %+v, %v; want color %s", got, err, tt.wantColor)
+			}
+		})
+	}
+}
+
+func TestCarServiceSkipsWritesWithoutChanges(t *testing.T) {
+	repo := newFakeCarRepository()
+	svc := NewCarService(repo, newFakeInventoryRepository())
+	ctx := context.Background()
+
+	car, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Opel", Model: "Astra", Year: 2017, Color: "Silver", VIN: testVIN(90)})
+
+	same := *car
+	updated, err := svc.Update(ctx, &same)
+	if err != nil || updated.Version != car.Version {
+		t.Fatalf("Update() without changes = %+v, %v; want version %d", updated, err, car.Version)
+	}
+	stale := *car
+	stale.Version = car.Version + 1
+	if _, err := svc.Update(ctx, &stale); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Update() without changes at a stale version error = %v, want ErrPreconditionFailed", err)
+	}
+	transferred, err := svc.Transfer(ctx, car.ID, car.InventoryID, 0)
+	if err != nil || transferred.Version != car.Version {
+		t.Fatalf("Transfer() to the same inventory = %+v, %v; want version %d", transferred, err, car.Version)
+	}
+	upserted, created, err := svc.UpsertByVIN(ctx, car.VIN, &models.Car{InventoryID: 1, Make: "Opel", Model: "Astra", Year: 2017, Color: "Silver"})
+	if err != nil || created || upserted.Version != car.Version {
+		t.Fatalf("UpsertByVIN() without changes = %+v, %v, %v; want version %d", upserted, created, err, car.Version)
+	}
+
+	if history, _ := svc.History(ctx, car.ID); len(history) != 1 {
+		t.Fatalf("History() has %d entries, want only the create", len(history))
+	}
+}
+
+func TestCarServiceTransitions(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := context.Background()
+
+	car, err := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Fiat", Model: "500", Year: 2020, Color: "Cream", VIN: testVIN(100), Price: 1450000, Currency: "eur", Mileage: 12000})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if car.Status != models.StatusInStock || car.Currency != "EUR" {
+		t.Fatalf("Create() = %+v, want in_stock priced in EUR", car)
+	}
+
+	steps := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "receive", wantErr: ErrInvalidTransition},
+		{name: "release", wantErr: ErrInvalidTransition},
+		{name: "ship", want: models.StatusInTransit},
+		{name: "ship", wantErr: ErrInvalidTransition},
+		{name: "receive", want: models.StatusInStock},
+		{name: "reserve", wantErr: ErrValidation},
+		{name: "sell", wantErr: ErrValidation},
+		{name: "retire", want: models.StatusRetired},
+		{name: "receive", wantErr: ErrInvalidTransition},
+		{name: "scrap", wantErr: ErrValidation},
+	}
+	for i, step := range steps {
+		got, err := svc.Transition(ctx, car.ID, step.name, 0)
+		if step.wantErr != nil {
+			if !errors.Is(err, step.wantErr) {
+				t.Fatalf("step %d: Transition(%s) error = %v, want %v", i, step.name, err, step.wantErr)
+			}
+			continue
+		}
+		if err != nil || got.Status != step.want {
+			t.Fatalf("step %d: Transition(%s) = %+v, %v; want status %s", i, step.name, got, err, step.want)
+		}
+	}
+
+	if _, err := svc.Transition(ctx, car.ID, "retire", 1); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Transition() at a stale version error = %v, want ErrPreconditionFailed", err)
+	}
+}
+
+func TestCarServiceReservations(t *testing.T) {
+	repo := newFakeCarRepository()
+	svc := NewCarService(repo, newFakeInventoryRepository())
+	ctx := ContextWithActor(context.Background(), "dana")
+
+	car, err := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Volvo", Model: "V60", Year: 2022, Color: "Grey", VIN: testVIN(130)})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	for _, in := range []struct {
+		customer string
+		hours    int
+	}{{"", 24}, {"Kim", 0}, {"Kim", maxReservationHours + 1}} {
+		if _, err := svc.Reserve(ctx, car.ID, in.customer, in.hours, 0); !errors.Is(err, ErrValidation) {
+			t.Fatalf("Reserve(%q, %d) error = %v, want ErrValidation", in.customer, in.hours, err)
+		}
+	}
+	if _, err := svc.Reserve(ctx, car.ID, "Kim", 24, car.Version+1); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Reserve() at a stale version error = %v, want ErrPreconditionFailed", err)
+	}
+
+	reservation, err := svc.Reserve(ctx, car.ID, " Kim ", 24, car.Version)
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if reservation.Customer != "Kim" || reservation.ReservedBy != "dana" || time.Until(reservation.ExpiresAt) < 23*time.Hour {
+		t.Fatalf("Reserve() = %+v, want a 24 hour hold for Kim by dana", reservation)
+	}
+	reserved, _ := svc.GetByID(ctx, car.ID)
+	if reserved.Status != models.StatusReserved || reserved.ReservedUntil == nil || !reserved.ReservedUntil.Equal(reservation.ExpiresAt) {
+		t.Fatalf("GetByID() = %+v, want reserved until %v", reserved, reservation.ExpiresAt)
+	}
+
+	if _, err := svc.Reserve(ctx, car.ID, "Lee", 2, 0); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Reserve() on a reserved car error = %v, want ErrInvalidTransition", err)
+	}
+	if _, err := svc.Transition(ctx, car.ID, "ship", 0); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Transition(ship) on a reserved car error = %v, want ErrInvalidTransition", err)
+	}
+
+	if released, err := svc.ExpireReservations(ctx, time.Now()); err != nil || released != 0 {
+		t.Fatalf("ExpireReservations() before expiry = %d, %v; want 0", released, err)
+	}
+	released, err := svc.ExpireReservations(ctx, reservation.ExpiresAt)
+	if err != nil || released != 1 {
+		t.Fatalf("ExpireReservations() = %d, %v; want 1", released, err)
+	}
+	expired, _ := svc.GetByID(ctx, car.ID)
+	if expired.Status != models.StatusInStock || expired.ReservedUntil != nil {
+		t.Fatalf("GetByID() after expiry = %+v, want in stock without a hold", expired)
+	}
+	history, _ := svc.History(ctx, car.ID)
+	if last := history[len(history)-1]; last.Action != expireAction {
+		t.Fatalf("last history action = %q, want %q", last.Action, expireAction)
+	}
+
+	if _, err := svc.Reserve(ctx, car.ID, "Lee", 2, 0); err != nil {
+		t.Fatalf("Reserve() after expiry error = %v", err)
+	}
+	if _, err := svc.Transition(ctx, car.ID, "release", 0); err != nil {
+		t.Fatalf("Transition(release) error = %v", err)
+	}
+
+	// A hold the sweeper has not reached yet gives way to the next change.
+	if _, err := svc.Reserve(ctx, car.ID, "Max", 1, 0); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	past := time.Now().Add(-time.Minute)
+	repo.cars[car.ID].ReservedUntil = &past
+	if shipped, err := svc.Transition(ctx, car.ID, "ship", 0); err != nil || shipped.Status != models.StatusInTransit {
+		t.Fatalf("Transition(ship) after the hold lapsed = %+v, %v; want in transit", shipped, err)
+	}
+
+	reservations, err := svc.ListReservations(ctx, car.ID)
+	if err != nil || len(reservations) != 3 {
+		t.Fatalf("ListReservations() = %d reservations, %v; want 3", len(reservations), err)
+	}
+	for i, want := range []string{expireAction, "release", expireAction} {
+		if reservations[i].ReleasedAt == nil || reservations[i].ReleaseReason != want {
+			t.Fatalf("reservations[%d] = %+v, want released by %s", i, reservations[i], want)
+		}
+	}
+	if _, err := svc.ListReservations(ctx, 999); !errors.Is(err, ErrCarNotFound) {
+		t.Fatalf("ListReservations() for a missing car error = %v, want ErrCarNotFound", err)
+	}
+}
+
+func TestCarServiceStatusAndPriceValidation(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	ctx := context.Background()
+
+	invalid := []models.Car{
+		{Status: models.StatusSold},
+		{Price: -1, Currency: "USD"},
+		{Price: 100},
+		{Currency: "US$"},
+		{Mileage: -5},
+	}
+	for i, fields := range invalid {
+		car := fields
+		car.InventoryID, car.Make, car.Model, car.Year, car.Color, car.VIN = 1, "Fiat", "Panda", 2019, "Red", testVIN(110+i)
+		if _, err := svc.Create(ctx, &car); !errors.Is(err, ErrValidation) {
+			t.Fatalf("Create(%+v) error = %v, want ErrValidation", fields, err)
+		}
+	}
+
+	car, _ := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "Fiat", Model: "Panda", Year: 2019, Color: "Red", VIN: testVIN(120), Status: models.StatusInTransit})
+	update := *car
+	update.Status = models.StatusInStock
+	if _, err := svc.Update(ctx, &update); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Update() changing status error = %v, want ErrValidation", err)
+	}
+	update.Status, update.Price, update.Currency = "", 990000, "USD"
+	updated, err := svc.Update(ctx, &update)
+	if err != nil || updated.Status != models.StatusInTransit || updated.Price != 990000 {
+		t.Fatalf("Update() without status = %+v, %v; want status kept and price set", updated, err)
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+//go:embed catalog.json
+var catalogJSON []byte
+
+var catalogMakes = loadCatalog(catalogJSON)
+
+type CatalogService interface {
+	Seed(ctx context.Context) error
+	ListMakes(ctx context.Context) ([]*models.CatalogMake, error)
+	ListModels(ctx context.Context, makeName string) ([]*models.CatalogModel, error)
+}
+
+type catalogService struct {
+	repo repository.CatalogRepository
+}
+
+func NewCatalogService(repo repository.CatalogRepository) CatalogService {
+	return &catalogService{repo: repo}
+}
+
+// Seed adds the catalog embedded in the server to the repository.
+func (s *catalogService) Seed(ctx context.Context) error {
+	return s.repo.Seed(ctx, catalogMakes)
+}
+
+func (s *catalogService) ListMakes(ctx context.Context) ([]*models.CatalogMake, error) {
+	return s.repo.GetMakes(ctx)
+}
+
+// ListModels returns the models of the make called, or also known as,
+// makeName.
+func (s *catalogService) ListModels(ctx context.Context, makeName string) ([]*models.CatalogModel, error) {
+	makeName = strings.TrimSpace(makeName)
+	if makeName == "" {
+		return nil, ValidationErrors{"make": {"is required"}}
+	}
+
+	catalogMake, err := s.repo.GetMake(ctx, makeName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMakeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetModels(ctx, catalogMake.ID)
+}
+
+// matchCatalog rewrites the make, model and "trim" attribute of car to their
+// catalog spelling, adding a problem for each the catalog does not have.
+func (s *carService) matchCatalog(ctx context.Context, car *models.Car, problems ValidationErrors) error {
+	catalogMake, err := s.catalog.GetMake(ctx, strings.TrimSpace(car.Make))
+	if errors.Is(err, sql.ErrNoRows) {
+		problems.Add("make", "is not in the catalog")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	car.Make = catalogMake.Name
+
+	catalogModels, err := s.catalog.GetModels(ctx, catalogMake.ID)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(catalogModels, func(model *models.CatalogModel) bool {
+		return strings.EqualFold(model.Name, strings.TrimSpace(car.Model))
+	})
+	if i < 0 {
+		problems.Add("model", fmt.Sprintf("is not a %s model in the catalog", car.Make))
+		return nil
+	}
+	model := catalogModels[i]
+	car.Model = model.Name
+
+	trim, ok := car.Attributes["trim"]
+	if !ok {
+		return nil
+	}
+	j := slices.IndexFunc(model.Trims, func(name string) bool {
+		return strings.EqualFold(name, strings.TrimSpace(trim))
+	})
+	if j < 0 {
+		problems.Add("attributes", fmt.Sprintf("trim %q is not a %s %s trim in the catalog", trim, car.Make, car.Model))
+		return nil
+	}
+	car.Attributes["trim"] = model.Trims[j]
+
+	return nil
+}
+
+func loadCatalog(data []byte) []models.CatalogMake {
+	var makes []models.CatalogMake
+	if err := json.Unmarshal(data, &makes); err != nil {
+		panic(fmt.Sprintf("parse embedded catalog: %v", err))
+	}
+
+	return makes
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"carsapi/internal/models"
+)
+
+type fakeCatalogRepository struct {
+	makes []models.CatalogMake
+}
+
+func newFakeCatalogRepository() *fakeCatalogRepository {
+	repo := &fakeCatalogRepository{}
+	_ = repo.Seed(context.Background(), catalogMakes)
+	return repo
+}
+
+func (f *fakeCatalogRepository) Seed(_ context.Context, makes []models.CatalogMake) error {
+	for _, catalogMake := range makes {
+		if _, err := f.GetMake(context.Background(), catalogMake.Name); err == nil {
+			continue
+		}
+		catalogMake.ID = int64(len(f.makes) + 1)
+		f.makes = append(f.makes, catalogMake)
+	}
+	return nil
+}
+
+func (f *fakeCatalogRepository) GetMakes(_ context.Context) ([]*models.CatalogMake, error) {
+	makes := make([]*models.CatalogMake, 0, len(f.makes))
+	for i := range f.makes {
+		makes = append(makes, &f.makes[i])
+	}
+	return makes, nil
+}
+
+func (f *fakeCatalogRepository) GetMake(_ context.Context, name string) (*models.CatalogMake, error) {
+	for i, catalogMake := range f.makes {
+		if strings.EqualFold(catalogMake.Name, name) {
+			return &f.makes[i], nil
+		}
+		for _, alias := range catalogMake.Aliases {
+			if strings.EqualFold(alias, name) {
+				return &f.makes[i], nil
+			}
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeCatalogRepository) GetModels(_ context.Context, makeID int64) ([]*models.CatalogModel, error) {
+	catalogModels := make([]*models.CatalogModel, 0)
+	for i := range f.makes[makeID-1].Models {
+		catalogModels = append(catalogModels, &f.makes[makeID-1].Models[i])
+	}
+	return catalogModels, nil
+}
+
+func TestEmbeddedCatalog(t *testing.T) {
+	seen := map[string]bool{}
+	for _, catalogMake := range catalogMakes {
+		for _, name := range append([]string{catalogMake.Name}, catalogMake.Aliases...) {
+			if seen[strings.ToLower(name)] {
+				t.Fatalf("make or alias %q is in the catalog twice", name)
+			}
+			seen[strings.ToLower(name)] = true
+		}
+		if len(catalogMake.Models) == 0 {
+			t.Fatalf("make %q has no models", catalogMake.Name)
+		}
+	}
+	if len(catalogMakes) == 0 {
+		t.Fatal("embedded catalog is empty")
+	}
+}
+
+func TestCatalogServiceListModels(t *testing.T) {
+	svc := NewCatalogService(newFakeCatalogRepository())
+	ctx := context.Background()
+
+	catalogModels, err := svc.ListModels(ctx, " vw ")
+	if err != nil || len(catalogModels) == 0 {
+		t.Fatalf("ListModels(vw) = %v, %v; want the Volkswagen models", catalogModels, err)
+	}
+	if _, err := svc.ListModels(ctx, "Trabant"); !errors.Is(err, ErrMakeNotFound) {
+		t.Fatalf("ListModels(Trabant) error = %v, want ErrMakeNotFound", err)
+	}
+	if _, err := svc.ListModels(ctx, " "); !errors.Is(err, ErrValidation) {
+		t.Fatalf("ListModels() without a make error = %v, want ErrValidation", err)
+	}
+}
+
+func TestCarServiceStrictCatalog(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository(), WithStrictCatalog(newFakeCatalogRepository()))
+	ctx := context.Background()
+
+	car, err := svc.Create(ctx, &models.Car{InventoryID: 1, Make: "vw", Model: " golf ", Year: 2020, Color: "Red", VIN: testVIN(180), Attributes: map[string]string{"trim": "gti"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if car.Make != "Volkswagen" || car.Model != "Golf" || car.Attributes["trim"] != "GTI" {
+		t.Fatalf("Create() = %s %s %s, want Volkswagen Golf GTI", car.Make, car.Model, car.Attributes["trim"])
+	}
+
+	invalid := map[string]*models.Car{
+		"make":       {InventoryID: 1, Make: "Trabant", Model: "601", Year: 1980, Color: "Blue", VIN: testVIN(181)},
+		"model":      {InventoryID: 1, Make: "Volkswagen", Model: "Beetle", Year: 2020, Color: "Blue", VIN: testVIN(182)},
+		"attributes": {InventoryID: 1, Make: "Volkswagen", Model: "Golf", Year: 2020, Color: "Blue", VIN: testVIN(183), Attributes: map[string]string{"trim": "Turbo"}},
+	}
+	for field, car := range invalid {
+		_, err := svc.Create(ctx, car)
+		var problems ValidationErrors
+		if !errors.As(err, &problems) || problems[field] == nil {
+			t.Fatalf("Create(%s %s) error = %v, want a %s problem", car.Make, car.Model, err, field)
+		}
+	}
+
+	car.Make = "Skoda"
+	if _, err := svc.Update(ctx, car); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Update() to a Skoda Golf error = %v, want ErrValidation", err)
+	}
+
+	lenient := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+	if _, err := lenient.Create(ctx, &models.Car{InventoryID: 1, Make: "Trabant", Model: "601", Year: 1980, Color: "Blue", VIN: testVIN(184)}); err != nil {
+		t.Fatalf("Create() without strict catalog error = %v", err)
+	}
+}
@@ -1,8 +1,57 @@
 package service
 
-import "errors"
+import (
+	"errors"
+
+	"carsapi/internal/repository"
+)
 
 var (
-	ErrCarNotFound = errors.New("car not found")
-	ErrValidation  = errors.New("validation failed")
+	ErrAttachmentNotFound    = errors.New("attachment not found")
+	ErrAttachmentTooLarge    = errors.New("attachment is too large")
+	ErrBulkAborted           = errors.New("not applied because another operation in the batch failed")
+	ErrCarNotFound           = errors.New("car not found")
+	ErrConflict              = errors.New("conflicts with an existing resource")
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was already used with a different request")
+	ErrInvalidTransition     = errors.New("invalid status transition")
+	ErrInventoryInUse        = errors.New("inventory still has cars")
+	ErrInventoryNotFound     = errors.New("inventory not found")
+	ErrMakeNotFound          = errors.New("make not found")
+	ErrPatchTestFailed       = errors.New("patch test failed")
+	ErrPreconditionFailed    = errors.New("precondition failed")
+	ErrReferenceNotFound     = errors.New("does not reference an existing resource")
+	ErrSaleNotFound          = errors.New("sale not found")
+	ErrServiceRecordNotFound = errors.New("service record not found")
+	ErrUnsupportedMediaType  = errors.New("unsupported media type")
+	ErrValidation            = errors.New("validation failed")
 )
+
+// FieldError attributes ErrConflict or ErrReferenceNotFound to the request
+// field that caused it.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// constraintError maps a repository constraint violation to a *FieldError
+// and returns any other error unchanged.
+func constraintError(err error) error {
+	var constraint *repository.ConstraintError
+	if !errors.As(err, &constraint) {
+		return err
+	}
+
+	if errors.Is(constraint, repository.ErrForeignKeyViolation) {
+		return &FieldError{Field: constraint.Column, Err: ErrReferenceNotFound}
+	}
+	return &FieldError{Field: constraint.Column, Err: ErrConflict}
+}
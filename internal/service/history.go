@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+const anonymousActor = "anonymous"
+
+type actorKey struct{}
+
+// ContextWithActor attributes the changes made with ctx to actor.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
+
+// History returns the recorded changes to a car, oldest first. Cars that
+// have been deleted or purged keep their history.
+func (s *carService) History(ctx context.Context, id int64) ([]*models.CarHistoryEntry, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: id must be positive", ErrValidation)
+	}
+
+	entries, err := s.repo.GetHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrCarNotFound
+	}
+
+	for _, entry := range entries {
+		if entry.Changes, err = diffCars(entry.Old, entry.New); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// inTx runs fn with a copy of s bound to a transaction, so that a change and
+// its history entry are written together or not at all.
+func (s *carService) inTx(ctx context.Context, fn func(tx *carService) error) error {
+	return s.repo.WithinTx(ctx, func(repo repository.CarRepository) error {
+		return fn(s.withRepo(repo))
+	})
+}
+
+func (w carWriter) record(ctx context.Context, action string, carID int64, before, after *models.Car) error {
+	return w.repo.AddHistory(ctx, &models.CarHistoryEntry{
+		CarID:  carID,
+		Action: action,
+		Actor:  actorFromContext(ctx),
+		Old:    before,
+		New:    after,
+	})
+}
+
+// diffCars lists the JSON fields that differ between two snapshots of a car,
+// in field name order. A nil snapshot has no fields.
+func diffCars(before, after *models.Car) ([]models.FieldChange, error) {
+	oldFields, err := carFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := carFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(oldFields)+len(newFields))
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]models.FieldChange, 0)
+	for _, name := range names {
+		if name == "id" || reflect.DeepEqual(oldFields[name], newFields[name]) {
+			continue
+		}
+		changes = append(changes, models.FieldChange{Field: name, Old: oldFields[name], New: newFields[name]})
+	}
+
+	return changes, nil
+}
+
+func carFields(car *models.Car) (map[string]any, error) {
+	fields := map[string]any{}
+	if car == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(car)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
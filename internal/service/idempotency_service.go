@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyLease is how long a request may hold its idempotency key
+// before a retry takes the key over, in case the process handling it died.
+const idempotencyLease = time.Minute
+
+type IdempotencyService interface {
+	// Begin claims key for a request with the given hash. It returns the
+	// stored record when the request already completed and should be
+	// replayed, or nil when the caller should process the request and then
+	// call Complete or Abandon.
+	Begin(ctx context.Context, key, requestHash string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *models.IdempotencyRecord) error
+	Abandon(ctx context.Context, key string) error
+}
+
+type idempotencyService struct {
+	repo repository.IdempotencyRepository
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+func NewIdempotencyService(repo repository.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{repo: repo, ttl: ttl, now: time.Now}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, key, requestHash string) (*models.IdempotencyRecord, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, fmt.Errorf("%w: Idempotency-Key must be between 1 and %d characters", ErrValidation, maxIdempotencyKeyLength)
+	}
+
+	if err := s.repo.DeleteCreatedBefore(ctx, s.now().Add(-s.ttl)); err != nil {
+		return nil, err
+	}
+
+	reserved, err := s.repo.Reserve(ctx, key, requestHash)
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	record, err := s.repo.GetByKey(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdempotencyInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if record.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if record.StatusCode == 0 {
+		reclaimed, err := s.repo.Reclaim(ctx, key, requestHash, s.now().Add(-idempotencyLease))
+		if err != nil {
+			return nil, err
+		}
+		if reclaimed {
+			return nil, nil
+		}
+		return nil, ErrIdempotencyInProgress
+	}
+
+	return record, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	return s.repo.Complete(ctx, record)
+}
+
+func (s *idempotencyService) Abandon(ctx context.Context, key string) error {
+	return s.repo.Delete(ctx, key)
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"carsapi/internal/models"
+)
+
+type fakeIdempotencyRepository struct {
+	records map[string]*models.IdempotencyRecord
+}
+
+func newFakeIdempotencyRepository() *fakeIdempotencyRepository {
+	return &fakeIdempotencyRepository{records: map[string]*models.IdempotencyRecord{}}
+}
+
+func (f *fakeIdempotencyRepository) Reserve(_ context.Context, key, requestHash string) (bool, error) {
+	if _, ok := f.records[key]; ok {
+		return false, nil
+	}
+	now := time.Now()
+	f.records[key] = &models.IdempotencyRecord{Key: key, RequestHash: requestHash, CreatedAt: now, ReservedAt: now}
+	return true, nil
+}
+
+func (f *fakeIdempotencyRepository) Reclaim(_ context.Context, key, requestHash string, reservedBefore time.Time) (bool, error) {
+	record, ok := f.records[key]
+	if !ok || record.RequestHash != requestHash || record.StatusCode != 0 || !record.ReservedAt.Before(reservedBefore) {
+		return false, nil
+	}
+	record.ReservedAt = time.Now()
+	return true, nil
+}
+
+func (f *fakeIdempotencyRepository) GetByKey(_ context.Context, key string) (*models.IdempotencyRecord, error) {
+	record, ok := f.records[key]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copyRecord := *record
+	return &copyRecord, nil
+}
+
+func (f *fakeIdempotencyRepository) Complete(_ context.Context, record *models.IdempotencyRecord) error {
+	stored, ok := f.records[record.Key]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	stored.StatusCode = record.StatusCode
+	stored.Body = record.Body
+	return nil
+}
+
+func (f *fakeIdempotencyRepository) Delete(_ context.Context, key string) error {
+	delete(f.records, key)
+	return nil
+}
+
+func (f *fakeIdempotencyRepository) DeleteCreatedBefore(_ context.Context, before time.Time) error {
+	for key, record := range f.records {
+		if record.CreatedAt.Before(before) {
+			delete(f.records, key)
+		}
+	}
+	return nil
+}
+
+func TestIdempotencyServiceBeginAndReplay(t *testing.T) {
+	svc := NewIdempotencyService(newFakeIdempotencyRepository(), time.Hour)
+	ctx := context.Background()
+
+	record, err := svc.Begin(ctx, "key-1", "hash-1")
+	if err != nil || record != nil {
+		t.Fatalf("Begin() = %v, %v; want nil, nil", record, err)
+	}
+
+	if _, err := svc.Begin(ctx, "key-1", "hash-1"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Fatalf("Begin() in flight error = %v, want ErrIdempotencyInProgress", err)
+	}
+
+	if err := svc.Complete(ctx, &models.IdempotencyRecord{Key: "key-1", StatusCode: 201, Body: []byte(`{}`)}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	record, err = svc.Begin(ctx, "key-1", "hash-1")
+	if err != nil || record == nil || record.StatusCode != 201 {
+		t.Fatalf("Begin() replay = %+v, %v; want stored 201 response", record, err)
+	}
+
+	if _, err := svc.Begin(ctx, "key-1", "hash-2"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("Begin() different body error = %v, want ErrIdempotencyKeyReused", err)
+	}
+}
+
+func TestIdempotencyServiceExpiry(t *testing.T) {
+	repo := newFakeIdempotencyRepository()
+	svc := NewIdempotencyService(repo, time.Hour)
+	ctx := context.Background()
+
+	_, _ = svc.Begin(ctx, "key-2", "hash-1")
+	repo.records["key-2"].CreatedAt = time.Now().Add(-2 * time.Hour)
+
+	record, err := svc.Begin(ctx, "key-2", "hash-2")
+	if err != nil || record != nil {
+		t.Fatalf("Begin() after expiry = %v, %v; want a fresh reservation", record, err)
+	}
+}
+
+func TestIdempotencyServiceAbandon(t *testing.T) {
+	svc := NewIdempotencyService(newFakeIdempotencyRepository(), time.Hour)
+	ctx := context.Background()
+
+	_, _ = svc.Begin(ctx, "key-3", "hash-1")
+	if err := svc.Abandon(ctx, "key-3"); err != nil {
+		t.Fatalf("Abandon() error = %v", err)
+	}
+
+	if record, err := svc.Begin(ctx, "key-3", "hash-1"); err != nil || record != nil {
+		t.Fatalf("Begin() after abandon = %v, %v; want a fresh reservation", record, err)
+	}
+}
+
+func TestIdempotencyServiceReclaimsAbandonedReservation(t *testing.T) {
+	repo := newFakeIdempotencyRepository()
+	svc := NewIdempotencyService(repo, time.Hour)
+	ctx := context.Background()
+
+	_, _ = svc.Begin(ctx, "key-4", "hash-1")
+	repo.records["key-4"].ReservedAt = time.Now().Add(-2 * idempotencyLease)
+
+	if _, err := svc.Begin(ctx, "key-4", "hash-2"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("Begin() different body error = %v, want ErrIdempotencyKeyReused", err)
+	}
+	if record, err := svc.Begin(ctx, "key-4", "hash-1"); err != nil || record != nil {
+		t.Fatalf("Begin() after the lease = %v, %v; want the reservation taken over", record, err)
+	}
+	if _, err := svc.Begin(ctx, "key-4", "hash-1"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Fatalf("Begin() after taking over error = %v, want ErrIdempotencyInProgress", err)
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+type InventoryService interface {
+	Create(ctx context.Context, inventory *models.Inventory) (*models.Inventory, error)
+	GetByID(ctx context.Context, id int64) (*models.Inventory, error)
+	GetAll(ctx context.Context) ([]*models.Inventory, error)
+	Update(ctx context.Context, inventory *models.Inventory) (*models.Inventory, error)
+	Delete(ctx context.Context, id int64) error
+	ListCars(ctx context.Context, id int64) ([]*models.Car, error)
+	AgingStock(ctx context.Context, id int64, days, limit int, cursor string) (*models.CarPage, error)
+}
+
+const (
+	defaultAgingDays = 30
+	maxAgingDays     = 3650
+)
+
+type inventoryService struct {
+	repo repository.InventoryRepository
+	cars repository.CarRepository
+}
+
+func NewInventoryService(repo repository.InventoryRepository, cars repository.CarRepository) InventoryService {
+	return &inventoryService{repo: repo, cars: cars}
+}
+
+func (s *inventoryService) Create(ctx context.Context, inventory *models.Inventory) (*models.Inventory, error) {
+	if err := validateInventory(inventory); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, inventory); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.GetByID(ctx, inventory.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *inventoryService) GetByID(ctx context.Context, id int64) (*models.Inventory, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: id must be positive", ErrValidation)
+	}
+
+	inventory, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInventoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return inventory, nil
+}
+
+func (s *inventoryService) GetAll(ctx context.Context) ([]*models.Inventory, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *inventoryService) Update(ctx context.Context, inventory *models.Inventory) (*models.Inventory, error) {
+	if inventory.ID <= 0 {
+		return nil, fmt.Errorf("%w: id must be positive", ErrValidation)
+	}
+
+	if err := validateInventory(inventory); err != nil {
+		return nil, err
+	}
+
+	err := s.repo.Update(ctx, inventory)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInventoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.GetByID(ctx, inventory.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (s *inventoryService) Delete(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return fmt.Errorf("%w: id must be positive", ErrValidation)
+	}
+
+	err := s.repo.Delete(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInventoryNotFound
+	}
+	if errors.Is(err, repository.ErrForeignKeyViolation) {
+		return ErrInventoryInUse
+	}
+
+	return err
+}
+
+func (s *inventoryService) ListCars(ctx context.Context, id int64) ([]*models.Car, error) {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.cars.GetByInventoryID(ctx, id)
+}
+
+// AgingStock pages through the in-stock cars of an inventory whose price has
+// not changed in the last days days, longest unchanged first.
+func (s *inventoryService) AgingStock(ctx context.Context, id int64, days, limit int, cursor string) (*models.CarPage, error) {
+	if days == 0 {
+		days = defaultAgingDays
+	}
+	if days < 0 || days > maxAgingDays {
+		return nil, ValidationErrors{"days": {fmt.Sprintf("must be between 1 and %d", maxAgingDays)}}
+	}
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return listCars(ctx, s.cars, models.CarFilter{
+		InventoryID:        id,
+		Status:             models.StatusInStock,
+		PriceChangedBefore: time.Now().AddDate(0, 0, -days),
+		SortBy:             "price_changed_at",
+		Limit:              limit,
+		Cursor:             cursor,
+	})
+}
+
+func validateInventory(inventory *models.Inventory) error {
+	if inventory == nil {
+		return fmt.Errorf("%w: inventory payload is required", ErrValidation)
+	}
+
+	problems := ValidationErrors{}
+	if strings.TrimSpace(inventory.Name) == "" {
+		problems.Add("name", "is required")
+	}
+
+	return problems.Err()
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+type fakeInventoryRepository struct {
+	inventories map[int64]*models.Inventory
+	withCars    map[int64]bool
+	nextID      int64
+}
+
+func newFakeInventoryRepository() *fakeInventoryRepository {
+	return &fakeInventoryRepository{
+		inventories: map[int64]*models.Inventory{1: {ID: 1, Name: "Default Inventory"}},
+		nextID:      2,
+	}
+}
+
+func (f *fakeInventoryRepository) Create(_ context.Context, inventory *models.Inventory) error {
+	inventory.ID = f.nextID
+	f.nextID++
+	copyInventory := *inventory
+	f.inventories[inventory.ID] = &copyInventory
+	return nil
+}
+
+func (f *fakeInventoryRepository) GetByID(_ context.Context, id int64) (*models.Inventory, error) {
+	inventory, ok := f.inventories[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copyInventory := *inventory
+	return &copyInventory, nil
+}
+
+func (f *fakeInventoryRepository) GetAll(_ context.Context) ([]*models.Inventory, error) {
+	out := make([]*models.Inventory, 0, len(f.inventories))
+	for id := int64(1); id <= f.nextID; id++ {
+		if inventory, ok := f.inventories[id]; ok {
+			copyInventory := *inventory
+			out = append(out, &copyInventory)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeInventoryRepository) Update(_ context.Context, inventory *models.Inventory) error {
+	if _, ok := f.inventories[inventory.ID]; !ok {
+		return sql.ErrNoRows
+	}
+	copyInventory := *inventory
+	f.inventories[inventory.ID] = &copyInventory
+	return nil
+}
+
+func (f *fakeInventoryRepository) Delete(_ context.Context, id int64) error {
+	if _, ok := f.inventories[id]; !ok {
+		return sql.ErrNoRows
+	}
+	if f.withCars[id] {
+		return &repository.ConstraintError{Err: repository.ErrForeignKeyViolation}
+	}
+	delete(f.inventories, id)
+	return nil
+}
+
+func TestInventoryServiceCreate(t *testing.T) {
+	svc := NewInventoryService(newFakeInventoryRepository(), newFakeCarRepository())
+
+	created, err := svc.Create(context.Background(), &models.Inventory{Name: "North Lot"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID == 0 {
+		t.Fatalf("Create() returned ID = 0")
+	}
+}
+
+func TestInventoryServiceCreateValidation(t *testing.T) {
+	svc := NewInventoryService(newFakeInventoryRepository(), newFakeCarRepository())
+
+	_, err := svc.Create(context.Background(), &models.Inventory{Name: "  "})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("Create() error = %v, want ErrValidation", err)
+	}
+}
+
+func TestInventoryServiceUpdateAndDelete(t *testing.T) {
+	svc := NewInventoryService(newFakeInventoryRepository(), newFakeCarRepository())
+	ctx := context.Background()
+
+	created, _ := svc.Create(ctx, &models.Inventory{Name: "South Lot"})
+	created.Name = "South Lot Annex"
+
+	updated, err := svc.Update(ctx, created)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.Name != "South Lot Annex" {
+		t.Fatalf("Update() name = %q, want South Lot Annex", updated.Name)
+	}
+
+	if err := svc.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := svc.GetByID(ctx, created.ID); !errors.Is(err, ErrInventoryNotFound) {
+		t.Fatalf("GetByID() error = %v, want ErrInventoryNotFound", err)
+	}
+}
+
+func TestInventoryServiceDeleteInUse(t *testing.T) {
+	repo := newFakeInventoryRepository()
+	repo.withCars = map[int64]bool{1: true}
+	svc := NewInventoryService(repo, newFakeCarRepository())
+
+	if err := svc.Delete(context.Background(), 1); !errors.Is(err, ErrInventoryInUse) {
+		t.Fatalf("Delete() of an inventory with cars error = %v, want ErrInventoryInUse", err)
+	}
+	if _, err := svc.GetByID(context.Background(), 1); err != nil {
+		t.Fatalf("GetByID() after a refused Delete() error = %v", err)
+	}
+}
+
+func TestInventoryServiceListCars(t *testing.T) {
+	cars := newFakeCarRepository()
+	svc := NewInventoryService(newFakeInventoryRepository(), cars)
+	ctx := context.Background()
+
+	_ = cars.Create(ctx, &models.Car{InventoryID: 1, Make: "Ford", Model: "Ranger", Year: 2021, Color: "Red", VIN: testVIN(101)})
+	_ = cars.Create(ctx, &models.Car{InventoryID: 2, Make: "Ford", Model: "Bronco", Year: 2022, Color: "Green", VIN: testVIN(102)})
+
+	listed, err := svc.ListCars(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListCars() error = %v", err)
+	}
+	if len(listed) != 1 {
+		t.Fatalf("ListCars() len = %d, want 1", len(listed))
+	}
+
+	if _, err := svc.ListCars(ctx, 99); !errors.Is(err, ErrInventoryNotFound) {
+		t.Fatalf("ListCars() error = %v, want ErrInventoryNotFound", err)
+	}
+}
+
+func TestInventoryServiceAgingStock(t *testing.T) {
+	cars := newFakeCarRepository()
+	svc := NewInventoryService(newFakeInventoryRepository(), cars)
+	ctx := context.Background()
+
+	for i, age := range []int{90, 10, 45} {
+		car := &models.Car{InventoryID: 1, Make: "Ford", Model: "Focus", Year: 2020, Color: "Blue", VIN: testVIN(180 + i), Status: models.StatusInStock}
+		_ = cars.Create(ctx, car)
+		cars.cars[car.ID].PriceChangedAt = time.Now().AddDate(0, 0, -age)
+	}
+	sold := &models.Car{InventoryID: 1, Make: "Ford", Model: "Focus", Year: 2020, Color: "Blue", VIN: testVIN(183), Status: models.StatusSold}
+	_ = cars.Create(ctx, sold)
+	cars.cars[sold.ID].PriceChangedAt = time.Now().AddDate(0, 0, -100)
+
+	page, err := svc.AgingStock(ctx, 1, 30, 0, "")
+	if err != nil {
+		t.Fatalf("AgingStock() error = %v", err)
+	}
+	if page.Total != 2 || len(page.Cars) != 2 || page.Cars[0].ID != 1 || page.Cars[1].ID != 3 {
+		t.Fatalf("AgingStock() = %+v, want cars 1 and 3, oldest price first", page.Cars)
+	}
+
+	if _, err := svc.AgingStock(ctx, 1, -1, 0, ""); !errors.Is(err, ErrValidation) {
+		t.Fatalf("AgingStock() with negative days error = %v, want ErrValidation", err)
+	}
+	if _, err := svc.AgingStock(ctx, 99, 30, 0, ""); !errors.Is(err, ErrInventoryNotFound) {
+		t.Fatalf("AgingStock() error = %v, want ErrInventoryNotFound", err)
+	}
+}
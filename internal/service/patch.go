@@ -0,0 +1,295 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type PatchKind int
+
+const (
+	// MergePatch is an RFC 7386 JSON Merge Patch document.
+	MergePatch PatchKind = iota + 1
+	// JSONPatch is an RFC 6902 JSON Patch operation list.
+	JSONPatch
+)
+
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyPatch applies patch to the JSON document doc and returns the patched
+// document.
+func applyPatch(doc []byte, kind PatchKind, patch []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	var err error
+	switch kind {
+	case MergePatch:
+		var p any
+		if err := json.Unmarshal(patch, &p); err != nil {
+			return nil, fmt.Errorf("%w: invalid merge patch document", ErrValidation)
+		}
+		target = applyMergePatch(target, p)
+	case JSONPatch:
+		var ops []jsonPatchOperation
+		if err := json.Unmarshal(patch, &ops); err != nil {
+			return nil, fmt.Errorf("%w: invalid json patch document", ErrValidation)
+		}
+		target, err = applyJSONPatch(target, ops)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported patch kind", ErrValidation)
+	}
+
+	return json.Marshal(target)
+}
+
+func applyMergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = applyMergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+func applyJSONPatch(doc any, ops []jsonPatchOperation) (any, error) {
+	for i, op := range ops {
+		if op.Path == nil {
+			return nil, fmt.Errorf("%w: operation %d is missing path", ErrValidation, i)
+		}
+		path, err := parsePointer(*op.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %v", ErrValidation, i, err)
+		}
+
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d is missing value", ErrValidation, i)
+			}
+			var value any
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return nil, fmt.Errorf("%w: operation %d has invalid value", ErrValidation, i)
+			}
+
+			switch op.Op {
+			case "add":
+				doc, err = pointerAdd(doc, path, value)
+			case "replace":
+				doc, err = pointerReplace(doc, path, value)
+			case "test":
+				var current any
+				current, err = pointerGet(doc, path)
+				if err == nil && !reflect.DeepEqual(current, value) {
+					return nil, fmt.Errorf("%w: test failed at %s", ErrPatchTestFailed, *op.Path)
+				}
+			}
+		case "remove":
+			doc, _, err = pointerRemove(doc, path)
+		case "move", "copy":
+			if op.From == nil {
+				return nil, fmt.Errorf("%w: operation %d is missing from", ErrValidation, i)
+			}
+			from, perr := parsePointer(*op.From)
+			if perr != nil {
+				return nil, fmt.Errorf("%w: operation %d: %v", ErrValidation, i, perr)
+			}
+
+			var value any
+			if op.Op == "move" {
+				doc, value, err = pointerRemove(doc, from)
+			} else {
+				value, err = pointerGet(doc, from)
+				value = deepCopy(value)
+			}
+			if err == nil {
+				doc, err = pointerAdd(doc, path, value)
+			}
+		default:
+			return nil, fmt.Errorf("%w: operation %d has unsupported op %q", ErrValidation, i, op.Op)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %v", ErrValidation, i, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func pointerGet(doc any, path []string) (any, error) {
+	node := doc
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q not found", token)
+			}
+			node = child
+		case []any:
+			index, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[index]
+		default:
+			return nil, fmt.Errorf("path %q not found", token)
+		}
+	}
+
+	return node, nil
+}
+
+func pointerAdd(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	last := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]any:
+		p[last] = value
+		return doc, nil
+	case []any:
+		index := len(p)
+		if last != "-" {
+			if index, err = arrayIndex(last, len(p)); err != nil {
+				return nil, err
+			}
+		}
+		updated := append(p[:index:index], append([]any{value}, p[index:]...)...)
+		return replaceContainer(doc, path[:len(path)-1], updated)
+	default:
+		return nil, fmt.Errorf("path %q not found", last)
+	}
+}
+
+func pointerReplace(doc any, path []string, value any) (any, error) {
+	if _, err := pointerGet(doc, path); err != nil {
+		return nil, err
+	}
+
+	return replaceContainer(doc, path, value)
+}
+
+func pointerRemove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+
+	removed, err := pointerGet(doc, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parent, _ := pointerGet(doc, path[:len(path)-1])
+	last := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]any:
+		delete(p, last)
+		return doc, removed, nil
+	case []any:
+		index, _ := arrayIndex(last, len(p)-1)
+		updated := append(p[:index:index], p[index+1:]...)
+		doc, err = replaceContainer(doc, path[:len(path)-1], updated)
+		return doc, removed, err
+	default:
+		return nil, nil, fmt.Errorf("path %q not found", last)
+	}
+}
+
+// replaceContainer stores value at path, which must already exist. Arrays
+// cannot be grown in place, so edits to them are written back to the parent.
+func replaceContainer(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	last := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]any:
+		p[last] = value
+	case []any:
+		index, err := arrayIndex(last, len(p)-1)
+		if err != nil {
+			return nil, err
+		}
+		p[index] = value
+	}
+
+	return doc, nil
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max {
+		return 0, fmt.Errorf("array index %q out of range", token)
+	}
+
+	return index, nil
+}
+
+func deepCopy(value any) any {
+	raw, _ := json.Marshal(value)
+
+	var out any
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	doc := []byte(`{"make":"Honda","model":"Civic","specs":{"trim":"LX","doors":4}}`)
+
+	got, err := applyPatch(doc, MergePatch, []byte(`{"model":"Accord","specs":{"doors":null,"drive":"FWD"}}`))
+	if err != nil {
+		t.Fatalf("applyPatch() error = %v", err)
+	}
+
+	assertJSONEqual(t, got, `{"make":"Honda","model":"Accord","specs":{"trim":"LX","drive":"FWD"}}`)
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := []byte(`{"make":"Honda","tags":["a","c"],"specs":{"trim":"LX"}}`)
+	patch := []byte(`[
+		{"op":"test","path":"/make","value":"Honda"},
+		{"op":"add","path":"/tags/1","value":"b"},
+		{"op":"add","path":"/tags/-","value":"d"},
+		{"op":"remove","path":"/tags/0"},
+		{"op":"replace","path":"/specs/trim","value":"EX"},
+		{"op":"move","from":"/specs/trim","path":"/trim"},
+		{"op":"copy","from":"/make","path":"/brand"}
+	]`)
+
+	got, err := applyPatch(doc, JSONPatch, patch)
+	if err != nil {
+		t.Fatalf("applyPatch() error = %v", err)
+	}
+
+	assertJSONEqual(t, got, `{"make":"Honda","brand":"Honda","tags":["b","c","d"],"specs":{},"trim":"EX"}`)
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	doc := []byte(`{"make":"Honda","tags":["a"]}`)
+
+	tests := []struct {
+		patch string
+		want  error
+	}{
+		{`[{"op":"test","path":"/make","value":"Ford"}]`, ErrPatchTestFailed},
+		{`[{"op":"replace","path":"/missing","value":1}]`, ErrValidation},
+		{`[{"op":"remove","path":"/tags/5"}]`, ErrValidation},
+		{`[{"op":"add","path":"/tags/01","value":"b"}]`, ErrValidation},
+		{`[{"op":"frobnicate","path":"/make"}]`, ErrValidation},
+		{`{"op":"add"}`, ErrValidation},
+	}
+
+	for _, tt := range tests {
+		if _, err := applyPatch(doc, JSONPatch, []byte(tt.patch)); !errors.Is(err, tt.want) {
+			t.Fatalf("applyPatch(%s) error = %v, want %v", tt.patch, err, tt.want)
+		}
+	}
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var gotValue, wantValue any
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("unmarshal got: %v", err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("unmarshal want: %v", err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Fatalf("document = %s, want %s", got, want)
+	}
+}
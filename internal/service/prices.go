@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"carsapi/internal/models"
+)
+
+// Prices returns the prices a car has been listed at, oldest first.
+func (s *carService) Prices(ctx context.Context, id int64) ([]*models.PriceChange, error) {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPriceHistory(ctx, id)
+}
+
+// recordPrice adds the price of after to its price history unless before,
+// the car as it was, had the same price and currency. A nil before is a new
+// car, whose first price is always recorded.
+func (s *carService) recordPrice(ctx context.Context, before, after *models.Car) error {
+	if before != nil && before.Price == after.Price && before.Currency == after.Currency {
+		return nil
+	}
+
+	return s.repo.AddPriceChange(ctx, &models.PriceChange{
+		CarID:     after.ID,
+		Price:     after.Price,
+		Currency:  after.Currency,
+		ChangedBy: actorFromContext(ctx),
+		ChangedAt: after.PriceChangedAt,
+	})
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"carsapi/internal/models"
+)
+
+const (
+	reserveAction = "reserve"
+	expireAction  = "expire"
+
+	maxReservationHours = 7 * 24
+)
+
+// Reserve holds an in-stock car for customer for the given number of hours.
+// Until the reservation expires or is released the car cannot be reserved
+// again or sold. version, when non-zero, must match the current car.
+func (s *carService) Reserve(ctx context.Context, id int64, customer string, hours int, version int64) (*models.Reservation, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: id must be positive", ErrValidation)
+	}
+	customer = strings.TrimSpace(customer)
+	problems := ValidationErrors{}
+	if customer == "" {
+		problems.Add("customer", "is required")
+	}
+	if hours < 1 || hours > maxReservationHours {
+		problems.Add("hours", fmt.Sprintf("must be between 1 and %d", maxReservationHours))
+	}
+	if err := problems.Err(); err != nil {
+		return nil, err
+	}
+
+	var reservation *models.Reservation
+	err := s.inTx(ctx, func(tx *carService) error {
+		current, err := tx.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && version != current.Version {
+			return ErrPreconditionFailed
+		}
+		now := time.Now()
+		if current, err = tx.expireIfDue(ctx, current, now); err != nil {
+			return err
+		}
+		if current.Status != models.StatusInStock {
+			return invalidTransition(reserveAction, current)
+		}
+
+		reservation = &models.Reservation{
+			CarID:      id,
+			Customer:   customer,
+			ReservedBy: actorFromContext(ctx),
+			ExpiresAt:  now.Add(time.Duration(hours) * time.Hour).UTC().Truncate(time.Second),
+		}
+		if err := tx.repo.CreateReservation(ctx, reservation); err != nil {
+			return constraintError(err)
+		}
+
+		updated := *current
+		updated.Status = models.StatusReserved
+		updated.ReservedUntil = &reservation.ExpiresAt
+		if err := tx.updateStatus(ctx, &updated); err != nil {
+			return err
+		}
+
+		reserved, err := tx.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return tx.record(ctx, reserveAction, id, current, reserved)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// ListReservations returns the reservations made for a car, newest first.
+func (s *carService) ListReservations(ctx context.Context, id int64) ([]*models.Reservation, error) {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetReservations(ctx, id)
+}
+
+// ExpireReservations releases every reservation that expired at or before
+// now, putting its car back in stock, and returns how many it released.
+func (s *carService) ExpireReservations(ctx context.Context, now time.Time) (int, error) {
+	expired, err := s.repo.GetExpiredReservations(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, reservation := range expired {
+		err := s.inTx(ctx, func(tx *carService) error {
+			current, err := tx.repo.GetByID(ctx, reservation.CarID)
+			if errors.Is(err, sql.ErrNoRows) {
+				// The car is in the trash; end the hold but leave the car.
+				return tx.repo.ReleaseReservation(ctx, reservation.CarID, expireAction)
+			}
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.releaseHold(ctx, current, expireAction)
+			return err
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(expired), nil
+}
+
+// expireIfDue releases the reservation on car if it has expired by now, so
+// that a hold the sweeper has not reached yet does not block a change.
+func (w carWriter) expireIfDue(ctx context.Context, car *models.Car, now time.Time) (*models.Car, error) {
+	if car.Status != models.StatusReserved || car.ReservedUntil == nil || car.ReservedUntil.After(now) {
+		return car, nil
+	}
+
+	return w.releaseHold(ctx, car, expireAction)
+}
+
+// releaseHold ends the active reservation on a car and, if the car is still
+// reserved, puts it back in stock, recording the change as action.
+func (w carWriter) releaseHold(ctx context.Context, current *models.Car, action string) (*models.Car, error) {
+	if err := w.repo.ReleaseReservation(ctx, current.ID, action); err != nil {
+		return nil, err
+	}
+	if current.Status != models.StatusReserved {
+		return current, nil
+	}
+
+	updated := *current
+	updated.Status = models.StatusInStock
+	updated.ReservedUntil = nil
+	if err := w.updateStatus(ctx, &updated); err != nil {
+		return nil, err
+	}
+
+	released, err := w.repo.GetByID(ctx, current.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.record(ctx, action, current.ID, current, released); err != nil {
+		return nil, err
+	}
+
+	return released, nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+type SaleService interface {
+	Create(ctx context.Context, sale *models.Sale) (*models.Sale, error)
+	GetByID(ctx context.Context, id int64) (*models.Sale, error)
+	GetByCarID(ctx context.Context, carID int64) (*models.Sale, error)
+	GetAll(ctx context.Context, filter models.SaleFilter) ([]*models.Sale, error)
+}
+
+// saleService records sales inside repo.WithinTx, which hands it the sale
+// and car repositories bound to one transaction, so that the sale, the car
+// being marked sold and its history entry are written together.
+type saleService struct {
+	repo repository.SaleRepository
+}
+
+func NewSaleService(repo repository.SaleRepository) SaleService {
+	return &saleService{repo: repo}
+}
+
+// Create records a sale and marks the car sold. A reserved car can only be
+// sold by naming the reservation that holds it in sale.ReservationID.
+func (s *saleService) Create(ctx context.Context, sale *models.Sale) (*models.Sale, error) {
+	now := time.Now()
+	if err := validateSale(ctx, sale, now); err != nil {
+		return nil, err
+	}
+
+	err := s.repo.WithinTx(ctx, func(sales repository.SaleRepository, cars repository.CarRepository) error {
+		tx := carWriter{repo: cars}
+		current, err := tx.repo.GetByID(ctx, sale.CarID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return &FieldError{Field: "car_id", Err: ErrReferenceNotFound}
+		}
+		if err != nil {
+			return err
+		}
+		if current, err = tx.expireIfDue(ctx, current, now); err != nil {
+			return err
+		}
+		if err := checkSaleReservation(ctx, tx, current, sale); err != nil {
+			return err
+		}
+
+		if sale.Currency == "" {
+			sale.Currency = current.Currency
+		}
+		if sale.Currency == "" && sale.SalePrice > 0 {
+			return ValidationErrors{"currency": {"is required when the car has no currency"}}
+		}
+
+		if err := sales.Create(ctx, sale); err != nil {
+			return constraintError(err)
+		}
+		if current.Status == models.StatusReserved {
+			if err := tx.repo.ReleaseReservation(ctx, current.ID, "sell"); err != nil {
+				return err
+			}
+		}
+
+		updated := *current
+		updated.Status = models.StatusSold
+		updated.ReservedUntil = nil
+		if err := tx.updateStatus(ctx, &updated); err != nil {
+			return err
+		}
+		sold, err := tx.repo.GetByID(ctx, current.ID)
+		if err != nil {
+			return err
+		}
+		return tx.record(ctx, "sell", current.ID, current, sold)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sale, nil
+}
+
+func (s *saleService) GetByID(ctx context.Context, id int64) (*models.Sale, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: id must be positive", ErrValidation)
+	}
+
+	sale, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSaleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return sale, nil
+}
+
+// GetByCarID returns the sale of a car. Sales are kept after the car is
+// deleted or purged.
+func (s *saleService) GetByCarID(ctx context.Context, carID int64) (*models.Sale, error) {
+	if carID <= 0 {
+		return nil, fmt.Errorf("%w: car id must be positive", ErrValidation)
+	}
+
+	sale, err := s.repo.GetByCarID(ctx, carID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSaleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return sale, nil
+}
+
+func (s *saleService) GetAll(ctx context.Context, filter models.SaleFilter) ([]*models.Sale, error) {
+	filter.Salesperson = strings.TrimSpace(filter.Salesperson)
+	if !filter.SoldFrom.IsZero() && !filter.SoldTo.IsZero() && !filter.SoldFrom.Before(filter.SoldTo) {
+		return nil, ValidationErrors{"sold_to": {"must be after sold_from"}}
+	}
+
+	return s.repo.GetAll(ctx, filter)
+}
+
+// checkSaleReservation allows selling an in-stock car, or a reserved one
+// when the sale names the reservation holding it.
+func checkSaleReservation(ctx context.Context, tx carWriter, car *models.Car, sale *models.Sale) error {
+	if car.Status != models.StatusReserved {
+		if car.Status != models.StatusInStock {
+			return invalidTransition("sell", car)
+		}
+		if sale.ReservationID != nil {
+			return fmt.Errorf("%w: reservation %d does not hold the car", ErrInvalidTransition, *sale.ReservationID)
+		}
+		return nil
+	}
+
+	if sale.ReservationID == nil {
+		return invalidTransition("sell", car)
+	}
+	reservations, err := tx.repo.GetReservations(ctx, car.ID)
+	if err != nil {
+		return err
+	}
+	for _, reservation := range reservations {
+		if reservation.ID == *sale.ReservationID && reservation.ReleasedAt == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: reservation %d does not hold the car", ErrInvalidTransition, *sale.ReservationID)
+}
+
+func validateSale(ctx context.Context, sale *models.Sale, now time.Time) error {
+	if sale == nil {
+		return fmt.Errorf("%w: sale payload is required", ErrValidation)
+	}
+
+	sale.BuyerName = strings.TrimSpace(sale.BuyerName)
+	sale.Salesperson = strings.TrimSpace(sale.Salesperson)
+	if sale.Salesperson == "" {
+		if actor := actorFromContext(ctx); actor != anonymousActor {
+			sale.Salesperson = actor
+		}
+	}
+	sale.Currency = strings.ToUpper(strings.TrimSpace(sale.Currency))
+	if sale.SoldAt.IsZero() {
+		sale.SoldAt = now
+	}
+	sale.SoldAt = sale.SoldAt.UTC().Truncate(time.Second)
+
+	problems := ValidationErrors{}
+	if sale.CarID <= 0 {
+		problems.Add("car_id", "must be positive")
+	}
+	if sale.BuyerName == "" {
+		problems.Add("buyer_name", "is required")
+	}
+	if sale.SalePrice < 0 {
+		problems.Add("sale_price", "must not be negative")
+	}
+	if sale.Currency != "" && !isCurrencyCode(sale.Currency) {
+		problems.Add("currency", "must be a three-letter ISO 4217 code")
+	}
+	if sale.SoldAt.After(now) {
+		problems.Add("sold_at", "must not be in the future")
+	}
+	if sale.Salesperson == "" {
+		problems.Add("salesperson", "is required")
+	}
+
+	return problems.Err()
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+// fakeSaleRepository keeps its sales in the fakeCarRepository it shares
+// transactions with, so that rolling one back undoes both.
+type fakeSaleRepository struct {
+	cars *fakeCarRepository
+}
+
+func (f fakeSaleRepository) Create(_ context.Context, sale *models.Sale) error {
+	for _, existing := range f.cars.sales {
+		if existing.CarID == sale.CarID {
+			return &repository.ConstraintError{Err: repository.ErrUniqueViolation, Column: "car_id"}
+		}
+	}
+	sale.ID = int64(len(f.cars.sales) + 1)
+	sale.CreatedAt = time.Now()
+	f.cars.sales = append(f.cars.sales, sale)
+	return nil
+}
+
+func (f fakeSaleRepository) GetByID(_ context.Context, id int64) (*models.Sale, error) {
+	for _, sale := range f.cars.sales {
+		if sale.ID == id {
+			return sale, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f fakeSaleRepository) GetByCarID(_ context.Context, carID int64) (*models.Sale, error) {
+	for _, sale := range f.cars.sales {
+		if sale.CarID == carID {
+			return sale, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f fakeSaleRepository) GetAll(_ context.Context, filter models.SaleFilter) ([]*models.Sale, error) {
+	out := make([]*models.Sale, 0)
+	for i := len(f.cars.sales) - 1; i >= 0; i-- {
+		if filter.Salesperson == "" || f.cars.sales[i].Salesperson == filter.Salesperson {
+			out = append(out, f.cars.sales[i])
+		}
+	}
+	return out, nil
+}
+
+func (f fakeSaleRepository) WithinTx(ctx context.Context, fn func(repository.SaleRepository, repository.CarRepository) error) error {
+	return f.cars.WithinTx(ctx, func(cars repository.CarRepository) error {
+		return fn(f, cars)
+	})
+}
+
+func TestSaleServiceCreate(t *testing.T) {
+	repo := newFakeCarRepository()
+	cars := NewCarService(repo, newFakeInventoryRepository())
+	sales := NewSaleService(fakeSaleRepository{cars: repo})
+	ctx := ContextWithActor(context.Background(), "sam")
+
+	car, err := cars.Create(ctx, &models.Car{InventoryID: 1, Make: "Mazda", Model: "CX-5", Year: 2021, Color: "Red", VIN: testVIN(140), Price: 2400000, Currency: "USD"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	invalid := []models.Sale{
+		{CarID: car.ID},
+		{CarID: car.ID, BuyerName: "Ana", SalePrice: -1},
+		{CarID: car.ID, BuyerName: "Ana", Currency: "dollars"},
+		{CarID: car.ID, BuyerName: "Ana", SoldAt: time.Now().Add(time.Hour)},
+	}
+	for _, sale := range invalid {
+		if _, err := sales.Create(ctx, &sale); !errors.Is(err, ErrValidation) {
+			t.Fatalf("Create(%+v) error = %v, want ErrValidation", sale, err)
+		}
+	}
+	if _, err := sales.Create(context.Background(), &models.Sale{CarID: car.ID, BuyerName: "Ana"}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Create() without a salesperson or actor error = %v, want ErrValidation", err)
+	}
+	if _, err := sales.Create(ctx, &models.Sale{CarID: 999, BuyerName: "Ana"}); !errors.Is(err, ErrReferenceNotFound) {
+		t.Fatalf("Create() for a missing car error = %v, want ErrReferenceNotFound", err)
+	}
+
+	sale, err := sales.Create(ctx, &models.Sale{CarID: car.ID, BuyerName: " Ana ", SalePrice: 2350000})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if sale.ID == 0 || sale.BuyerName != "Ana" || sale.Salesperson != "sam" || sale.Currency != "USD" || sale.SoldAt.IsZero() {
+		t.Fatalf("Create() = %+v, want Ana's sale by sam in USD", sale)
+	}
+	sold, _ := cars.GetByID(ctx, car.ID)
+	if sold.Status != models.StatusSold {
+		t.Fatalf("car status = %s, want sold", sold.Status)
+	}
+	history, _ := cars.History(ctx, car.ID)
+	if last := history[len(history)-1]; last.Action != "sell" || last.Actor != "sam" {
+		t.Fatalf("last history entry = %+v, want a sell by sam", last)
+	}
+
+	if _, err := sales.Create(ctx, &models.Sale{CarID: car.ID, BuyerName: "Ben"}); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Create() for a sold car error = %v, want ErrInvalidTransition", err)
+	}
+
+	if got, err := sales.GetByCarID(ctx, car.ID); err != nil || got.ID != sale.ID {
+		t.Fatalf("GetByCarID() = %+v, %v; want sale %d", got, err, sale.ID)
+	}
+	if _, err := sales.GetByID(ctx, 999); !errors.Is(err, ErrSaleNotFound) {
+		t.Fatalf("GetByID() for a missing sale error = %v, want ErrSaleNotFound", err)
+	}
+}
+
+func TestSaleServiceRejectsCarsInTransit(t *testing.T) {
+	repo := newFakeCarRepository()
+	cars := NewCarService(repo, newFakeInventoryRepository())
+	sales := NewSaleService(fakeSaleRepository{cars: repo})
+	ctx := context.Background()
+
+	car, _ := cars.Create(ctx, &models.Car{InventoryID: 1, Make: "Mazda", Model: "3", Year: 2020, Color: "Grey", VIN: testVIN(141)})
+	if _, err := cars.Transition(ctx, car.ID, "ship", 0); err != nil {
+		t.Fatalf("Transition(ship) error = %v", err)
+	}
+	if _, err := sales.Create(ctx, &models.Sale{CarID: car.ID, BuyerName: "Ana", Salesperson: "sam"}); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Create() for a car in transit error = %v, want ErrInvalidTransition", err)
+	}
+	if list, _ := sales.GetAll(ctx, models.SaleFilter{}); len(list) != 0 {
+		t.Fatalf("GetAll() = %d sales, want none after a rejected sale", len(list))
+	}
+}
+
+func TestSaleServiceReservedCar(t *testing.T) {
+	repo := newFakeCarRepository()
+	cars := NewCarService(repo, newFakeInventoryRepository())
+	sales := NewSaleService(fakeSaleRepository{cars: repo})
+	ctx := ContextWithActor(context.Background(), "sam")
+
+	car, _ := cars.Create(ctx, &models.Car{InventoryID: 1, Make: "Mazda", Model: "MX-5", Year: 2019, Color: "Blue", VIN: testVIN(142)})
+	reservation, err := cars.Reserve(ctx, car.ID, "Ana", 24, 0)
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+
+	if _, err := sales.Create(ctx, &models.Sale{CarID: car.ID, BuyerName: "Ben"}); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Create() for a reserved car error = %v, want ErrInvalidTransition", err)
+	}
+	otherID := reservation.ID + 1
+	if _, err := sales.Create(ctx, &models.Sale{CarID: car.ID, BuyerName: "Ben", ReservationID: &otherID}); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Create() naming another reservation error = %v, want ErrInvalidTransition", err)
+	}
+
+	sale, err := sales.Create(ctx, &models.Sale{CarID: car.ID, BuyerName: "Ana", ReservationID: &reservation.ID})
+	if err != nil || *sale.ReservationID != reservation.ID {
+		t.Fatalf("Create() converting the reservation = %+v, %v", sale, err)
+	}
+	sold, _ := cars.GetByID(ctx, car.ID)
+	if sold.Status != models.StatusSold || sold.ReservedUntil != nil {
+		t.Fatalf("car = %+v, want sold without a hold", sold)
+	}
+	reservations, _ := cars.ListReservations(ctx, car.ID)
+	if reservations[0].ReleasedAt == nil || reservations[0].ReleaseReason != "sell" {
+		t.Fatalf("reservation = %+v, want released by the sale", reservations[0])
+	}
+}
+
+func TestSaleServiceGetAll(t *testing.T) {
+	sales := NewSaleService(fakeSaleRepository{cars: newFakeCarRepository()})
+
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := sales.GetAll(context.Background(), models.SaleFilter{SoldFrom: from, SoldTo: from}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("GetAll() with an empty range error = %v, want ErrValidation", err)
+	}
+	if list, err := sales.GetAll(context.Background(), models.SaleFilter{Salesperson: " sam "}); err != nil || len(list) != 0 {
+		t.Fatalf("GetAll() = %+v, %v; want no sales", list, err)
+	}
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+const serviceDateLayout = "2006-01-02"
+
+var serviceKinds = map[string]bool{
+	models.ServiceInspection:  true,
+	models.ServiceMaintenance: true,
+	models.ServiceRepair:      true,
+}
+
+type ServiceRecordService interface {
+	Create(ctx context.Context, record *models.ServiceRecord) (*models.ServiceRecord, error)
+	List(ctx context.Context, carID int64) ([]*models.ServiceRecord, error)
+	GetByID(ctx context.Context, carID, id int64) (*models.ServiceRecord, error)
+	Delete(ctx context.Context, carID, id int64) error
+}
+
+// serviceRecordService writes through a carWriter so that a record, the
+// service summary it changes and the car's history entry share one
+// transaction.
+type serviceRecordService struct {
+	repo repository.ServiceRecordRepository
+	cars carWriter
+}
+
+func NewServiceRecordService(repo repository.ServiceRecordRepository, cars repository.CarRepository) ServiceRecordService {
+	return &serviceRecordService{repo: repo, cars: carWriter{repo: cars}}
+}
+
+// Create logs work done on a car. Its mileage must fit between the records
+// performed before and after it, and its currency must match theirs.
+func (s *serviceRecordService) Create(ctx context.Context, record *models.ServiceRecord) (*models.ServiceRecord, error) {
+	if err := validateServiceRecord(ctx, record, time.Now()); err != nil {
+		return nil, err
+	}
+
+	err := s.repo.WithinTx(ctx, func(repo repository.ServiceRecordRepository, cars repository.CarRepository) error {
+		tx := carWriter{repo: cars}
+		current, err := tx.getCar(ctx, record.CarID)
+		if err != nil {
+			return err
+		}
+		records, err := repo.GetByCarID(ctx, record.CarID)
+		if err != nil {
+			return err
+		}
+		if record.Currency == "" {
+			record.Currency = serviceCurrency(records, current)
+		}
+		if err := checkServiceRecord(record, current, records); err != nil {
+			return err
+		}
+
+		if err := repo.Create(ctx, record); err != nil {
+			return constraintError(err)
+		}
+		return updateServiceSummary(ctx, repo, tx, current)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// List returns the service records of a car in the order the work was
+// performed.
+func (s *serviceRecordService) List(ctx context.Context, carID int64) ([]*models.ServiceRecord, error) {
+	if _, err := s.cars.getCar(ctx, carID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByCarID(ctx, carID)
+}
+
+func (s *serviceRecordService) GetByID(ctx context.Context, carID, id int64) (*models.ServiceRecord, error) {
+	if _, err := s.cars.getCar(ctx, carID); err != nil {
+		return nil, err
+	}
+
+	return getServiceRecord(ctx, s.repo, carID, id)
+}
+
+func (s *serviceRecordService) Delete(ctx context.Context, carID, id int64) error {
+	return s.repo.WithinTx(ctx, func(repo repository.ServiceRecordRepository, cars repository.CarRepository) error {
+		tx := carWriter{repo: cars}
+		current, err := tx.getCar(ctx, carID)
+		if err != nil {
+			return err
+		}
+		if _, err := getServiceRecord(ctx, repo, carID, id); err != nil {
+			return err
+		}
+
+		if err := repo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return updateServiceSummary(ctx, repo, tx, current)
+	})
+}
+
+// getServiceRecord returns the record with id, reporting records of other
+// cars as not found.
+func getServiceRecord(ctx context.Context, repo repository.ServiceRecordRepository, carID, id int64) (*models.ServiceRecord, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: service record id must be positive", ErrValidation)
+	}
+
+	record, err := repo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && record.CarID != carID {
+		return nil, ErrServiceRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// updateServiceSummary refreshes the service summary of current after its
+// records changed and records the change through cars.
+func updateServiceSummary(ctx context.Context, repo repository.ServiceRecordRepository, cars carWriter, current *models.Car) error {
+	if err := repo.UpdateSummary(ctx, current.ID); err != nil {
+		return err
+	}
+
+	updated, err := cars.repo.GetByID(ctx, current.ID)
+	if err != nil {
+		return err
+	}
+	return cars.record(ctx, models.CarServiced, current.ID, current, updated)
+}
+
+func sameServiceSummary(a, b *models.ServiceSummary) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Records == b.Records &&
+		a.ReconditioningCost == b.ReconditioningCost &&
+		a.Currency == b.Currency &&
+		a.LastServiceAt.Equal(b.LastServiceAt)
+}
+
+// serviceCurrency is the currency new records of a car default to: that of
+// its existing records, else the car's own.
+func serviceCurrency(records []*models.ServiceRecord, car *models.Car) string {
+	for _, record := range records {
+		if record.Currency != "" {
+			return record.Currency
+		}
+	}
+	return car.Currency
+}
+
+// checkServiceRecord validates record against the car it is for and the
+// car's existing records, which must be in performed order.
+func checkServiceRecord(record *models.ServiceRecord, car *models.Car, records []*models.ServiceRecord) error {
+	problems := ValidationErrors{}
+
+	// Model years start selling the year before.
+	earliest := time.Date(car.Year-1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if record.PerformedAt.Before(earliest) {
+		problems.Add("performed_at", fmt.Sprintf("must not be before %s for a %d model year car", earliest.Format(serviceDateLayout), car.Year))
+	}
+	if record.Cost > 0 && record.Currency == "" {
+		problems.Add("currency", "is required when neither the car nor its other service records have one")
+	}
+	for _, other := range records {
+		if other.Currency != "" && record.Currency != other.Currency {
+			problems.Add("currency", fmt.Sprintf("must be %s, the currency of the car's other service records", other.Currency))
+			break
+		}
+	}
+
+	// The odometer only goes up: the record's mileage must be at least that
+	// of every record performed up to then and at most that of every later
+	// one.
+	for _, other := range records {
+		if !other.PerformedAt.After(record.PerformedAt) && other.Mileage > record.Mileage {
+			problems.Add("mileage", fmt.Sprintf("must be at least %d, the mileage recorded on %s", other.Mileage, other.PerformedAt.Format(serviceDateLayout)))
+			break
+		}
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		other := records[i]
+		if other.PerformedAt.After(record.PerformedAt) && other.Mileage < record.Mileage {
+			problems.Add("mileage", fmt.Sprintf("must be at most %d, the mileage recorded on %s", other.Mileage, other.PerformedAt.Format(serviceDateLayout)))
+			break
+		}
+	}
+
+	return problems.Err()
+}
+
+func validateServiceRecord(ctx context.Context, record *models.ServiceRecord, now time.Time) error {
+	if record == nil {
+		return fmt.Errorf("%w: service record payload is required", ErrValidation)
+	}
+
+	record.Kind = strings.ToLower(strings.TrimSpace(record.Kind))
+	record.Description = strings.TrimSpace(record.Description)
+	record.Vendor = strings.TrimSpace(record.Vendor)
+	record.Currency = strings.ToUpper(strings.TrimSpace(record.Currency))
+	record.RecordedBy = actorFromContext(ctx)
+	if record.PerformedAt.IsZero() {
+		record.PerformedAt = now
+	}
+	record.PerformedAt = record.PerformedAt.UTC().Truncate(time.Second)
+
+	problems := ValidationErrors{}
+	if record.CarID <= 0 {
+		problems.Add("car_id", "must be positive")
+	}
+	if !serviceKinds[record.Kind] {
+		problems.Add("kind", "must be one of inspection, maintenance, repair")
+	}
+	if record.PerformedAt.After(now) {
+		problems.Add("performed_at", "must not be in the future")
+	}
+	if record.Mileage < 0 {
+		problems.Add("mileage", "must not be negative")
+	}
+	if record.Cost < 0 {
+		problems.Add("cost", "must not be negative")
+	}
+	if record.Currency != "" && !isCurrencyCode(record.Currency) {
+		problems.Add("currency", "must be a three-letter ISO 4217 code")
+	}
+
+	return problems.Err()
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+// fakeServiceRecordRepository keeps its records in the fakeCarRepository it
+// shares transactions with.
+type fakeServiceRecordRepository struct {
+	cars *fakeCarRepository
+}
+
+func (f fakeServiceRecordRepository) Create(_ context.Context, record *models.ServiceRecord) error {
+	record.ID = int64(len(f.cars.services) + 1)
+	record.CreatedAt = time.Now()
+	copyRecord := *record
+	f.cars.services = append(f.cars.services, &copyRecord)
+	return nil
+}
+
+func (f fakeServiceRecordRepository) GetByID(_ context.Context, id int64) (*models.ServiceRecord, error) {
+	for _, record := range f.cars.services {
+		if record.ID == id {
+			return record, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f fakeServiceRecordRepository) GetByCarID(_ context.Context, carID int64) ([]*models.ServiceRecord, error) {
+	out := make([]*models.ServiceRecord, 0)
+	for _, record := range f.cars.services {
+		if record.CarID == carID {
+			out = append(out, record)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b *models.ServiceRecord) int {
+		return a.PerformedAt.Compare(b.PerformedAt)
+	})
+	return out, nil
+}
+
+func (f fakeServiceRecordRepository) Delete(_ context.Context, id int64) error {
+	for i, record := range f.cars.services {
+		if record.ID == id {
+			f.cars.services = slices.Delete(f.cars.services, i, i+1)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (f fakeServiceRecordRepository) UpdateSummary(ctx context.Context, carID int64) error {
+	car, ok := f.cars.cars[carID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	records, _ := f.GetByCarID(ctx, carID)
+	car.ServiceSummary = nil
+	for _, record := range records {
+		if car.ServiceSummary == nil {
+			car.ServiceSummary = &models.ServiceSummary{}
+		}
+		car.ServiceSummary.Records++
+		car.ServiceSummary.ReconditioningCost += record.Cost
+		car.ServiceSummary.Currency = max(car.ServiceSummary.Currency, record.Currency)
+		car.ServiceSummary.LastServiceAt = record.PerformedAt
+	}
+	car.Version++
+	return nil
+}
+
+func (f fakeServiceRecordRepository) WithinTx(ctx context.Context, fn func(repository.ServiceRecordRepository, repository.CarRepository) error) error {
+	return f.cars.WithinTx(ctx, func(cars repository.CarRepository) error {
+		return fn(f, cars)
+	})
+}
+
+func TestServiceRecordServiceCreate(t *testing.T) {
+	repo := newFakeCarRepository()
+	cars := NewCarService(repo, newFakeInventoryRepository())
+	records := NewServiceRecordService(fakeServiceRecordRepository{cars: repo}, repo)
+	ctx := ContextWithActor(context.Background(), "sam")
+
+	car, err := cars.Create(ctx, &models.Car{InventoryID: 1, Make: "Volvo", Model: "XC70", Year: 2015, Color: "Silver", VIN: testVIN(150), Price: 1500000, Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	day := func(s string) time.Time {
+		d, _ := time.Parse(serviceDateLayout, s)
+		return d
+	}
+
+	first, err := records.Create(ctx, &models.ServiceRecord{CarID: car.ID, Kind: " Maintenance ", Description: "Oil change", PerformedAt: day("2024-03-01"), Mileage: 80000, Cost: 12000})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if first.ID == 0 || first.Kind != models.ServiceMaintenance || first.Currency != "EUR" || first.RecordedBy != "sam" {
+		t.Fatalf("Create() = %+v, want a maintenance record in EUR by sam", first)
+	}
+	if _, err := records.Create(ctx, &models.ServiceRecord{CarID: car.ID, Kind: "repair", PerformedAt: day("2024-06-01"), Mileage: 90000, Cost: 30000}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	invalid := []models.ServiceRecord{
+		{CarID: car.ID, Kind: "wash", PerformedAt: day("2024-04-01"), Mileage: 85000},
+		{CarID: car.ID, Kind: "repair", PerformedAt: time.Now().Add(time.Hour), Mileage: 95000},
+		{CarID: car.ID, Kind: "repair", PerformedAt: day("2013-12-31"), Mileage: 0},
+		{CarID: car.ID, Kind: "repair", PerformedAt: day("2024-04-01"), Mileage: 79000},
+		{CarID: car.ID, Kind: "repair", PerformedAt: day("2024-04-01"), Mileage: 91000},
+		{CarID: car.ID, Kind: "repair", PerformedAt: day("2024-07-01"), Mileage: 95000, Cost: 100, Currency: "USD"},
+		{CarID: car.ID, Kind: "repair", PerformedAt: day("2024-07-01"), Mileage: 95000, Cost: -1},
+	}
+	for _, record := range invalid {
+		if _, err := records.Create(ctx, &record); !errors.Is(err, ErrValidation) {
+			t.Fatalf("Create(%+v) error = %v, want ErrValidation", record, err)
+		}
+	}
+	if _, err := records.Create(ctx, &models.ServiceRecord{CarID: 999, Kind: "repair"}); !errors.Is(err, ErrCarNotFound) {
+		t.Fatalf("Create() for a missing car error = %v, want ErrCarNotFound", err)
+	}
+
+	// A record between two others fits if its mileage does.
+	if _, err := records.Create(ctx, &models.ServiceRecord{CarID: car.ID, Kind: "inspection", PerformedAt: day("2024-04-01"), Mileage: 85000}); err != nil {
+		t.Fatalf("Create() between records error = %v", err)
+	}
+
+	serviced, _ := cars.GetByID(ctx, car.ID)
+	summary := serviced.ServiceSummary
+	if summary == nil || summary.Records != 3 || summary.ReconditioningCost != 42000 || summary.Currency != "EUR" || !summary.LastServiceAt.Equal(day("2024-06-01")) {
+		t.Fatalf("service summary = %+v, want 3 records costing 42000 EUR last on 2024-06-01", summary)
+	}
+	if serviced.Version != car.Version+3 {
+		t.Fatalf("car version = %d, want %d", serviced.Version, car.Version+3)
+	}
+	history, _ := cars.History(ctx, car.ID)
+	if last := history[len(history)-1]; last.Action != models.CarServiced || last.Actor != "sam" {
+		t.Fatalf("last history entry = %+v, want a service by sam", last)
+	}
+
+	list, err := records.List(ctx, car.ID)
+	if err != nil || len(list) != 3 || list[1].Kind != models.ServiceInspection {
+		t.Fatalf("List() = %+v, %v; want 3 records with the inspection second", list, err)
+	}
+}
+
+func TestServiceRecordServiceDelete(t *testing.T) {
+	repo := newFakeCarRepository()
+	cars := NewCarService(repo, newFakeInventoryRepository())
+	records := NewServiceRecordService(fakeServiceRecordRepository{cars: repo}, repo)
+	ctx := context.Background()
+
+	car, _ := cars.Create(ctx, &models.Car{InventoryID: 1, Make: "Volvo", Model: "V60", Year: 2018, Color: "Blue", VIN: testVIN(151), Currency: "EUR"})
+	other, _ := cars.Create(ctx, &models.Car{InventoryID: 1, Make: "Volvo", Model: "S60", Year: 2018, Color: "Black", VIN: testVIN(152)})
+	record, err := records.Create(ctx, &models.ServiceRecord{CarID: car.ID, Kind: "repair", Mileage: 40000, Cost: 5000})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if _, err := records.GetByID(ctx, other.ID, record.ID); !errors.Is(err, ErrServiceRecordNotFound) {
+		t.Fatalf("GetByID() through another car error = %v, want ErrServiceRecordNotFound", err)
+	}
+	if err := records.Delete(ctx, other.ID, record.ID); !errors.Is(err, ErrServiceRecordNotFound) {
+		t.Fatalf("Delete() through another car error = %v, want ErrServiceRecordNotFound", err)
+	}
+
+	if err := records.Delete(ctx, car.ID, record.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := records.GetByID(ctx, car.ID, record.ID); !errors.Is(err, ErrServiceRecordNotFound) {
+		t.Fatalf("GetByID() after Delete() error = %v, want ErrServiceRecordNotFound", err)
+	}
+	if got, _ := cars.GetByID(ctx, car.ID); got.ServiceSummary != nil {
+		t.Fatalf("service summary after deleting the last record = %+v, want none", got.ServiceSummary)
+	}
+}
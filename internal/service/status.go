@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"carsapi/internal/models"
+	"carsapi/internal/repository"
+)
+
+type carTransition struct {
+	to   string
+	from []string
+}
+
+// carTransitions is the status state machine: each transition moves a car
+// to one status from any of a set of others. Sold and retired are final.
+// Cars become reserved through Reserve and sold through SaleService.Create
+// rather than a transition, so that every sold car has a sale.
+var carTransitions = map[string]carTransition{
+	"release": {to: models.StatusInStock, from: []string{models.StatusReserved}},
+	"ship":    {to: models.StatusInTransit, from: []string{models.StatusInStock}},
+	"receive": {to: models.StatusInStock, from: []string{models.StatusInTransit}},
+	"retire":  {to: models.StatusRetired, from: []string{models.StatusInStock, models.StatusReserved, models.StatusInTransit}},
+}
+
+var carStatuses = []string{models.StatusInStock, models.StatusReserved, models.StatusSold, models.StatusInTransit, models.StatusRetired}
+
+// Transition applies the named status transition to a car. version, when
+// non-zero, must match the current car.
+func (s *carService) Transition(ctx context.Context, id int64, name string, version int64) (*models.Car, error) {
+	transition, ok := carTransitions[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown transition %q", ErrValidation, name)
+	}
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: id must be positive", ErrValidation)
+	}
+
+	var changed *models.Car
+	err := s.inTx(ctx, func(tx *carService) error {
+		current, err := tx.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && version != current.Version {
+			return ErrPreconditionFailed
+		}
+		if current, err = tx.expireIfDue(ctx, current, time.Now()); err != nil {
+			return err
+		}
+		if !slices.Contains(transition.from, current.Status) {
+			return invalidTransition(name, current)
+		}
+
+		if current.Status == models.StatusReserved {
+			if err := tx.repo.ReleaseReservation(ctx, id, name); err != nil {
+				return err
+			}
+		}
+		updated := *current
+		updated.Status = transition.to
+		updated.ReservedUntil = nil
+		if err := tx.updateStatus(ctx, &updated); err != nil {
+			return err
+		}
+
+		if changed, err = tx.repo.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return tx.record(ctx, name, id, current, changed)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+func (w carWriter) updateStatus(ctx context.Context, car *models.Car) error {
+	err := w.repo.UpdateStatus(ctx, car)
+	if errors.Is(err, repository.ErrStaleVersion) {
+		return ErrPreconditionFailed
+	}
+	return err
+}
+
+func invalidTransition(name string, car *models.Car) error {
+	if car.ReservedUntil != nil {
+		return fmt.Errorf("%w: cannot %s a car that is reserved until %s", ErrInvalidTransition, name, car.ReservedUntil.Format(time.RFC3339))
+	}
+	return fmt.Errorf("%w: cannot %s a car that is %s", ErrInvalidTransition, name, car.Status)
+}
+
+// checkStatus defaults car.Status and rejects statuses a plain write may not
+// set: a new car (current is nil) starts in stock or in transit, and an
+// existing car only changes status through Transition.
+func checkStatus(current, car *models.Car) error {
+	if current == nil {
+		if car.Status == "" {
+			car.Status = models.StatusInStock
+		}
+		if car.Status != models.StatusInStock && car.Status != models.StatusInTransit {
+			return ValidationErrors{"status": {"must be in_stock or in_transit for a new car"}}
+		}
+		return nil
+	}
+
+	if car.Status == "" {
+		car.Status = current.Status
+	}
+	if car.Status != current.Status {
+		return ValidationErrors{"status": {"can only be changed through the status transition endpoints"}}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxTags                 = 20
+	maxTagLength            = 50
+	maxAttributes           = 50
+	maxAttributeNameLength  = 64
+	maxAttributeValueLength = 255
+)
+
+// normalizeTags trims and lower-cases tags, dropping duplicates, so that
+// "Certified" and "certified " are the same tag. The result is sorted, as
+// tags are read back.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(tag)))
+	}
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized)
+}
+
+// tagProblems reports what is wrong with normalized tags.
+func tagProblems(tags []string) []string {
+	var problems []string
+	if len(tags) > maxTags {
+		problems = append(problems, fmt.Sprintf("must not list more than %d tags", maxTags))
+	}
+	for _, tag := range tags {
+		if tag == "" {
+			problems = append(problems, "must not contain empty tags")
+		} else if tagTooLong(tag) {
+			problems = append(problems, fmt.Sprintf("tag %q must be at most %d characters", tag, maxTagLength))
+		}
+	}
+
+	return problems
+}
+
+func tagTooLong(tag string) bool {
+	return utf8.RuneCountInString(tag) > maxTagLength
+}
+
+// attributeProblems reports what is wrong with the custom attributes of a
+// car, in attribute name order.
+func attributeProblems(attributes map[string]string) []string {
+	var problems []string
+	if len(attributes) > maxAttributes {
+		problems = append(problems, fmt.Sprintf("must not have more than %d attributes", maxAttributes))
+	}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if problem := attributeNameProblem(name); problem != "" {
+			problems = append(problems, problem)
+		}
+		if utf8.RuneCountInString(attributes[name]) > maxAttributeValueLength {
+			problems = append(problems, fmt.Sprintf("%q must be at most %d characters", name, maxAttributeValueLength))
+		}
+	}
+
+	return problems
+}
+
+// attributeNameProblem keeps names to letters, digits, "_" and "-", so that
+// they can be used as attr.<name> query parameters.
+func attributeNameProblem(name string) string {
+	if name == "" {
+		return "names must not be empty"
+	}
+	if len(name) > maxAttributeNameLength {
+		return fmt.Sprintf("name %q must be at most %d characters", name, maxAttributeNameLength)
+	}
+	for _, r := range name {
+		if !isAttributeNameRune(r) {
+			return fmt.Sprintf(`name %q must contain only letters, digits, "_" and "-"`, name)
+		}
+	}
+	return ""
+}
+
+func isAttributeNameRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-'
+}
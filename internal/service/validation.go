@@ -0,0 +1,48 @@
+package service
+
+import (
+	"sort"
+	"strings"
+)
+
+// ValidationErrors maps each invalid request field to every problem found
+// with it. It matches ErrValidation under errors.Is.
+type ValidationErrors map[string][]string
+
+func (v ValidationErrors) Add(field, message string) {
+	v[field] = append(v[field], message)
+}
+
+func (v ValidationErrors) Error() string {
+	return ErrValidation.Error() + ": " + v.summary()
+}
+
+// summary lists every problem as "field message", ordered by field.
+func (v ValidationErrors) summary() string {
+	fields := make([]string, 0, len(v))
+	for field := range v {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	problems := make([]string, 0, len(fields))
+	for _, field := range fields {
+		for _, message := range v[field] {
+			problems = append(problems, field+" "+message)
+		}
+	}
+
+	return strings.Join(problems, "; ")
+}
+
+func (v ValidationErrors) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Err returns v as an error, or nil when nothing was added.
+func (v ValidationErrors) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	vinLength = 17
+	// vinStandardYear is the first model year the 17-character VIN with a
+	// check digit was mandatory; older vehicles carry free-form serials.
+	vinStandardYear = 1981
+)
+
+var vinWeights = [vinLength]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// normalizeVIN uppercases vin and drops any whitespace, so "1hgcm 82633a004352"
+// and "1HGCM82633A004352" are stored identically.
+func normalizeVIN(vin string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, vin)
+}
+
+// vinProblems reports what is wrong with a normalized VIN for a vehicle of the
+// given model year. Pre-1981 vehicles only need a non-empty VIN.
+func vinProblems(vin string, year int) []string {
+	if vin == "" {
+		return []string{"is required"}
+	}
+	if year > 0 && year < vinStandardYear {
+		return nil
+	}
+
+	if problem := vinFormatProblem(vin); problem != "" {
+		return []string{problem}
+	}
+	if check := vinCheckDigit(vin); vin[8] != check {
+		return []string{"check digit must be " + string(check)}
+	}
+
+	return nil
+}
+
+// vinFormatProblem checks the length and alphabet of a normalized VIN.
+func vinFormatProblem(vin string) string {
+	if len(vin) != vinLength {
+		return "must be 17 characters"
+	}
+	for _, r := range vin {
+		if vinValue(r) < 0 {
+			return "may only contain digits and letters other than I, O and Q"
+		}
+	}
+
+	return ""
+}
+
+// vinCheckDigit computes the ISO 3779 / 49 CFR 565 check digit for a
+// 17-character VIN; the digit at position 9 is ignored.
+func vinCheckDigit(vin string) byte {
+	sum := 0
+	for i := 0; i < vinLength; i++ {
+		sum += vinValue(rune(vin[i])) * vinWeights[i]
+	}
+
+	remainder := sum % 11
+	if remainder == 10 {
+		return 'X'
+	}
+	return byte('0' + remainder)
+}
+
+// vinValue transliterates a VIN character to its numeric value, or returns
+// -1 for characters a VIN may not contain.
+func vinValue(r rune) int {
+	switch {
+	case r >= '0' && r <= '9':
+		return int(r - '0')
+	case r >= 'A' && r <= 'H':
+		return int(r-'A') + 1
+	case r >= 'J' && r <= 'N':
+		return int(r-'J') + 1
+	case r == 'P':
+		return 7
+	case r == 'R':
+		return 9
+	case r >= 'S' && r <= 'Z':
+		return int(r-'S') + 2
+	default:
+		return -1
+	}
+}
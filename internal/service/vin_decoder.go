@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"carsapi/internal/models"
+)
+
+// vinYearCodes lists the position 10 model year codes in order from 1980;
+// the cycle repeats every 30 years.
+const vinYearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+//go:embed vin_wmi.csv
+var vinWMICSV string
+
+type wmiEntry struct {
+	manufacturer string
+	country      string
+}
+
+var wmiTable = loadWMITable(vinWMICSV)
+
+// vinRegions names the country for WMIs missing from the table where the
+// first character alone is unambiguous.
+var vinRegions = map[byte]string{
+	'1': "United States",
+	'2': "Canada",
+	'3': "Mexico",
+	'4': "United States",
+	'5': "United States",
+	'J': "Japan",
+	'K': "South Korea",
+	'L': "China",
+	'W': "Germany",
+	'Z': "Italy",
+}
+
+type VINService interface {
+	Decode(ctx context.Context, vin string) (*models.DecodedVIN, error)
+}
+
+type vinService struct{}
+
+func NewVINService() VINService {
+	return &vinService{}
+}
+
+func (s *vinService) Decode(_ context.Context, vin string) (*models.DecodedVIN, error) {
+	return decodeVIN(vin)
+}
+
+func decodeVIN(raw string) (*models.DecodedVIN, error) {
+	vin := normalizeVIN(raw)
+	if vin == "" {
+		return nil, ValidationErrors{"vin": {"is required"}}
+	}
+	if problem := vinFormatProblem(vin); problem != "" {
+		return nil, ValidationErrors{"vin": {problem}}
+	}
+
+	decoded := &models.DecodedVIN{
+		VIN:             vin,
+		WMI:             vin[:3],
+		Country:         vinRegions[vin[0]],
+		ModelYear:       vinModelYear(vin),
+		PlantCode:       vin[10:11],
+		SerialNumber:    vin[11:],
+		CheckDigitValid: vin[8] == vinCheckDigit(vin),
+	}
+	if entry, ok := wmiTable[decoded.WMI]; ok {
+		decoded.Manufacturer = entry.manufacturer
+		decoded.Country = entry.country
+	}
+
+	return decoded, nil
+}
+
+// vinModelYear resolves the position 10 year code. North American VINs use a
+// letter at position 7 from 2010 on, which picks between the two 30-year
+// cycles the code could belong to.
+func vinModelYear(vin string) int {
+	i := strings.IndexByte(vinYearCodes, vin[9])
+	if i < 0 {
+		return 0
+	}
+
+	year := 1980 + i
+	if vin[6] < '0' || vin[6] > '9' {
+		year += 30
+	}
+	return year
+}
+
+// vinMismatches compares the submitted year and make of car against what its
+// VIN decodes to. Years are compared modulo the 30-year code cycle since the
+// position 7 rule only holds for North American vehicles.
+func vinMismatches(car *models.Car) ValidationErrors {
+	problems := ValidationErrors{}
+	if car.Year < vinStandardYear {
+		return problems
+	}
+
+	decoded, err := decodeVIN(car.VIN)
+	if err != nil {
+		return problems
+	}
+
+	if decoded.ModelYear != 0 && (car.Year-decoded.ModelYear)%30 != 0 {
+		problems.Add("year", fmt.Sprintf("does not match model year %d decoded from vin", decoded.ModelYear))
+	}
+	if decoded.Manufacturer != "" && !strings.EqualFold(strings.TrimSpace(car.Make), decoded.Manufacturer) {
+		problems.Add("make", fmt.Sprintf("does not match manufacturer %s decoded from vin", decoded.Manufacturer))
+	}
+
+	return problems
+}
+
+func loadWMITable(data string) map[string]wmiEntry {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("parse embedded WMI table: %v", err))
+	}
+
+	table := make(map[string]wmiEntry, len(records))
+	for _, record := range records[1:] {
+		table[record[0]] = wmiEntry{manufacturer: record[1], country: record[2]}
+	}
+
+	return table
+}
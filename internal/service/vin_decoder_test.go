@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"carsapi/internal/models"
+)
+
+func TestDecodeVIN(t *testing.T) {
+	decoded, err := NewVINService().Decode(context.Background(), "1hgcm82633a004352")
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	want := models.DecodedVIN{
+		VIN:             "1HGCM82633A004352",
+		WMI:             "1HG",
+		Manufacturer:    "Honda",
+		Country:         "United States",
+		ModelYear:       2003,
+		PlantCode:       "A",
+		SerialNumber:    "004352",
+		CheckDigitValid: true,
+	}
+	if *decoded != want {
+		t.Fatalf("Decode() = %+v, want %+v", *decoded, want)
+	}
+}
+
+func TestDecodeVINUnknownWMI(t *testing.T) {
+	decoded, err := NewVINService().Decode(context.Background(), "2ZZCM82633L004352")
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if decoded.Manufacturer != "" || decoded.Country != "Canada" {
+		t.Fatalf("Decode() = %+v, want no manufacturer and the Canada region", decoded)
+	}
+}
+
+func TestDecodeVINInvalid(t *testing.T) {
+	if _, err := NewVINService().Decode(context.Background(), "1HGCM8263"); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Decode() error = %v, want ErrValidation", err)
+	}
+}
+
+func TestVINModelYear(t *testing.T) {
+	tests := map[string]int{
+		"1M8GDM9AXKP042788": 1989,
+		"1HGCM82633A004352": 2003,
+		"5YJ3E1EA7KF317000": 2019,
+		"1HGCM8263UA004352": 0,
+	}
+
+	for vin, want := range tests {
+		if got := vinModelYear(vin); got != want {
+			t.Fatalf("vinModelYear(%q) = %d, want %d", vin, got, want)
+		}
+	}
+}
+
+func TestCarServiceCreateVINCheck(t *testing.T) {
+	mismatched := models.Car{InventoryID: 1, Make: "Toyota", Model: "Accord", Year: 2004, Color: "Red", VIN: "1HGCM82633A004352"}
+
+	reject := NewCarService(newFakeCarRepository(), newFakeInventoryRepository(), WithVINCheck(VINCheckReject))
+	car := mismatched
+	_, err := reject.Create(context.Background(), &car)
+	var problems ValidationErrors
+	if !errors.As(err, &problems) || len(problems["make"]) != 1 || len(problems["year"]) != 1 {
+		t.Fatalf("Create() error = %v, want make and year mismatches", err)
+	}
+
+	var warnings []string
+	warn := NewCarService(newFakeCarRepository(), newFakeInventoryRepository(), WithVINCheck(VINCheckWarn)).(*carService)
+	warn.logf = func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	car = mismatched
+	if _, err := warn.Create(context.Background(), &car); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("warnings = %v, want one", warnings)
+	}
+
+	matching := models.Car{InventoryID: 1, Make: "honda", Model: "Accord", Year: 2003, Color: "Red", VIN: "1HGCM82633A004352"}
+	if _, err := reject.Create(context.Background(), &matching); err != nil {
+		t.Fatalf("Create() matching car error = %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"carsapi/internal/models"
+)
+
+// testVIN returns a distinct, checksum-valid VIN for serial n.
+func testVIN(n int) string {
+	vin := fmt.Sprintf("1HGCM82600A%06d", n)
+	return vin[:8] + string(vinCheckDigit(vin)) + vin[9:]
+}
+
+func TestNormalizeVIN(t *testing.T) {
+	if got := normalizeVIN(" 1m8gdm9a xkp042788\t"); got != "1M8GDM9AXKP042788" {
+		t.Fatalf("normalizeVIN() = %q", got)
+	}
+}
+
+func TestVINProblems(t *testing.T) {
+	tests := []struct {
+		vin   string
+		year  int
+		valid bool
+	}{
+		{"1M8GDM9AXKP042788", 1989, true},
+		{"11111111111111111", 2001, true},
+		{"1M8GDM9A1KP042788", 1989, false},
+		{"1M8GDM9AXKP04278", 1989, false},
+		{"1M8GDM9AXKP0427I8", 1989, false},
+		{"", 2020, false},
+		{"124370S100452", 1970, true},
+		{"", 1970, false},
+	}
+
+	for _, tt := range tests {
+		problems := vinProblems(tt.vin, tt.year)
+		if (len(problems) == 0) != tt.valid {
+			t.Fatalf("vinProblems(%q, %d) = %v, want valid %v", tt.vin, tt.year, problems, tt.valid)
+		}
+	}
+}
+
+func TestCarServiceCreateNormalizesVIN(t *testing.T) {
+	svc := NewCarService(newFakeCarRepository(), newFakeInventoryRepository())
+
+	created, err := svc.Create(context.Background(), &models.Car{InventoryID: 1, Make: "Honda", Model: "Accord", Year: 2003, Color: "Silver", VIN: "1hgcm 82633a004352"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.VIN != "1HGCM82633A004352" {
+		t.Fatalf("Create() VIN = %q, want normalized", created.VIN)
+	}
+}
@@ -36,7 +36,8 @@ func TestCarsAPI_CRUDAgainstRunningServer(t *testing.T) {
 		baseURL = "http://localhost:8080"
 	}
 
-	vin := fmt.Sprintf("VIN-IT-%d", time.Now().UnixNano())
+	serial := time.Now().UnixNano() % 1000000
+	vin := serialVIN(serial)
 
 	created := createCar(t, baseURL, car{
 		InventoryID: 1,
@@ -52,7 +53,7 @@ func TestCarsAPI_CRUDAgainstRunningServer(t *testing.T) {
 		t.Fatalf("GET /api/cars/{id} VIN=%q want %q", fetched.VIN, vin)
 	}
 
-	updatedVIN := fmt.Sprintf("VIN-IT-UPDATED-%d", time.Now().UnixNano())
+	updatedVIN := serialVIN(serial + 1)
 	updated := updateCar(t, baseURL, created.ID, car{
 		InventoryID: 1,
 		Make:        "Toyota",
@@ -89,6 +90,25 @@ func TestCarsAPI_CRUDAgainstRunningServer(t *testing.T) {
 	}
 }
 
+// serialVIN builds a VIN with a valid check digit around a six-digit serial.
+func serialVIN(serial int64) string {
+	vin := []byte(fmt.Sprintf("1HGCM82600A%06d", serial%1000000))
+	weights := []int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+	values := map[byte]int{'A': 1, 'C': 3, 'G': 7, 'H': 8, 'M': 4}
+
+	sum := 0
+	for i, c := range vin {
+		value, ok := values[c]
+		if !ok {
+			value = int(c - '0')
+		}
+		sum += value * weights[i]
+	}
+
+	vin[8] = "0123456789X"[sum%11]
+	return string(vin)
+}
+
 func createCar(t *testing.T, baseURL string, in car) car {
 	t.Helper()
 	body, _ := json.Marshal(in)
@@ -165,7 +185,7 @@ func updateCar(t *testing.T, baseURL string, id int64, in car) car {
 
 func listCars(t *testing.T, baseURL string) []car {
 	t.Helper()
-	resp, err := http.Get(baseURL + "/api/cars")
+	resp, err := http.Get(baseURL + "/api/cars?order=desc")
 	if err != nil {
 		t.Fatalf("GET /api/cars error: %v", err)
 	}
@@ -179,11 +199,13 @@ func listCars(t *testing.T, baseURL string) []car {
 		t.Fatalf("decode list response: %v", err)
 	}
 
-	var out []car
+	var out struct {
+		Cars []car `json:"cars"`
+	}
 	if err := json.Unmarshal(js.Data, &out); err != nil {
 		t.Fatalf("unmarshal list data: %v", err)
 	}
-	return out
+	return out.Cars
 }
 
 func deleteCar(t *testing.T, baseURL string, id int64) {